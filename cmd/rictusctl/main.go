@@ -15,7 +15,7 @@ const (
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: rictusctl <start|stop|restart|status>")
+		fmt.Println("Usage: rictusctl <start|stop|restart|status|projects>")
 		os.Exit(1)
 	}
 
@@ -30,6 +30,8 @@ func main() {
 		restartDaemon()
 	case "status":
 		status()
+	case "projects":
+		projectsCmd(os.Args[2:])
 	default:
 		fmt.Println("Unknown command:", cmd)
 		os.Exit(1)
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultAPI = "http://127.0.0.1:8080"
+
+// project mirrors the daemon's project record for display.
+type project struct {
+	Name          string   `json:"name"`
+	Path          string   `json:"path"`
+	Type          string   `json:"type"`
+	Languages     []string `json:"languages"`
+	Frameworks    []string `json:"frameworks"`
+	Tooling       []string `json:"tooling"`
+	RegisteredAt  string   `json:"registered_at"`
+	Aliases       []string `json:"aliases"`
+	Tags          []string `json:"tags"`
+	Group         string   `json:"group"`
+	Description   string   `json:"description"`
+	Owner         string   `json:"owner"`
+	DefaultBranch string   `json:"default_branch"`
+	Standards     string   `json:"standards"`
+}
+
+func projectsUsage() {
+	fmt.Println(`Usage: rictusctl projects <command> [args]
+
+  list [--tag T | --group G]   list registered projects
+  show <name>                  show one project
+  add <path>                   register a project
+  remove <name>                unregister a project (files are untouched)
+  rename <name> <new-name>
+  alias <name> <alias>         add an alias
+  unalias <name> <alias>
+  tag <name> <tag>...
+  untag <name> <tag>...
+  group <name> <group>         use "" to clear
+  move <name> <new-path>       relocate after the directory moved
+  set <name> <field> <value>   field: description, owner, default_branch, standards
+
+The daemon is reached at $RICTUSD_API (default ` + defaultAPI + `).`)
+}
+
+func projectsCmd(args []string) {
+	if len(args) == 0 {
+		projectsUsage()
+		os.Exit(1)
+	}
+
+	sub, rest := args[0], args[1:]
+
+	need := func(n int) {
+		if len(rest) < n {
+			projectsUsage()
+			os.Exit(1)
+		}
+	}
+
+	switch sub {
+	case "list":
+		path := "/api/projects"
+		if len(rest) == 2 && (rest[0] == "--tag" || rest[0] == "--group") {
+			path += "?" + strings.TrimPrefix(rest[0], "--") + "=" + url.QueryEscape(rest[1])
+		}
+		var list []project
+		apiCall(http.MethodGet, path, nil, &list)
+		if len(list) == 0 {
+			fmt.Println("No projects registered.")
+			return
+		}
+		for _, p := range list {
+			line := fmt.Sprintf("%-20s %s", p.Name, p.Path)
+			if p.Group != "" {
+				line += " [" + p.Group + "]"
+			}
+			if len(p.Tags) > 0 {
+				line += " #" + strings.Join(p.Tags, " #")
+			}
+			fmt.Println(line)
+		}
+
+	case "show":
+		need(1)
+		var p project
+		apiCall(http.MethodGet, projectPath(rest[0]), nil, &p)
+		printProject(p)
+
+	case "add":
+		need(1)
+		var p project
+		apiCall(http.MethodPost, "/api/projects", map[string]string{"path": rest[0]}, &p)
+		fmt.Printf("Registered %q at %s\n", p.Name, p.Path)
+
+	case "remove":
+		need(1)
+		var p project
+		apiCall(http.MethodDelete, projectPath(rest[0]), nil, &p)
+		fmt.Printf("Unregistered %q\n", p.Name)
+
+	case "rename":
+		need(2)
+		patchProject(rest[0], map[string]interface{}{"name": rest[1]})
+
+	case "alias":
+		need(2)
+		patchProject(rest[0], map[string]interface{}{"add_aliases": rest[1:]})
+
+	case "unalias":
+		need(2)
+		patchProject(rest[0], map[string]interface{}{"remove_aliases": rest[1:]})
+
+	case "tag":
+		need(2)
+		patchProject(rest[0], map[string]interface{}{"add_tags": rest[1:]})
+
+	case "untag":
+		need(2)
+		patchProject(rest[0], map[string]interface{}{"remove_tags": rest[1:]})
+
+	case "group":
+		need(2)
+		patchProject(rest[0], map[string]interface{}{"group": rest[1]})
+
+	case "move":
+		need(2)
+		patchProject(rest[0], map[string]interface{}{"path": rest[1]})
+
+	case "set":
+		need(3)
+		field := strings.ReplaceAll(rest[1], "-", "_")
+		patchProject(rest[0], map[string]interface{}{field: strings.Join(rest[2:], " ")})
+
+	default:
+		fmt.Println("Unknown projects command:", sub)
+		projectsUsage()
+		os.Exit(1)
+	}
+}
+
+func patchProject(name string, body map[string]interface{}) {
+	var p project
+	apiCall(http.MethodPatch, projectPath(name), body, &p)
+	printProject(p)
+}
+
+func projectPath(name string) string {
+	return "/api/projects/" + url.PathEscape(name)
+}
+
+func printProject(p project) {
+	fmt.Printf("Name:        %s\n", p.Name)
+	fmt.Printf("Path:        %s\n", p.Path)
+	fmt.Printf("Type:        %s\n", p.Type)
+	if len(p.Languages) > 0 {
+		fmt.Printf("Languages:   %s\n", strings.Join(p.Languages, ", "))
+	}
+	if len(p.Frameworks) > 0 {
+		fmt.Printf("Frameworks:  %s\n", strings.Join(p.Frameworks, ", "))
+	}
+	if len(p.Tooling) > 0 {
+		fmt.Printf("Tooling:     %s\n", strings.Join(p.Tooling, ", "))
+	}
+	if len(p.Aliases) > 0 {
+		fmt.Printf("Aliases:     %s\n", strings.Join(p.Aliases, ", "))
+	}
+	if len(p.Tags) > 0 {
+		fmt.Printf("Tags:        %s\n", strings.Join(p.Tags, ", "))
+	}
+	if p.Group != "" {
+		fmt.Printf("Group:       %s\n", p.Group)
+	}
+	if p.Description != "" {
+		fmt.Printf("Description: %s\n", p.Description)
+	}
+	if p.Owner != "" {
+		fmt.Printf("Owner:       %s\n", p.Owner)
+	}
+	if p.DefaultBranch != "" {
+		fmt.Printf("Branch:      %s\n", p.DefaultBranch)
+	}
+	if p.Standards != "" {
+		fmt.Printf("Standards:   %s\n", p.Standards)
+	}
+}
+
+// apiCall sends a JSON request to the daemon and decodes the reply into out,
+// exiting with a message on any failure.
+func apiCall(method, path string, body interface{}, out interface{}) {
+	base := os.Getenv("RICTUSD_API")
+	if base == "" {
+		base = defaultAPI
+	}
+
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			fmt.Printf("Failed to encode request: %v\n", err)
+			os.Exit(1)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(base, "/")+path, payload)
+	if err != nil {
+		fmt.Printf("Failed to build request: %v\n", err)
+		os.Exit(1)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Printf("Could not reach RictusD at %s: %v\n", base, err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if e.Error == "" {
+			e.Error = resp.Status
+		}
+		fmt.Printf("RictusD refused the request: %s\n", e.Error)
+		os.Exit(1)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			fmt.Printf("Failed to decode reply: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
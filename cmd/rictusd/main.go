@@ -1,14 +1,32 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"rictusd/modules/core"
+	"rictusd/modules/lsp"
+	"rictusd/modules/mcp"
+	"rictusd/modules/mind"
 	"rictusd/modules/server"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lsp":
+			runLSP(os.Args[2:])
+			return
+		case "mcp":
+			runMCP(os.Args[2:])
+			return
+		}
+	}
+
 	// 1. Load core system (config, logger, dirs)
 	c, err := core.New()
 	if err != nil {
@@ -25,11 +43,69 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 3. Start listening
+	// 3. Start listening until interrupted; in-flight scans are cancelled on
+	// shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	c.Log.Infof("RictusD listening on %s", c.Config.ListenAddr)
 
-	if err := srv.Start(); err != nil {
+	if err := srv.Start(ctx); err != nil {
 		c.Log.Errorf("Server error: %v", err)
 		os.Exit(1)
 	}
 }
+
+// stdioCore prepares the core for a mode that talks over stdin and stdout.
+// Clients start such servers in their own working directory, so -root
+// points at the RictusD directory whose data/ holds the registered
+// projects. Logs go to stderr, which clients show in their output panel.
+func stdioCore(mode string, args []string) *core.Core {
+	fs := flag.NewFlagSet("rictusd "+mode, flag.ExitOnError)
+	root := fs.String("root", "", "RictusD root directory (default: the working directory)")
+	fs.Parse(args)
+
+	if *root != "" {
+		if err := os.Chdir(*root); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to enter %s: %v\n", *root, err)
+			os.Exit(1)
+		}
+	}
+
+	c, err := core.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize core: %v\n", err)
+		os.Exit(1)
+	}
+	return c
+}
+
+// runLSP serves the Language Server Protocol on stdio for an editor.
+func runLSP(args []string) {
+	c := stdioCore("lsp", args)
+	c.Log.Info("RictusD language server starting on stdio…")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := lsp.New(c).Serve(ctx, os.Stdin, os.Stdout); err != nil {
+		c.Log.Errorf("Language server error: %v", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// runMCP serves the Model Context Protocol on stdio for another agent.
+func runMCP(args []string) {
+	c := stdioCore("mcp", args)
+	c.Log.Info("RictusD MCP server starting on stdio…")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := mcp.New(c, mind.New(c)).ServeStdio(ctx, os.Stdin, os.Stdout); err != nil {
+		c.Log.Errorf("MCP server error: %v", err)
+		stop()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,188 @@
+package brain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rictusd/modules/core"
+	"rictusd/modules/law"
+)
+
+// CheckViewInclude flags a file that includes a view by its path instead of
+// going through a renderer.
+const CheckViewInclude = "view-include"
+
+// LayerChecks are the checks a layer can forbid.
+var LayerChecks = []string{law.CheckDB, law.CheckHTML, law.CheckHeader, law.CheckFileIO, law.CheckLogging, CheckViewInclude}
+
+// DefaultForbid is what each layer must not do unless the project's
+// "architecture" config says otherwise.
+var DefaultForbid = map[Role][]string{
+	RoleView:       {law.CheckDB},
+	RoleModel:      {law.CheckHTML},
+	RoleController: {CheckViewInclude},
+}
+
+// ArchFinding is one layer violation.
+type ArchFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Role    Role   `json:"role"`
+	Check   string `json:"check"`
+	Snippet string `json:"snippet"`
+	Message string `json:"message"`
+}
+
+// ArchReport is the result of an architecture check.
+type ArchReport struct {
+	Roles      map[Role]int      `json:"roles"`             // PHP files per role
+	Rules      map[Role][]string `json:"rules"`             // the checks in effect per role
+	Unknown    []string          `json:"unknown,omitempty"` // config entries that name no role or check
+	Violations []ArchFinding     `json:"violations"`
+}
+
+// archRules merges the project's forbid lists over the defaults.
+func archRules(cfg core.ArchitectureConfig) (rules map[Role][]string, unknown []string) {
+	rules = make(map[Role][]string, len(DefaultForbid))
+	for r, checks := range DefaultForbid {
+		rules[r] = checks
+	}
+	for name, checks := range cfg.Forbid {
+		r, ok := ParseRole(name)
+		if !ok {
+			unknown = append(unknown, "role "+name)
+			continue
+		}
+		valid := make([]string, 0, len(checks))
+		for _, c := range checks {
+			c = strings.ToLower(strings.TrimSpace(c))
+			if isLayerCheck(c) {
+				valid = append(valid, c)
+			} else {
+				unknown = append(unknown, "check "+c)
+			}
+		}
+		rules[r] = valid
+	}
+	for glob, name := range cfg.Roles {
+		if _, ok := ParseRole(name); !ok {
+			unknown = append(unknown, "role "+name+" for "+glob)
+		}
+	}
+	sort.Strings(unknown)
+	return rules, unknown
+}
+
+func isLayerCheck(name string) bool {
+	for _, c := range LayerChecks {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// roleOf is a file's role after the project's pinned roles are applied.
+func roleOf(e *FileEntry, cfg core.ArchitectureConfig) Role {
+	if name, ok := cfg.RoleFor(e.Path); ok {
+		if r, ok := ParseRole(name); ok {
+			return r
+		}
+	}
+	if e.Role == "" {
+		return RoleUnknown
+	}
+	return e.Role
+}
+
+// CheckArchitecture classifies every PHP file of a project into its MVC
+// layer and checks each layer's rules.
+func (s *PHPScanner) CheckArchitecture(ctx context.Context, p core.Project) (ArchReport, error) {
+	report := ArchReport{Roles: make(map[Role]int), Violations: make([]ArchFinding, 0)}
+
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		s.core.Log.Warnf("architecture: %v", err)
+	}
+	report.Rules, report.Unknown = archRules(cfg.Architecture)
+
+	idx, _, err := NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return report, fmt.Errorf("architecture: %w", err)
+	}
+
+	roles := make(map[string]Role, len(idx.Files))
+	for _, e := range idx.Files {
+		roles[e.Path] = roleOf(e, cfg.Architecture)
+	}
+
+	for _, e := range idx.Lang("php") {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		role := roles[e.Path]
+		report.Roles[role]++
+
+		checks := report.Rules[role]
+		if len(checks) == 0 || e.PHP == nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(p.Path, filepath.FromSlash(e.Path)))
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(data), "\n")
+		toks := LexPHP(data)
+		code := PHPCode(toks)
+
+		add := func(check string, line int, msg string) {
+			report.Violations = append(report.Violations, ArchFinding{
+				File: e.Path, Line: line, Role: role, Check: check,
+				Snippet: snippetAt(lines, line), Message: msg,
+			})
+		}
+
+		for _, check := range checks {
+			if check == CheckViewInclude {
+				for _, r := range e.PHP.Requires {
+					if target, ok := viewTarget(idx, roles, r, cfg.IncludePaths); ok {
+						add(check, r.Line, "includes the view "+target+" by path instead of rendering it")
+					}
+				}
+				continue
+			}
+			for _, h := range routerDetectors[check](code, toks, 0) {
+				add(check, h.line, h.msg)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		a, b := report.Violations[i], report.Violations[j]
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// viewTarget reports whether a require points at a view: a file classified
+// as one, or an unresolved path that reads like a template.
+func viewTarget(idx *ProjectIndex, roles map[string]Role, r PHPRequire, includePaths []string) (string, bool) {
+	if to, ok := resolveInIndex(idx, r, includePaths); ok {
+		return to, roles[to] == RoleView
+	}
+	l := strings.ToLower(r.Candidate)
+	if strings.Contains(l, "view") || strings.Contains(l, "template") || strings.HasSuffix(l, ".phtml") || strings.HasSuffix(l, ".tpl.php") {
+		return r.Candidate, true
+	}
+	return "", false
+}
@@ -0,0 +1,478 @@
+package brain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rictusd/modules/core"
+)
+
+// ComposerAutoload is one autoload section of composer.json. PSR-4 and PSR-0
+// values may be a single directory or a list in the file; both are
+// normalised to lists of slash-separated, project-relative directories.
+type ComposerAutoload struct {
+	PSR4     map[string][]string `json:"psr-4"`
+	PSR0     map[string][]string `json:"psr-0"`
+	Classmap []string            `json:"classmap"`
+	Files    []string            `json:"files"`
+}
+
+// UnmarshalJSON accepts the string-or-list forms composer allows.
+func (a *ComposerAutoload) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		PSR4     map[string]json.RawMessage `json:"psr-4"`
+		PSR0     map[string]json.RawMessage `json:"psr-0"`
+		Classmap []string                   `json:"classmap"`
+		Files    []string                   `json:"files"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	dirs := func(in map[string]json.RawMessage) (map[string][]string, error) {
+		out := make(map[string][]string, len(in))
+		for prefix, msg := range in {
+			var one string
+			if json.Unmarshal(msg, &one) == nil {
+				out[prefix] = []string{cleanComposerDir(one)}
+				continue
+			}
+			var many []string
+			if err := json.Unmarshal(msg, &many); err != nil {
+				return nil, fmt.Errorf("autoload %q: %w", prefix, err)
+			}
+			for i := range many {
+				many[i] = cleanComposerDir(many[i])
+			}
+			out[prefix] = many
+		}
+		return out, nil
+	}
+
+	var err error
+	if a.PSR4, err = dirs(raw.PSR4); err != nil {
+		return err
+	}
+	if a.PSR0, err = dirs(raw.PSR0); err != nil {
+		return err
+	}
+	a.Classmap = make([]string, 0, len(raw.Classmap))
+	for _, c := range raw.Classmap {
+		a.Classmap = append(a.Classmap, cleanComposerDir(c))
+	}
+	a.Files = make([]string, 0, len(raw.Files))
+	for _, f := range raw.Files {
+		a.Files = append(a.Files, cleanComposerDir(f))
+	}
+	return nil
+}
+
+// cleanComposerDir turns "./src/", "src" and "" into "src", "src" and ".".
+func cleanComposerDir(dir string) string {
+	dir = path.Clean(strings.ReplaceAll(strings.TrimSpace(dir), `\`, "/"))
+	return strings.TrimPrefix(dir, "/")
+}
+
+// ComposerConfig is the slice of composer.json the autoload check reads.
+type ComposerConfig struct {
+	Autoload    ComposerAutoload `json:"autoload"`
+	AutoloadDev ComposerAutoload `json:"autoload-dev"`
+}
+
+// psrMapping is one namespace prefix to directory mapping.
+type psrMapping struct {
+	prefix string // with a trailing "\" unless empty
+	dir    string
+	psr0   bool
+	dev    bool
+}
+
+// mappings flattens both sections, most specific directory first.
+func (c *ComposerConfig) mappings() []psrMapping {
+	out := make([]psrMapping, 0)
+	add := func(m map[string][]string, psr0, dev bool) {
+		for prefix, dirs := range m {
+			for _, d := range dirs {
+				out = append(out, psrMapping{prefix: prefix, dir: d, psr0: psr0, dev: dev})
+			}
+		}
+	}
+	add(c.Autoload.PSR4, false, false)
+	add(c.Autoload.PSR0, true, false)
+	add(c.AutoloadDev.PSR4, false, true)
+	add(c.AutoloadDev.PSR0, true, true)
+
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].dir) != len(out[j].dir) {
+			return len(out[i].dir) > len(out[j].dir)
+		}
+		return out[i].prefix < out[j].prefix
+	})
+	return out
+}
+
+// classmap lists the classmap entries of both sections.
+func (c *ComposerConfig) classmap() []string {
+	return append(append([]string(nil), c.Autoload.Classmap...), c.AutoloadDev.Classmap...)
+}
+
+// files lists the autoload.files entries of both sections.
+func (c *ComposerConfig) files() []string {
+	return append(append([]string(nil), c.Autoload.Files...), c.AutoloadDev.Files...)
+}
+
+// LoadComposer reads composer.json from a project root. It returns nil and
+// no error when there is no composer.json.
+func LoadComposer(root string) (*ComposerConfig, error) {
+	data, err := os.ReadFile(filepath.Join(root, "composer.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cc ComposerConfig
+	if err := json.Unmarshal(data, &cc); err != nil {
+		return nil, fmt.Errorf("composer.json: %w", err)
+	}
+	return &cc, nil
+}
+
+// AutoloadIssue is one problem with how Composer would load a class or file.
+type AutoloadIssue struct {
+	Kind       string `json:"kind"` // misplaced, unmapped, unresolved-use, missing-dir, missing-file
+	File       string `json:"file,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Class      string `json:"class,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// AutoloadReport is the result of CheckAutoload.
+type AutoloadReport struct {
+	HasComposer    bool            `json:"has_composer"`
+	Mappings       int             `json:"mappings"`
+	ClassesChecked int             `json:"classes_checked"`
+	Issues         []AutoloadIssue `json:"issues"`
+}
+
+// CheckAutoload validates a project's composer.json autoload and
+// autoload-dev sections against its classes: every PSR-4 or PSR-0 directory
+// and autoloaded file must exist, each class must live where its mapping
+// says, and every class imported with use must be loadable from the
+// project's own mappings, a classmap, or an installed package.
+func (s *PHPScanner) CheckAutoload(ctx context.Context, p core.Project) (AutoloadReport, error) {
+	report := AutoloadReport{Issues: make([]AutoloadIssue, 0)}
+
+	cc, err := LoadComposer(p.Path)
+	if err != nil {
+		return report, fmt.Errorf("autoload: %w", err)
+	}
+	if cc == nil {
+		return report, nil
+	}
+	report.HasComposer = true
+
+	idx, _, err := NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return report, fmt.Errorf("autoload: %w", err)
+	}
+
+	maps := cc.mappings()
+	report.Mappings = len(maps)
+	classmap, files := cc.classmap(), cc.files()
+	if len(maps) == 0 && len(classmap) == 0 && len(files) == 0 {
+		// Composer is only used for dependencies here.
+		return report, nil
+	}
+
+	exists := func(rel string) bool {
+		if idx.Has(rel) {
+			return true
+		}
+		_, err := os.Stat(filepath.Join(p.Path, filepath.FromSlash(rel)))
+		return err == nil
+	}
+
+	// Declared directories and files.
+	seenDir := make(map[string]bool)
+	for _, m := range maps {
+		if seenDir[m.dir] {
+			continue
+		}
+		seenDir[m.dir] = true
+		if !exists(m.dir) {
+			section := "autoload"
+			if m.dev {
+				section = "autoload-dev"
+			}
+			report.Issues = append(report.Issues, AutoloadIssue{
+				Kind:       "missing-dir",
+				File:       m.dir,
+				Message:    "composer.json’s " + section + " maps " + quotePrefix(m.prefix) + " to " + m.dir + "/, which doesn’t exist",
+				Suggestion: "create " + m.dir + "/ or fix the path in composer.json",
+			})
+		}
+	}
+	for _, f := range files {
+		if !exists(f) {
+			report.Issues = append(report.Issues, AutoloadIssue{
+				Kind:       "missing-file",
+				File:       f,
+				Message:    "composer.json autoloads " + f + ", which doesn’t exist",
+				Suggestion: "restore " + f + " or remove it from autoload.files",
+			})
+		}
+	}
+	for _, c := range classmap {
+		if !exists(c) {
+			report.Issues = append(report.Issues, AutoloadIssue{
+				Kind:       "missing-dir",
+				File:       c,
+				Message:    "composer.json has " + c + " in its classmap, but it doesn’t exist",
+				Suggestion: "remove " + c + " from the classmap or restore it",
+			})
+		}
+	}
+
+	// Classes against their mappings. Files Composer loads outright count
+	// like classmap entries.
+	loaded := append(classmap, files...)
+	defined := make(map[string]bool)
+	for _, e := range idx.Lang("php") {
+		if e.PHP == nil {
+			continue
+		}
+		for _, sym := range e.PHP.Symbols {
+			if !isClassKind(sym.Kind) {
+				continue
+			}
+			defined[strings.ToLower(sym.FQN)] = true
+			report.ClassesChecked++
+			if issue, bad := checkClassPlacement(e.Path, sym, maps, loaded); bad {
+				report.Issues = append(report.Issues, issue)
+			}
+		}
+	}
+
+	// Imports against everything that can load them.
+	packages := installedPrefixes(p.Path)
+	reported := make(map[string]bool)
+	for _, e := range idx.Lang("php") {
+		if e.PHP == nil {
+			continue
+		}
+		for _, u := range e.PHP.Uses {
+			if u.Kind != "class" || !strings.Contains(u.Name, `\`) || defined[strings.ToLower(u.Name)] {
+				continue
+			}
+			if issue, bad := checkImport(u, maps, packages, exists); bad && !reported[e.Path+u.Name] {
+				reported[e.Path+u.Name] = true
+				issue.File = e.Path
+				report.Issues = append(report.Issues, issue)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func isClassKind(kind string) bool {
+	return kind == "class" || kind == "interface" || kind == "trait" || kind == "enum"
+}
+
+// quotePrefix renders a namespace prefix the way composer.json spells it.
+func quotePrefix(prefix string) string {
+	if prefix == "" {
+		return `the root namespace ""`
+	}
+	return `"` + strings.ReplaceAll(prefix, `\`, `\\`) + `"`
+}
+
+// expectedPath is where a mapping wants a class to live, or "" when the
+// mapping doesn't cover the class's namespace.
+func expectedPath(m psrMapping, fqn string) string {
+	if !strings.HasPrefix(strings.ToLower(fqn), strings.ToLower(m.prefix)) {
+		return ""
+	}
+	rel := fqn
+	if !m.psr0 {
+		rel = fqn[len(m.prefix):]
+	} else if i := strings.LastIndex(rel, `\`); i >= 0 {
+		// PSR-0 maps underscores in the class name, not the namespace.
+		rel = rel[:i+1] + strings.ReplaceAll(rel[i+1:], "_", "/")
+	} else {
+		rel = strings.ReplaceAll(rel, "_", "/")
+	}
+	return path.Join(m.dir, strings.ReplaceAll(rel, `\`, "/")+".php")
+}
+
+// checkClassPlacement reports a class that Composer couldn't load from
+// where it is. loaded holds the classmap and autoload.files entries, which
+// Composer loads whatever their namespace.
+func checkClassPlacement(file string, sym PHPSymbol, maps []psrMapping, loaded []string) (AutoloadIssue, bool) {
+	for _, c := range loaded {
+		if c == "." || file == c || strings.HasPrefix(file, c+"/") {
+			return AutoloadIssue{}, false
+		}
+	}
+
+	var covering []psrMapping
+	for _, m := range maps {
+		if m.dir == "." || strings.HasPrefix(file, m.dir+"/") {
+			covering = append(covering, m)
+		}
+	}
+	for _, m := range covering {
+		if expectedPath(m, sym.FQN) == file {
+			return AutoloadIssue{}, false
+		}
+	}
+
+	// Where the class's namespace says it should be.
+	want := ""
+	for _, m := range maps {
+		if p := expectedPath(m, sym.FQN); p != "" {
+			want = p
+			break
+		}
+	}
+
+	issue := AutoloadIssue{File: file, Line: sym.Line, Class: sym.FQN}
+	switch {
+	case len(covering) > 0:
+		m := covering[0]
+		issue.Kind = "misplaced"
+		issue.Message = sym.Kind + " " + sym.FQN + " is in " + m.dir + "/, which composer.json maps to " + quotePrefix(m.prefix) + ", so Composer looks for it elsewhere"
+		if want != "" {
+			issue.Suggestion = "move it to " + want
+		}
+		if ns := expectedClass(m, file); ns != "" {
+			if issue.Suggestion != "" {
+				issue.Suggestion += ", or "
+			}
+			issue.Suggestion += "rename it to " + ns + " to match its location"
+		}
+	case want != "":
+		issue.Kind = "misplaced"
+		issue.Message = sym.Kind + " " + sym.FQN + " is outside every autoload directory"
+		issue.Suggestion = "move it to " + want + ", or add its directory to autoload.classmap"
+	default:
+		issue.Kind = "unmapped"
+		issue.Message = sym.Kind + " " + sym.FQN + " isn’t covered by any autoload mapping"
+		where := path.Dir(file) + "/"
+		if where == "./" {
+			where = file
+		}
+		issue.Suggestion = "add a psr-4 mapping for " + quotePrefix(namespaceOf(sym.FQN)) + " or add " + where + " to autoload.classmap"
+	}
+	return issue, true
+}
+
+// expectedClass is the class name a PSR-4 mapping gives a file, or "" for
+// PSR-0 mappings, where underscores make it ambiguous.
+func expectedClass(m psrMapping, file string) string {
+	if m.psr0 {
+		return ""
+	}
+	rel := strings.TrimSuffix(file, ".php")
+	if m.dir != "." {
+		rel = strings.TrimPrefix(rel, m.dir+"/")
+	}
+	return m.prefix + strings.ReplaceAll(rel, "/", `\`)
+}
+
+// namespaceOf returns "App\Http\" for "App\Http\Foo".
+func namespaceOf(fqn string) string {
+	if i := strings.LastIndex(fqn, `\`); i >= 0 {
+		return fqn[:i+1]
+	}
+	return ""
+}
+
+// checkImport reports a use import that nothing would load.
+func checkImport(u PHPUse, maps []psrMapping, packages []string, exists func(string) bool) (AutoloadIssue, bool) {
+	lower := strings.ToLower(u.Name)
+
+	owned := false
+	for _, m := range maps {
+		if m.prefix == "" || !strings.HasPrefix(lower, strings.ToLower(m.prefix)) {
+			continue
+		}
+		owned = true
+		if exists(expectedPath(m, u.Name)) {
+			return AutoloadIssue{}, false
+		}
+	}
+
+	if !owned {
+		if packages == nil {
+			// Without vendor/ there's no telling which packages provide what.
+			return AutoloadIssue{}, false
+		}
+		for _, prefix := range packages {
+			if strings.HasPrefix(lower, strings.ToLower(prefix)) {
+				return AutoloadIssue{}, false
+			}
+		}
+	}
+
+	issue := AutoloadIssue{
+		Kind:    "unresolved-use",
+		Line:    u.Line,
+		Class:   u.Name,
+		Message: "use " + u.Name + " doesn’t resolve to any file",
+	}
+	if owned {
+		for _, m := range maps {
+			if p := expectedPath(m, u.Name); p != "" && m.prefix != "" {
+				issue.Suggestion = "create " + p + ", or fix the import"
+				break
+			}
+		}
+	} else {
+		issue.Suggestion = "require the package that provides " + namespaceOf(u.Name) + ", or add a psr-4 mapping for it"
+	}
+	return issue, true
+}
+
+// installedPrefixes lists the PSR-4 and PSR-0 prefixes of the packages in
+// vendor/composer/installed.json, or nil when nothing is installed.
+func installedPrefixes(root string) []string {
+	data, err := os.ReadFile(filepath.Join(root, "vendor", "composer", "installed.json"))
+	if err != nil {
+		return nil
+	}
+
+	type pkg struct {
+		Autoload ComposerAutoload `json:"autoload"`
+	}
+	// Composer 2 wraps the list in {"packages": [...]}; Composer 1 doesn't.
+	var v2 struct {
+		Packages []pkg `json:"packages"`
+	}
+	var pkgs []pkg
+	if json.Unmarshal(data, &v2) == nil && v2.Packages != nil {
+		pkgs = v2.Packages
+	} else if json.Unmarshal(data, &pkgs) != nil {
+		return nil
+	}
+
+	out := make([]string, 0)
+	for _, p := range pkgs {
+		for prefix := range p.Autoload.PSR4 {
+			out = append(out, prefix)
+		}
+		for prefix := range p.Autoload.PSR0 {
+			out = append(out, prefix)
+		}
+	}
+	return out
+}
@@ -0,0 +1,258 @@
+package brain
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Stack is what RictusD could tell about a project's technology from its
+// manifest files and layout.
+type Stack struct {
+	Type       string   `json:"type"`       // "php", "go", "javascript", "python", "mixed" or "unknown"
+	Languages  []string `json:"languages"`  // e.g. "php", "go", "javascript", "typescript"
+	Frameworks []string `json:"frameworks"` // e.g. "laravel", "symfony", "slim", "mvc"
+	Tooling    []string `json:"tooling"`    // e.g. "composer", "make", "docker"
+}
+
+// composerManifest is the slice of composer.json DetectStack cares about.
+type composerManifest struct {
+	Require    map[string]string `json:"require"`
+	RequireDev map[string]string `json:"require-dev"`
+}
+
+// packageManifest is the slice of package.json DetectStack cares about.
+type packageManifest struct {
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+// DetectStack inspects manifest files at the project root (composer.json,
+// go.mod, package.json, pyproject.toml, Makefile, Dockerfile) and a few
+// layout hints to work out the project's languages and frameworks. It only
+// reads; it never fails, returning "unknown" when nothing is recognizable.
+func DetectStack(root string) Stack {
+	langs := make(map[string]struct{})
+	frameworks := make(map[string]struct{})
+	tooling := make(map[string]struct{})
+
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(root, rel))
+		return err == nil
+	}
+
+	// PHP / Composer.
+	if data, err := os.ReadFile(filepath.Join(root, "composer.json")); err == nil {
+		langs["php"] = struct{}{}
+		tooling["composer"] = struct{}{}
+
+		var cm composerManifest
+		if json.Unmarshal(data, &cm) == nil {
+			deps := mergeKeys(cm.Require, cm.RequireDev)
+			switch {
+			case hasKey(deps, "laravel/framework") || hasKey(deps, "laravel/lumen-framework"):
+				frameworks["laravel"] = struct{}{}
+			case hasKey(deps, "symfony/framework-bundle") || hasKey(deps, "symfony/http-kernel"):
+				frameworks["symfony"] = struct{}{}
+			}
+			if hasKey(deps, "slim/slim") {
+				frameworks["slim"] = struct{}{}
+			}
+		}
+	}
+	if exists("artisan") {
+		langs["php"] = struct{}{}
+		frameworks["laravel"] = struct{}{}
+	}
+	if exists("bin/console") && exists("config/bundles.php") {
+		frameworks["symfony"] = struct{}{}
+	}
+
+	// Go.
+	if exists("go.mod") {
+		langs["go"] = struct{}{}
+	}
+
+	// JavaScript / TypeScript.
+	if data, err := os.ReadFile(filepath.Join(root, "package.json")); err == nil {
+		langs["javascript"] = struct{}{}
+		tooling["npm"] = struct{}{}
+
+		var pm packageManifest
+		if json.Unmarshal(data, &pm) == nil {
+			deps := mergeKeys(pm.Dependencies, pm.DevDependencies)
+			if hasKey(deps, "typescript") {
+				langs["typescript"] = struct{}{}
+			}
+			for _, fw := range []string{"react", "vue", "express", "next", "svelte"} {
+				if hasKey(deps, fw) {
+					frameworks[fw] = struct{}{}
+				}
+			}
+		}
+	}
+	if exists("tsconfig.json") {
+		langs["typescript"] = struct{}{}
+	}
+
+	// Python.
+	for _, manifest := range []string{"pyproject.toml", "setup.py", "requirements.txt", "Pipfile"} {
+		data, err := os.ReadFile(filepath.Join(root, manifest))
+		if err != nil {
+			continue
+		}
+		langs["python"] = struct{}{}
+		lower := strings.ToLower(string(data))
+		for _, fw := range []string{"django", "flask", "fastapi"} {
+			if strings.Contains(lower, fw) {
+				frameworks[fw] = struct{}{}
+			}
+		}
+	}
+
+	// Build and runtime tooling.
+	if exists("Makefile") || exists("makefile") || exists("GNUmakefile") {
+		tooling["make"] = struct{}{}
+	}
+	if exists("Dockerfile") || exists("docker-compose.yml") || exists("docker-compose.yaml") || exists("compose.yaml") {
+		tooling["docker"] = struct{}{}
+	}
+
+	// No manifest for the language itself: fall back to what sits near the top.
+	// PHP counts even next to other manifests, since plain PHP apps rarely
+	// have a composer.json but often a package.json for their assets.
+	manifested := len(langs) > 0
+	for _, l := range shallowLanguages(root) {
+		if !manifested || l == "php" {
+			langs[l] = struct{}{}
+		}
+	}
+
+	// Hand-rolled PHP MVC: no framework, but the classic directory trio.
+	if _, php := langs["php"]; php && len(frameworks) == 0 && looksLikeMVC(root) {
+		frameworks["mvc"] = struct{}{}
+	}
+
+	st := Stack{
+		Languages:  sortedKeys(langs),
+		Frameworks: sortedKeys(frameworks),
+		Tooling:    sortedKeys(tooling),
+	}
+
+	primary := make([]string, 0, len(st.Languages))
+	for _, l := range st.Languages {
+		// TypeScript projects are JavaScript projects for typing purposes.
+		if l != "typescript" {
+			primary = append(primary, l)
+		}
+	}
+	switch len(primary) {
+	case 0:
+		st.Type = "unknown"
+	case 1:
+		st.Type = primary[0]
+	default:
+		st.Type = "mixed"
+	}
+
+	return st
+}
+
+// HasLanguage reports whether the stack includes a language.
+func (s Stack) HasLanguage(lang string) bool {
+	for _, l := range s.Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFramework reports whether the stack includes a framework.
+func (s Stack) HasFramework(fw string) bool {
+	for _, f := range s.Frameworks {
+		if f == fw {
+			return true
+		}
+	}
+	return false
+}
+
+// shallowLanguages looks at files in the root and one level down for known
+// source extensions.
+func shallowLanguages(root string) []string {
+	found := make(map[string]struct{})
+
+	var visit func(dir string, depth int)
+	visit = func(dir string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				if depth == 0 && !strings.HasPrefix(e.Name(), ".") && e.Name() != "vendor" && e.Name() != "node_modules" {
+					visit(filepath.Join(dir, e.Name()), depth+1)
+				}
+				continue
+			}
+			switch filepath.Ext(e.Name()) {
+			case ".php":
+				found["php"] = struct{}{}
+			case ".go":
+				found["go"] = struct{}{}
+			case ".js", ".mjs", ".cjs":
+				found["javascript"] = struct{}{}
+			case ".ts", ".tsx":
+				found["typescript"] = struct{}{}
+			case ".py":
+				found["python"] = struct{}{}
+			}
+		}
+	}
+	visit(root, 0)
+
+	return sortedKeys(found)
+}
+
+// looksLikeMVC reports whether the project has controller and view
+// directories in one of the usual places.
+func looksLikeMVC(root string) bool {
+	hasDir := func(names ...string) bool {
+		for _, n := range names {
+			if st, err := os.Stat(filepath.Join(root, n)); err == nil && st.IsDir() {
+				return true
+			}
+		}
+		return false
+	}
+
+	return hasDir("controllers", "app/controllers", "app/Controllers", "src/Controllers", "src/controllers") &&
+		hasDir("views", "app/views", "app/Views", "templates", "src/Views", "src/views")
+}
+
+func mergeKeys(maps ...map[string]string) map[string]string {
+	out := make(map[string]string)
+	for _, m := range maps {
+		for k, v := range m {
+			out[strings.ToLower(k)] = v
+		}
+	}
+	return out
+}
+
+func hasKey(m map[string]string, k string) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,146 @@
+package brain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDiscoverDepth is how many directory levels below the root Discover
+// searches when no depth is given.
+const DefaultDiscoverDepth = 3
+
+// repoMarkers are the files or directories that mark a repository root, in
+// the order they are reported.
+var repoMarkers = []string{".git", "composer.json", "go.mod"}
+
+// Discovered is a repository root found under a workspace directory.
+type Discovered struct {
+	Path   string `json:"path"`
+	Marker string `json:"marker"` // the first repoMarkers entry present
+	Stack  Stack  `json:"stack"`
+}
+
+// Discover walks root up to depth levels down and returns every directory
+// that looks like a repository root. It does not descend into a repository
+// once found, so vendored or nested checkouts are not reported twice.
+func Discover(root string, depth int) ([]Discovered, error) {
+	abs, err := filepath.Abs(ExpandHome(root))
+	if err != nil {
+		return nil, fmt.Errorf("resolve root: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("stat root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", abs)
+	}
+	if depth <= 0 {
+		depth = DefaultDiscoverDepth
+	}
+
+	out := make([]Discovered, 0)
+
+	var visit func(dir string, level int)
+	visit = func(dir string, level int) {
+		if marker := repoMarker(dir); marker != "" {
+			out = append(out, Discovered{
+				Path:   dir,
+				Marker: marker,
+				Stack:  DetectStack(dir),
+			})
+			return
+		}
+		if level >= depth {
+			return
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if !e.IsDir() || skipDiscoverDir(e.Name()) {
+				continue
+			}
+			visit(filepath.Join(dir, e.Name()), level+1)
+		}
+	}
+	visit(abs, 0)
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
+// Watch re-runs Discover on root every interval until ctx is cancelled and
+// calls found for each repository root that was not there on the previous
+// pass. The first pass only establishes the baseline.
+func Watch(ctx context.Context, root string, depth int, interval time.Duration, found func(Discovered)) {
+	seen := make(map[string]struct{})
+	if list, err := Discover(root, depth); err == nil {
+		for _, d := range list {
+			seen[d.Path] = struct{}{}
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		list, err := Discover(root, depth)
+		if err != nil {
+			continue
+		}
+		for _, d := range list {
+			if _, ok := seen[d.Path]; ok {
+				continue
+			}
+			seen[d.Path] = struct{}{}
+			found(d)
+		}
+	}
+}
+
+func repoMarker(dir string) string {
+	for _, m := range repoMarkers {
+		if _, err := os.Stat(filepath.Join(dir, m)); err == nil {
+			return m
+		}
+	}
+	return ""
+}
+
+// skipDiscoverDir reports directories that never hold projects of their own.
+func skipDiscoverDir(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	switch name {
+	case "vendor", "node_modules":
+		return true
+	}
+	return false
+}
+
+// ExpandHome turns a leading "~" into the user's home directory.
+func ExpandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
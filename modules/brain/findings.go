@@ -0,0 +1,293 @@
+package brain
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/core"
+	"rictusd/modules/law"
+)
+
+// Severity ranks a finding. The values are SARIF's result levels.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // broken: something will fail at runtime
+	SeverityWarning Severity = "warning" // breaks a rule or a convention
+	SeverityNote    Severity = "note"    // worth knowing, nothing is wrong
+)
+
+// Finding is one observation from any analyzer, in a shape editors and
+// code review tools can import.
+type Finding struct {
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file,omitempty"`     // project-relative; empty for project-wide findings
+	Line     int      `json:"line,omitempty"`     // 1-based; 0 when the whole file is meant
+	EndLine  int      `json:"end_line,omitempty"` // last line of the range, when it spans several
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix,omitempty"` // the suggested fix, in words
+	Law      string   `json:"law,omitempty"` // the lawbook rule the finding enforces
+}
+
+// Rule describes a rule ID for exports.
+type Rule struct {
+	ID       string   `json:"id"`
+	Summary  string   `json:"summary"`
+	Severity Severity `json:"severity"` // the default; a finding may differ
+}
+
+// Rules are the fixed rule IDs. Router Law, layer and composer rules are
+// named after their check or kind and described by RuleFor.
+var Rules = []Rule{
+	{"php/strict-types", "PHP file without declare(strict_types=1)", SeverityWarning},
+	{"php/docblock", "PHP file without a top-level docblock", SeverityNote},
+	{"php/unresolved-require", "require/include target that matches no file", SeverityError},
+	{"php/include-cycle", "PHP files that include each other in a cycle", SeverityWarning},
+	{"php/orphan", "PHP file that nothing includes and that isn't an entry point", SeverityNote},
+	{"php/unresolved-route", "route whose handler doesn't resolve", SeverityError},
+	{"go/parse-error", "Go file that doesn't parse", SeverityError},
+	{"go/import-cycle", "Go packages that import each other in a cycle", SeverityError},
+	{"go/undocumented", "exported Go declaration without a doc comment", SeverityNote},
+	{"go/unchecked-error", "call whose error result is dropped", SeverityWarning},
+	{"go/long-func", "Go function longer than " + strconv.Itoa(GoLongFunc) + " lines", SeverityWarning},
+	{"js/unresolved-import", "relative import that matches no file", SeverityError},
+	{"js/leftover", "console.log or debugger statement left in", SeverityWarning},
+	{"js/missing-header", "script that doesn't open with a comment", SeverityNote},
+	{"js/undeclared-dependency", "package imported without being declared in package.json", SeverityError},
+	{"js/unused-dependency", "dependency in package.json that nothing imports", SeverityWarning},
+	{"structure/empty", "project with no files", SeverityWarning},
+	{"structure/deep", "deeply nested directory tree", SeverityNote},
+	{"structure/mixed-languages", "PHP and Go in one tree", SeverityNote},
+	{"structure/stray-files", "many files in no tracked language", SeverityNote},
+	{"structure/flat", "many files in a shallow tree", SeverityNote},
+}
+
+// RuleFor describes a rule ID, including the ones derived from Router Law
+// checks, layer checks and composer issue kinds.
+func RuleFor(id string) Rule {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r
+		}
+	}
+	kind, name, _ := strings.Cut(id, "/")
+	switch kind {
+	case "router":
+		return Rule{id, "router does what Router Law forbids: " + name, SeverityWarning}
+	case "architecture":
+		return Rule{id, "layer does what the architecture forbids: " + name, SeverityWarning}
+	case "composer":
+		sev := SeverityError
+		if name == "misplaced" || name == "unmapped" {
+			sev = SeverityWarning
+		}
+		return Rule{id, "composer autoload problem: " + name, sev}
+	}
+	return Rule{ID: id, Summary: id, Severity: SeverityWarning}
+}
+
+// newFinding fills in the rule's default severity.
+func newFinding(rule, file string, line int, msg, fix string) Finding {
+	return Finding{RuleID: rule, Severity: RuleFor(rule).Severity, File: file, Line: line, Message: msg, Fix: fix}
+}
+
+// SortFindings orders findings by file, line and rule, project-wide ones
+// first.
+func SortFindings(fs []Finding) {
+	sort.SliceStable(fs, func(i, j int) bool {
+		a, b := fs[i], fs[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.RuleID < b.RuleID
+	})
+}
+
+// Findings converts the PHP scan.
+func (r PHPReport) Findings() []Finding {
+	out := make([]Finding, 0, len(r.NoStrict)+len(r.NoDoc)+len(r.MissingRequires))
+	for _, f := range r.NoStrict {
+		out = append(out, newFinding("php/strict-types", f, 1, "declare(strict_types=1) is missing",
+			"Add declare(strict_types=1); right after the opening <?php tag."))
+	}
+	for _, f := range r.NoDoc {
+		out = append(out, newFinding("php/docblock", f, 1, "the file has no top-level docblock",
+			"Add a /** ... */ block under the opening tag saying what the file is for."))
+	}
+	for _, mr := range r.MissingRequires {
+		out = append(out, newFinding("php/unresolved-require", mr.File, mr.Line, mr.Target+" doesn’t match a file",
+			"Point the require at an existing file, or create "+mr.Resolved+"."))
+	}
+	return out
+}
+
+// Findings converts the composer autoload check.
+func (r AutoloadReport) Findings() []Finding {
+	out := make([]Finding, 0, len(r.Issues))
+	for _, is := range r.Issues {
+		file := is.File
+		if file == "" {
+			file = "composer.json"
+		}
+		out = append(out, newFinding("composer/"+is.Kind, file, is.Line, is.Message, is.Suggestion))
+	}
+	return out
+}
+
+// RouterFindings converts a Router Law check of one router file, citing
+// the rule each finding breaks.
+func RouterFindings(file string, rl law.RouterLaw, hits []RouterFinding) []Finding {
+	name := "Router Law"
+	if rl.Version != "" {
+		name += " v" + rl.Version
+	}
+	out := make([]Finding, 0, len(hits))
+	for _, h := range hits {
+		f := newFinding("router/"+h.Check, file, h.Line, h.Message, "Move this out of the router into the code it dispatches to.")
+		f.Law = name + " rule " + strconv.Itoa(h.Rule) + ": " + h.RuleTitle
+		if h.Check == law.CheckLength || h.Check == law.CheckBranches {
+			f.Fix = "Split the routing into smaller route tables or move the logic into handlers."
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// Findings converts the route table's unresolved handlers.
+func (t RouteTable) Findings() []Finding {
+	out := make([]Finding, 0, t.Problems)
+	for _, r := range t.Routes {
+		if r.Problem != "" {
+			out = append(out, newFinding("php/unresolved-route", r.File, r.Line, r.Method+" "+r.Path+": "+r.Problem,
+				"Point the route at an existing handler."))
+		}
+	}
+	return out
+}
+
+// Findings converts the architecture check.
+func (r ArchReport) Findings() []Finding {
+	out := make([]Finding, 0, len(r.Violations))
+	for _, af := range r.Violations {
+		out = append(out, newFinding("architecture/"+af.Check, af.File, af.Line,
+			af.Message+" in a "+string(af.Role)+" file", "Move this into the layer responsible for it."))
+	}
+	return out
+}
+
+// Findings converts the include graph's cycles and orphans.
+func (g *IncludeGraph) Findings() []Finding {
+	out := make([]Finding, 0, len(g.Cycles)+len(g.Orphans))
+	for _, c := range g.Cycles {
+		out = append(out, newFinding("php/include-cycle", c[0], 0, "include cycle: "+strings.Join(c, " → "),
+			"Break the cycle by moving what both files need into a third one."))
+	}
+	for _, o := range g.Orphans {
+		out = append(out, newFinding("php/orphan", o, 0, "nothing includes this file and it isn’t an entry point",
+			"Delete it if it’s dead, or include it where it’s meant to be used."))
+	}
+	return out
+}
+
+// Findings converts the Go scan.
+func (r GoReport) Findings() []Finding {
+	out := make([]Finding, 0)
+	for _, is := range r.ParseErrors {
+		out = append(out, newFinding("go/parse-error", is.File, is.Line, is.Message, ""))
+	}
+	for _, c := range r.ImportCycles {
+		out = append(out, newFinding("go/import-cycle", "", 0, "import cycle: "+strings.Join(c, " → "),
+			"Move what the packages share into a package both can import."))
+	}
+	for _, is := range r.Undocumented {
+		out = append(out, newFinding("go/undocumented", is.File, is.Line, is.Message,
+			"Add a comment starting with "+is.Name[strings.LastIndex(is.Name, ".")+1:]+"."))
+	}
+	for _, is := range r.UncheckedErrors {
+		out = append(out, newFinding("go/unchecked-error", is.File, is.Line, is.Message,
+			"Handle the error, or assign it to _ to show it’s ignored on purpose."))
+	}
+	for _, is := range r.LongFuncs {
+		out = append(out, newFinding("go/long-func", is.File, is.Line, is.Message, "Split it into smaller functions."))
+	}
+	return out
+}
+
+// Findings converts the JavaScript scan.
+func (r JSReport) Findings() []Finding {
+	out := make([]Finding, 0)
+	for _, imp := range r.UnresolvedImports {
+		out = append(out, newFinding("js/unresolved-import", imp.File, imp.Line, imp.Spec+" doesn’t match a file",
+			"Fix the path, or add the missing module."))
+	}
+	for _, f := range r.NoDoc {
+		out = append(out, newFinding("js/missing-header", f, 1, "the file doesn’t open with a comment",
+			"Add a comment at the top saying what the script is for."))
+	}
+	for _, is := range r.Leftovers {
+		out = append(out, newFinding("js/leftover", is.File, is.Line, is.Message, "Remove it before shipping."))
+	}
+	for _, is := range r.UndeclaredDeps {
+		out = append(out, newFinding("js/undeclared-dependency", is.File, is.Line, is.Message,
+			"Add "+is.Name+" to package.json."))
+	}
+	for _, dep := range r.UnusedDeps {
+		out = append(out, newFinding("js/unused-dependency", "package.json", 0, dep+" is declared but nothing imports it",
+			"Remove it from dependencies, or move it to devDependencies if a tool needs it."))
+	}
+	return out
+}
+
+// findingsExts are the export files WriteFindings keeps per project under
+// data/maps, by format.
+var findingsExts = map[string]string{"json": ".findings.json", "sarif": ".sarif"}
+
+// FindingsReport is the JSON export of an analysis.
+type FindingsReport struct {
+	Project  string    `json:"project"`
+	Root     string    `json:"root"`
+	Findings []Finding `json:"findings"`
+}
+
+// EncodeFindings renders findings as "json" or "sarif".
+func EncodeFindings(p core.Project, format string, findings []Finding) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(FindingsReport{Project: p.Name, Root: p.Path, Findings: findings}, "", "  ")
+	case "sarif":
+		return SARIF(p.Path, findings)
+	}
+	return nil, fmt.Errorf("unknown findings format %q", format)
+}
+
+// WriteFindings saves findings next to the project map in the given format
+// and returns the file's path.
+func (m *Mapper) WriteFindings(p core.Project, format string, findings []Finding) (string, error) {
+	data, err := EncodeFindings(p, format, findings)
+	if err != nil {
+		return "", err
+	}
+
+	mapsDir := filepath.Join(m.core.Data, "maps")
+	if err := os.MkdirAll(mapsDir, 0o755); err != nil {
+		return "", fmt.Errorf("create maps dir: %w", err)
+	}
+	final := filepath.Join(mapsDir, p.Name+findingsExts[format])
+	tmp := final + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", fmt.Errorf("write temp findings: %w", err)
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return "", fmt.Errorf("rename findings: %w", err)
+	}
+	return final, nil
+}
@@ -0,0 +1,463 @@
+package brain
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/core"
+)
+
+// GoLongFunc is the most lines a Go function body may span before it is
+// reported as too long.
+const GoLongFunc = 80
+
+// GoIssue is one Go finding, tied to a file and line.
+type GoIssue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Name    string `json:"name,omitempty"` // the function, type or call involved
+	Message string `json:"message"`
+}
+
+// GoPackage is one package of the project.
+type GoPackage struct {
+	Path    string   `json:"path"` // import path, or the directory without a go.mod
+	Dir     string   `json:"dir"`  // project-relative
+	Name    string   `json:"name"`
+	Files   int      `json:"files"`   // test files not included
+	Imports []string `json:"imports"` // packages of this project it imports
+}
+
+// GoReport summarizes Go-level observations for a project, in the same
+// shape as PHPReport.
+type GoReport struct {
+	TotalFiles      int         `json:"total_files"`
+	Module          string      `json:"module"` // from go.mod, empty without one
+	Packages        []GoPackage `json:"packages"`
+	MissingDocHint  int         `json:"missing_doc_hint"` // exported declarations without a doc comment
+	SampleNoDoc     []string    `json:"sample_no_doc"`
+	Undocumented    []GoIssue   `json:"undocumented"`
+	ImportCycles    [][]string  `json:"import_cycles"`
+	UncheckedErrors []GoIssue   `json:"unchecked_errors"`
+	LongFuncs       []GoIssue   `json:"long_funcs"`
+	ParseErrors     []GoIssue   `json:"parse_errors"`
+	Skipped         SkipStats   `json:"skipped"` // left out by ignore rules
+}
+
+// GoScanner performs read-only Go analysis with go/parser; nothing is
+// built or type-checked.
+type GoScanner struct {
+	core *core.Core
+}
+
+// NewGoScanner constructs a GoScanner bound to the daemon core.
+func NewGoScanner(c *core.Core) *GoScanner {
+	return &GoScanner{core: c}
+}
+
+// uncheckedCalls are package functions whose error is easy to drop by
+// calling them as a statement.
+var uncheckedCalls = map[string]map[string]bool{
+	"os": {
+		"Remove": true, "RemoveAll": true, "Mkdir": true, "MkdirAll": true, "WriteFile": true,
+		"Rename": true, "Chmod": true, "Chdir": true, "Setenv": true, "Unsetenv": true, "Symlink": true, "Truncate": true,
+	},
+	"json":     {"Unmarshal": true},
+	"io":       {"Copy": true, "WriteString": true, "ReadFull": true},
+	"filepath": {"Walk": true, "WalkDir": true},
+	"http":     {"ListenAndServe": true, "ListenAndServeTLS": true},
+}
+
+// uncheckedMethods are methods whose only result is usually an error.
+var uncheckedMethods = map[string]bool{
+	"Encode": true, "Decode": true, "Sync": true, "Unmarshal": true,
+}
+
+// hintedMethods are methods that return an error on some types and nothing
+// on others, such as http.Flusher's Flush. They are only reported when the
+// receiver is a variable the file assigns from one of the listed
+// constructors or composite literals.
+var hintedMethods = map[string]map[string]bool{
+	"Flush":    {"bufio.NewWriter": true, "bufio.NewReadWriter": true, "gzip.NewWriter": true, "zlib.NewWriter": true, "flate.NewWriter": true},
+	"Run":      {"exec.Command": true, "exec.CommandContext": true},
+	"Execute":  {"template.New": true, "template.Must": true, "template.ParseFiles": true, "template.ParseGlob": true},
+	"Shutdown": {"http.Server": true},
+}
+
+// AnalyzeProject parses the project's Go files and reports packages,
+// undocumented exported API, import cycles, unchecked errors and long
+// functions.
+func (s *GoScanner) AnalyzeProject(p core.Project) (GoReport, error) {
+	return s.AnalyzeProjectContext(context.Background(), p)
+}
+
+// AnalyzeProjectContext is AnalyzeProject with cancellation.
+func (s *GoScanner) AnalyzeProjectContext(ctx context.Context, p core.Project) (GoReport, error) {
+	report := GoReport{
+		Packages:        make([]GoPackage, 0),
+		SampleNoDoc:     make([]string, 0),
+		Undocumented:    make([]GoIssue, 0),
+		ImportCycles:    make([][]string, 0),
+		UncheckedErrors: make([]GoIssue, 0),
+		LongFuncs:       make([]GoIssue, 0),
+		ParseErrors:     make([]GoIssue, 0),
+	}
+
+	idx, _, err := NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return report, fmt.Errorf("goscan: %w", err)
+	}
+	report.Skipped = idx.Skipped
+	report.Module = goModule(p.Path)
+
+	pkgs := make(map[string]*GoPackage)
+	imports := make(map[string]map[string]bool)
+	fset := token.NewFileSet()
+
+	for _, e := range idx.Lang("go") {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.TotalFiles++
+		if strings.HasSuffix(e.Path, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(p.Path, filepath.FromSlash(e.Path)), nil, parser.ParseComments)
+		if err != nil {
+			report.ParseErrors = append(report.ParseErrors, GoIssue{File: e.Path, Message: err.Error()})
+			if f == nil {
+				continue
+			}
+		}
+
+		dir := path.Dir(e.Path)
+		pkg, ok := pkgs[dir]
+		if !ok {
+			pkg = &GoPackage{Path: goImportPath(report.Module, dir), Dir: dir, Name: f.Name.Name}
+			pkgs[dir] = pkg
+			imports[pkg.Path] = make(map[string]bool)
+		}
+		pkg.Files++
+
+		for _, imp := range f.Imports {
+			ip, _ := strconv.Unquote(imp.Path.Value)
+			if report.Module != "" && (ip == report.Module || strings.HasPrefix(ip, report.Module+"/")) {
+				imports[pkg.Path][ip] = true
+			}
+		}
+
+		pos := func(n ast.Node) int { return fset.Position(n.Pos()).Line }
+		for _, u := range undocumented(f) {
+			report.Undocumented = append(report.Undocumented, GoIssue{File: e.Path, Line: pos(u.node), Name: u.name, Message: u.kind + " " + u.name + " has no doc comment"})
+		}
+		for _, fn := range f.Decls {
+			fd, ok := fn.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			start, end := fset.Position(fd.Body.Lbrace).Line, fset.Position(fd.Body.Rbrace).Line
+			if n := end - start - 1; n > GoLongFunc {
+				report.LongFuncs = append(report.LongFuncs, GoIssue{File: e.Path, Line: pos(fd), Name: funcName(fd),
+					Message: funcName(fd) + " is " + strconv.Itoa(n) + " lines long"})
+			}
+		}
+		for _, c := range uncheckedErrors(f) {
+			report.UncheckedErrors = append(report.UncheckedErrors, GoIssue{File: e.Path, Line: pos(c.call), Name: c.name,
+				Message: "the error from " + c.name + " is ignored"})
+		}
+	}
+
+	report.MissingDocHint = len(report.Undocumented)
+	for _, u := range report.Undocumented {
+		if len(report.SampleNoDoc) == 5 {
+			break
+		}
+		report.SampleNoDoc = append(report.SampleNoDoc, u.File+":"+strconv.Itoa(u.Line)+" "+u.Name)
+	}
+
+	dirs := make([]string, 0, len(pkgs))
+	for d := range pkgs {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		pkg := pkgs[d]
+		pkg.Imports = make([]string, 0, len(imports[pkg.Path]))
+		for ip := range imports[pkg.Path] {
+			pkg.Imports = append(pkg.Imports, ip)
+		}
+		sort.Strings(pkg.Imports)
+		report.Packages = append(report.Packages, *pkg)
+	}
+	report.ImportCycles = goImportCycles(report.Packages)
+
+	return report, nil
+}
+
+// goModule reads the module path from go.mod at the project root.
+func goModule(root string) string {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if rest, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+func goImportPath(module, dir string) string {
+	switch {
+	case module == "":
+		return dir
+	case dir == ".":
+		return module
+	}
+	return module + "/" + dir
+}
+
+// goImportCycles finds cycles in the import graph between the project's
+// own packages.
+func goImportCycles(pkgs []GoPackage) [][]string {
+	pos := make(map[string]int, len(pkgs))
+	for i, p := range pkgs {
+		pos[p.Path] = i
+	}
+	adj := make([][]int, len(pkgs))
+	for i, p := range pkgs {
+		for _, ip := range p.Imports {
+			if j, ok := pos[ip]; ok {
+				adj[i] = append(adj[i], j)
+			}
+		}
+	}
+
+	cycles := make([][]string, 0)
+	for _, scc := range stronglyConnected(adj) {
+		if len(scc) == 1 && !containsInt(adj[scc[0]], scc[0]) {
+			continue
+		}
+		cycle := make([]string, 0, len(scc)+1)
+		for _, i := range cyclePath(adj, scc) {
+			cycle = append(cycle, pkgs[i].Path)
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles
+}
+
+type goDecl struct {
+	node ast.Node
+	kind string
+	name string
+}
+
+// undocumented lists the exported declarations of a file that have no doc
+// comment. A comment on a grouped const/var/type block covers its members,
+// as does a line comment on a member of a group, and methods count only on
+// exported types.
+func undocumented(f *ast.File) []goDecl {
+	var out []goDecl
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() || d.Doc != nil {
+				continue
+			}
+			if d.Recv != nil && !ast.IsExported(recvType(d)) {
+				continue
+			}
+			kind := "func"
+			if d.Recv != nil {
+				kind = "method"
+			}
+			out = append(out, goDecl{d, kind, funcName(d)})
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT || d.Doc != nil {
+				continue
+			}
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					if sp.Name.IsExported() && sp.Doc == nil {
+						out = append(out, goDecl{sp, "type", sp.Name.Name})
+					}
+				case *ast.ValueSpec:
+					if sp.Doc != nil || (d.Lparen.IsValid() && sp.Comment != nil) {
+						continue
+					}
+					for _, n := range sp.Names {
+						if n.IsExported() {
+							out = append(out, goDecl{n, d.Tok.String(), n.Name})
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// recvType returns the name of a method's receiver type.
+func recvType(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return ""
+	}
+	t := fd.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func funcName(fd *ast.FuncDecl) string {
+	if r := recvType(fd); r != "" {
+		return r + "." + fd.Name.Name
+	}
+	return fd.Name.Name
+}
+
+type goCall struct {
+	call *ast.CallExpr
+	name string
+}
+
+// uncheckedErrors finds calls made as bare statements whose error result is
+// dropped. Without type information only well-known calls are recognised:
+// package functions such as os.Remove, methods such as Encode that return
+// nothing but an error, and hinted methods on receivers whose origin is
+// known. Deferred and go'd calls are left alone.
+func uncheckedErrors(f *ast.File) []goCall {
+	local := make(map[string]string) // local package name -> import path base
+	for _, imp := range f.Imports {
+		ip, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(ip)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		local[name] = path.Base(ip)
+	}
+	origins := receiverOrigins(f, local)
+
+	var out []goCall
+	ast.Inspect(f, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.ExprStmt)
+		if !ok {
+			return true
+		}
+		call, ok := stmt.X.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+			if pkg, ok := local[id.Name]; ok {
+				if uncheckedCalls[pkg][sel.Sel.Name] {
+					out = append(out, goCall{call, id.Name + "." + sel.Sel.Name})
+				}
+				return true
+			}
+		}
+		if uncheckedMethods[sel.Sel.Name] {
+			out = append(out, goCall{call, sel.Sel.Name})
+		} else if id, ok := sel.X.(*ast.Ident); ok && hintedMethods[sel.Sel.Name][origins[id.Name]] {
+			out = append(out, goCall{call, sel.Sel.Name})
+		}
+		return true
+	})
+	return out
+}
+
+// receiverOrigins maps variables in f to what they were first assigned
+// from, such as "bufio.NewWriter" for w := bufio.NewWriter(os.Stdout) or
+// "http.Server" for srv := &http.Server{...}. Scopes are not tracked.
+func receiverOrigins(f *ast.File, local map[string]string) map[string]string {
+	origins := make(map[string]string)
+	record := func(name *ast.Ident, value ast.Expr) {
+		if _, seen := origins[name.Name]; seen || name.Name == "_" {
+			return
+		}
+		if o := exprOrigin(value, local); o != "" {
+			origins[name.Name] = o
+		}
+	}
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 {
+				if id, ok := n.Lhs[0].(*ast.Ident); ok {
+					record(id, n.Rhs[0])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Values) == 1 && len(n.Names) > 0 {
+				record(n.Names[0], n.Values[0])
+			}
+		}
+		return true
+	})
+	return origins
+}
+
+// exprOrigin names the package function a call expression invokes, or the
+// package type a composite literal builds.
+func exprOrigin(e ast.Expr, local map[string]string) string {
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		e = u.X
+	}
+	var fun ast.Expr
+	switch e := e.(type) {
+	case *ast.CallExpr:
+		fun = e.Fun
+	case *ast.CompositeLit:
+		fun = e.Type
+	default:
+		return ""
+	}
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	pkg, ok := local[id.Name]
+	if !ok {
+		return ""
+	}
+	return pkg + "." + sel.Sel.Name
+}
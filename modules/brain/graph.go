@@ -0,0 +1,474 @@
+package brain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/core"
+)
+
+// GraphCentral is how many of the most connected files a graph lists.
+const GraphCentral = 5
+
+// GraphEdge is one resolved require/include.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Line int    `json:"line"`
+}
+
+// GraphNode is a PHP file with its fan-in (files including it) and fan-out
+// (files it includes).
+type GraphNode struct {
+	File   string `json:"file"`
+	FanIn  int    `json:"fan_in"`
+	FanOut int    `json:"fan_out"`
+	Entry  bool   `json:"entry,omitempty"`
+}
+
+// IncludeGraph is the require/include graph of a project's PHP files. It is
+// saved next to the project map as data/maps/<project>.graph.json.
+type IncludeGraph struct {
+	Project    string      `json:"project"`
+	Nodes      []GraphNode `json:"nodes"` // ordered by path
+	Edges      []GraphEdge `json:"edges"`
+	Unresolved int         `json:"unresolved"` // requires that point nowhere
+	Cycles     [][]string  `json:"cycles"`     // each a path ending where it starts
+	Orphans    []string    `json:"orphans"`
+	Central    []GraphNode `json:"central"` // up to GraphCentral, most connected first
+}
+
+// entryDirs hold files a web server or CLI runs directly.
+var entryDirs = []string{"public", "web", "www", "htdocs", "bin"}
+
+// isEntryPoint reports whether a file is run directly rather than included:
+// the configured router, a root or front-controller script, or a CLI tool.
+func isEntryPoint(rel string, cfg core.ProjectConfig) bool {
+	if cfg.Router != "" && path.Clean(filepath.ToSlash(cfg.Router)) == rel {
+		return true
+	}
+	if !strings.Contains(rel, "/") || path.Base(rel) == "artisan" {
+		return true
+	}
+	top := rel[:strings.IndexByte(rel, '/')]
+	for _, d := range entryDirs {
+		if top == d && strings.Count(rel, "/") == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadedByConvention reports whether something other than an include loads
+// the file: the autoloader for class files, and frameworks for routes,
+// config, views and tests.
+func loadedByConvention(e *FileEntry, autoloaded map[string]bool) bool {
+	if autoloaded[e.Path] {
+		return true
+	}
+	switch e.Role {
+	case RoleRouter, RoleConfig, RoleView:
+		return true
+	}
+	for _, s := range e.PHP.Symbols {
+		if isClassKind(s.Kind) {
+			return true
+		}
+	}
+	lower := strings.ToLower(e.Path)
+	return strings.HasPrefix(lower, "tests/") || strings.HasPrefix(lower, "test/") ||
+		strings.HasPrefix(lower, "database/") || strings.HasSuffix(lower, "test.php")
+}
+
+// IncludeGraphContext refreshes the index, builds the include graph and
+// saves it alongside the project map.
+func (m *Mapper) IncludeGraphContext(ctx context.Context, p core.Project) (*IncludeGraph, error) {
+	idx, _, err := NewIndexer(m.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return nil, fmt.Errorf("graph: %w", err)
+	}
+	g := buildIncludeGraph(p, idx)
+	if err := m.writeGraph(g); err != nil {
+		return g, err
+	}
+	return g, nil
+}
+
+func buildIncludeGraph(p core.Project, idx *ProjectIndex) *IncludeGraph {
+	cfg, _ := core.LoadProjectConfig(p.Path)
+
+	autoloaded := make(map[string]bool)
+	if cc, err := LoadComposer(p.Path); err == nil && cc != nil {
+		for _, f := range append(append([]string(nil), cc.Autoload.Files...), cc.AutoloadDev.Files...) {
+			autoloaded[f] = true
+		}
+	}
+
+	g := &IncludeGraph{
+		Project: p.Name,
+		Nodes:   make([]GraphNode, 0),
+		Edges:   make([]GraphEdge, 0),
+		Cycles:  make([][]string, 0),
+		Orphans: make([]string, 0),
+		Central: make([]GraphNode, 0),
+	}
+
+	files := idx.Lang("php")
+	pos := make(map[string]int, len(files))
+	for i, e := range files {
+		pos[e.Path] = i
+		g.Nodes = append(g.Nodes, GraphNode{File: e.Path, Entry: isEntryPoint(e.Path, cfg)})
+	}
+
+	adj := make([][]int, len(files))
+	for i, e := range files {
+		if e.PHP == nil {
+			continue
+		}
+		seen := make(map[int]bool)
+		for _, r := range e.PHP.Requires {
+			to, ok := resolveInIndex(idx, r, cfg.IncludePaths)
+			if !ok {
+				// Ignored directories such as vendor/ aren't indexed, so a
+				// require into one is looked up on disk, as analyze does.
+				if !resolveRequire(p.Path, r.Candidate) && !resolveOnIncludePath(p.Path, r.Target, cfg.IncludePaths) {
+					g.Unresolved++
+				}
+				continue
+			}
+			j, isPHP := pos[to]
+			if !isPHP {
+				continue
+			}
+			g.Edges = append(g.Edges, GraphEdge{From: e.Path, To: to, Line: r.Line})
+			if !seen[j] {
+				seen[j] = true
+				adj[i] = append(adj[i], j)
+				g.Nodes[i].FanOut++
+				g.Nodes[j].FanIn++
+			}
+		}
+	}
+
+	for i, e := range files {
+		n := g.Nodes[i]
+		if n.FanIn == 0 && !n.Entry && e.PHP != nil && !loadedByConvention(e, autoloaded) {
+			g.Orphans = append(g.Orphans, n.File)
+		}
+	}
+
+	for _, scc := range stronglyConnected(adj) {
+		if len(scc) == 1 && !containsInt(adj[scc[0]], scc[0]) {
+			continue
+		}
+		cycle := make([]string, 0)
+		for _, i := range cyclePath(adj, scc) {
+			cycle = append(cycle, files[i].Path)
+		}
+		g.Cycles = append(g.Cycles, cycle)
+	}
+	sort.Slice(g.Cycles, func(i, j int) bool { return g.Cycles[i][0] < g.Cycles[j][0] })
+
+	central := make([]GraphNode, 0)
+	for _, n := range g.Nodes {
+		if n.FanIn+n.FanOut > 0 {
+			central = append(central, n)
+		}
+	}
+	sort.SliceStable(central, func(i, j int) bool {
+		a, b := central[i], central[j]
+		if a.FanIn+a.FanOut != b.FanIn+b.FanOut {
+			return a.FanIn+a.FanOut > b.FanIn+b.FanOut
+		}
+		return a.FanIn > b.FanIn
+	})
+	if len(central) > GraphCentral {
+		central = central[:GraphCentral]
+	}
+	g.Central = central
+
+	return g
+}
+
+// resolveInIndex finds the indexed file a require points at, trying the
+// literal candidate, an implied ".php", and the configured include paths.
+func resolveInIndex(idx *ProjectIndex, r PHPRequire, includePaths []string) (string, bool) {
+	for _, c := range []string{r.Candidate, r.Candidate + ".php"} {
+		if idx.Has(c) {
+			return path.Clean(c), true
+		}
+	}
+	if r.Target == "" || strings.HasPrefix(r.Target, "/") || strings.HasPrefix(r.Target, "./") || strings.HasPrefix(r.Target, "../") {
+		return "", false
+	}
+	for _, inc := range includePaths {
+		for _, c := range []string{path.Join(inc, r.Target), path.Join(inc, r.Target+".php")} {
+			if idx.Has(c) {
+				return c, true
+			}
+		}
+	}
+	return "", false
+}
+
+// stronglyConnected returns the strongly connected components of a graph,
+// using Tarjan's algorithm.
+func stronglyConnected(adj [][]int) [][]int {
+	index := make([]int, len(adj))
+	low := make([]int, len(adj))
+	onStack := make([]bool, len(adj))
+	for i := range index {
+		index[i] = -1
+	}
+
+	var (
+		stack []int
+		out   [][]int
+		next  int
+	)
+
+	var visit func(v int)
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range adj[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+
+		if low[v] == index[v] {
+			var scc []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			sort.Ints(scc)
+			out = append(out, scc)
+		}
+	}
+
+	for v := range adj {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+	return out
+}
+
+// cyclePath finds one concrete cycle through the first node of a strongly
+// connected component, returned with the start repeated at the end.
+func cyclePath(adj [][]int, scc []int) []int {
+	in := make(map[int]bool, len(scc))
+	for _, v := range scc {
+		in[v] = true
+	}
+	start := scc[0]
+
+	// Breadth-first from start's successors back to start, inside the SCC.
+	prev := map[int]int{}
+	queue := []int{}
+	for _, w := range adj[start] {
+		if !in[w] {
+			continue
+		}
+		if w == start {
+			return []int{start, start}
+		}
+		if _, ok := prev[w]; !ok {
+			prev[w] = start
+			queue = append(queue, w)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range adj[v] {
+			if !in[w] {
+				continue
+			}
+			if w == start {
+				path := []int{start}
+				for u := v; u != start; u = prev[u] {
+					path = append(path, u)
+				}
+				// path is start, v, ..., first hop; reverse all but the start.
+				for i, j := 1, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return append(path, start)
+			}
+			if _, ok := prev[w]; !ok {
+				prev[w] = v
+				queue = append(queue, w)
+			}
+		}
+	}
+	return append(append([]int(nil), scc...), start)
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// DOT renders the graph in Graphviz format. Entry points are boxes, orphans
+// are dashed, and edges on a cycle are red.
+func (g *IncludeGraph) DOT() string {
+	onCycle := g.cycleEdges()
+	orphan := make(map[string]bool, len(g.Orphans))
+	for _, o := range g.Orphans {
+		orphan[o] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph " + strconv.Quote(g.Project) + " {\n")
+	b.WriteString("  rankdir=LR;\n  node [shape=ellipse, fontname=\"monospace\"];\n")
+	for _, n := range g.Nodes {
+		attrs := make([]string, 0, 2)
+		if n.Entry {
+			attrs = append(attrs, "shape=box")
+		}
+		if orphan[n.File] {
+			attrs = append(attrs, "style=dashed")
+		}
+		line := "  " + strconv.Quote(n.File)
+		if len(attrs) > 0 {
+			line += " [" + strings.Join(attrs, ", ") + "]"
+		}
+		b.WriteString(line + ";\n")
+	}
+	for _, e := range g.uniqueEdges() {
+		line := "  " + strconv.Quote(e.From) + " -> " + strconv.Quote(e.To)
+		if onCycle[e.From+"\x00"+e.To] {
+			line += " [color=red]"
+		}
+		b.WriteString(line + ";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (g *IncludeGraph) Mermaid() string {
+	id := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		id[n.File] = "n" + strconv.Itoa(i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		label := strings.ReplaceAll(n.File, `"`, "#quot;")
+		if n.Entry {
+			b.WriteString("  " + id[n.File] + "[\"" + label + "\"]\n")
+		} else {
+			b.WriteString("  " + id[n.File] + "(\"" + label + "\")\n")
+		}
+	}
+	onCycle := g.cycleEdges()
+	link := 0
+	var red []string
+	for _, e := range g.uniqueEdges() {
+		b.WriteString("  " + id[e.From] + " --> " + id[e.To] + "\n")
+		if onCycle[e.From+"\x00"+e.To] {
+			red = append(red, strconv.Itoa(link))
+		}
+		link++
+	}
+	if len(red) > 0 {
+		b.WriteString("  linkStyle " + strings.Join(red, ",") + " stroke:red\n")
+	}
+	if len(g.Orphans) > 0 {
+		ids := make([]string, 0, len(g.Orphans))
+		for _, o := range g.Orphans {
+			ids = append(ids, id[o])
+		}
+		b.WriteString("  classDef orphan stroke-dasharray: 4 4\n  class " + strings.Join(ids, ",") + " orphan\n")
+	}
+	return b.String()
+}
+
+// uniqueEdges drops repeated includes between the same two files.
+func (g *IncludeGraph) uniqueEdges() []GraphEdge {
+	seen := make(map[string]bool, len(g.Edges))
+	out := make([]GraphEdge, 0, len(g.Edges))
+	for _, e := range g.Edges {
+		k := e.From + "\x00" + e.To
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (g *IncludeGraph) cycleEdges() map[string]bool {
+	out := make(map[string]bool)
+	for _, c := range g.Cycles {
+		for i := 0; i+1 < len(c); i++ {
+			out[c[i]+"\x00"+c[i+1]] = true
+		}
+	}
+	return out
+}
+
+// graphExts are the files writeGraph keeps per project under data/maps.
+var graphExts = []string{".graph.json", ".dot", ".mmd"}
+
+// writeGraph saves the graph as JSON next to the project map, plus DOT and
+// Mermaid renderings for tools that want them.
+func (m *Mapper) writeGraph(g *IncludeGraph) error {
+	mapsDir := filepath.Join(m.core.Data, "maps")
+	if err := os.MkdirAll(mapsDir, 0o755); err != nil {
+		return fmt.Errorf("create maps dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode include graph: %w", err)
+	}
+
+	for i, content := range [][]byte{data, []byte(g.DOT()), []byte(g.Mermaid())} {
+		final := filepath.Join(mapsDir, g.Project+graphExts[i])
+		tmp := final + ".tmp"
+		if err := os.WriteFile(tmp, content, 0o644); err != nil {
+			return fmt.Errorf("write temp include graph: %w", err)
+		}
+		if err := os.Rename(tmp, final); err != nil {
+			return fmt.Errorf("rename include graph: %w", err)
+		}
+	}
+	return nil
+}
+
+// GraphFiles returns where a project's graph is saved: JSON, DOT and Mermaid.
+func (m *Mapper) GraphFiles(name string) []string {
+	out := make([]string, 0, len(graphExts))
+	for _, ext := range graphExts {
+		out = append(out, filepath.Join(m.core.Data, "maps", name+ext))
+	}
+	return out
+}
@@ -0,0 +1,245 @@
+package brain
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"rictusd/modules/core"
+)
+
+// DefaultIgnores are skipped in every project unless an ignore file
+// re-includes them with "!". They hold dependencies and generated state, not
+// the project's own code.
+var DefaultIgnores = []string{"vendor/", "node_modules/", "storage/", "cache/"}
+
+// IgnoreFiles are read from the project root, in order; later rules win.
+// .gitignore files in subdirectories are picked up during the walk.
+var IgnoreFiles = []string{".gitignore", ".rictusignore"}
+
+// SkipStats records what a walk left out because of ignore rules. Files
+// inside a skipped directory are not counted, since the walk never enters it.
+type SkipStats struct {
+	Dirs   int      `json:"dirs"`
+	Files  int      `json:"files"`
+	Sample []string `json:"sample,omitempty"` // a few skipped paths, directories first
+}
+
+// Total is the number of skipped directories and files.
+func (s SkipStats) Total() int { return s.Dirs + s.Files }
+
+// ignoreRule is one compiled gitignore line.
+type ignoreRule struct {
+	base    string // directory of the ignore file, project-relative ("" for root)
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	byName  bool // no slash in the pattern: match the basename at any depth
+}
+
+// Ignore decides which project paths a walk should skip. It follows
+// gitignore rules: the last matching pattern wins, "!" re-includes, a
+// trailing "/" matches directories only, patterns without a slash match at
+// any depth, and "**" spans directories. Exclude globs from the project's
+// .rictus config apply on top.
+type Ignore struct {
+	root  string
+	cfg   core.ProjectConfig
+	rules []ignoreRule
+	stats SkipStats
+}
+
+// NewIgnore loads the built-in defaults and the root ignore files.
+func NewIgnore(root string, cfg core.ProjectConfig) *Ignore {
+	ig := &Ignore{
+		root:  root,
+		cfg:   cfg,
+		stats: SkipStats{Sample: make([]string, 0)},
+	}
+
+	for _, pat := range DefaultIgnores {
+		ig.addPattern("", pat)
+	}
+	for _, name := range IgnoreFiles {
+		ig.loadFile("", filepath.Join(root, name))
+	}
+
+	return ig
+}
+
+// Skip reports whether a walk should leave rel out, counting it if so.
+// Directories that are kept have their own .gitignore loaded, so call Skip
+// for a directory before walking into it.
+func (ig *Ignore) Skip(rel string, isDir bool) bool {
+	rel = filepath.ToSlash(rel)
+	if rel == "." || rel == "" {
+		return false
+	}
+
+	if isDir && filepath.Base(rel) == ".git" {
+		return true
+	}
+
+	if ig.Match(rel, isDir) {
+		if isDir {
+			ig.stats.Dirs++
+		} else {
+			ig.stats.Files++
+		}
+		if len(ig.stats.Sample) < 5 {
+			if isDir {
+				rel += "/"
+			}
+			ig.stats.Sample = append(ig.stats.Sample, rel)
+		}
+		return true
+	}
+
+	if isDir {
+		ig.loadFile(rel, filepath.Join(ig.root, filepath.FromSlash(rel), ".gitignore"))
+	}
+	return false
+}
+
+// Match reports whether rel is ignored, without recording anything.
+func (ig *Ignore) Match(rel string, isDir bool) bool {
+	rel = filepath.ToSlash(rel)
+
+	ignored := false
+	for _, r := range ig.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		sub := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			sub = rel[len(r.base)+1:]
+		}
+		if r.byName {
+			sub = sub[strings.LastIndex(sub, "/")+1:]
+		}
+
+		if r.re.MatchString(sub) {
+			ignored = !r.negate
+		}
+	}
+
+	if !ignored && ig.cfg.Excluded(rel) {
+		ignored = true
+	}
+	return ignored
+}
+
+// Stats returns what has been skipped so far, directories first in the
+// sample.
+func (ig *Ignore) Stats() SkipStats {
+	out := ig.stats
+	out.Sample = append([]string(nil), ig.stats.Sample...)
+	sort.SliceStable(out.Sample, func(i, j int) bool {
+		return strings.HasSuffix(out.Sample[i], "/") && !strings.HasSuffix(out.Sample[j], "/")
+	})
+	return out
+}
+
+func (ig *Ignore) loadFile(base, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ig.addPattern(base, scanner.Text())
+	}
+}
+
+// addPattern compiles one gitignore line. Blank lines and comments are
+// ignored; unsupported patterns are dropped rather than guessed at.
+func (ig *Ignore) addPattern(base, line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	r := ignoreRule{base: base}
+
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	// A slash anywhere but the end anchors the pattern to the ignore file's
+	// directory; without one it matches a name at any depth.
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		r.byName = true
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return
+	}
+	r.re = re
+
+	ig.rules = append(ig.rules, r)
+}
+
+// globToRegexp translates gitignore glob syntax into a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Leading or inner "**/": zero or more directories.
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			// Trailing "**": everything below.
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package brain
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"rictusd/modules/core"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"*.log", "debug.log", true},
+		{"*.log", "logs/debug.log", false},
+		{"debug?.log", "debug1.log", true},
+		{"debug?.log", "debug10.log", false},
+		{"debug[0-9].log", "debug7.log", true},
+		{"debug[!0-9].log", "debug7.log", false},
+		{"debug[!0-9].log", "debugx.log", true},
+		{"**/logs", "logs", true},
+		{"**/logs", "a/b/logs", true},
+		{"logs/**", "logs/a/b.txt", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/xb", false},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+		{"file.php", "fileXphp", false},
+		{"[abc", "[abc", true},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile("^" + globToRegexp(tt.glob) + "$")
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("glob %q against %q = %v; want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreMatch(t *testing.T) {
+	root := t.TempDir()
+	gitignore := "# comment\n*.log\n!keep.log\nbuild/\n/top.txt\ndocs/*.md\n\\#hash\n"
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte(gitignore), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".rictusignore"), []byte("!vendor/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ig := NewIgnore(root, core.ProjectConfig{})
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"src/deep/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"top.txt", false, true},
+		{"src/top.txt", false, false},
+		{"docs/a.md", false, true},
+		{"docs/sub/a.md", false, false},
+		{"#hash", false, true},
+		{"node_modules", true, true},
+		{"vendor", true, false},
+		{"src/app.php", false, false},
+	}
+	for _, tt := range tests {
+		if got := ig.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, dir=%v) = %v; want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreNestedGitignore(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", ".gitignore"), []byte("/local.php\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ig := NewIgnore(root, core.ProjectConfig{})
+	if ig.Skip("sub", true) {
+		t.Fatal("sub should not be skipped")
+	}
+	if !ig.Skip("sub/local.php", false) {
+		t.Error("sub/local.php should be skipped by sub/.gitignore")
+	}
+	if ig.Skip("local.php", false) {
+		t.Error("local.php at the root should not be skipped by sub/.gitignore")
+	}
+	if st := ig.Stats(); st.Files != 1 || st.Dirs != 0 {
+		t.Errorf("Stats() = %+v; want one skipped file", st)
+	}
+}
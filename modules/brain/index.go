@@ -0,0 +1,427 @@
+package brain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"rictusd/modules/core"
+)
+
+// FileEntry is what the index knows about one project file.
+type FileEntry struct {
+	Path    string       `json:"path"` // project-relative, slash-separated
+	Size    int64        `json:"size"`
+	ModTime int64        `json:"mtime"` // unix nanoseconds
+	Hash    string       `json:"hash"`  // sha256 of the content
+	Lang    string       `json:"lang,omitempty"`
+	Role    Role         `json:"role"`
+	Pinned  bool         `json:"pinned,omitempty"` // Role comes from the project's config
+	PHP     *PHPFileScan `json:"php,omitempty"`
+
+	Lines     LineCounts `json:"lines"`               // zero for binary files
+	Binary    bool       `json:"binary,omitempty"`    // NUL bytes near the start
+	Generated bool       `json:"generated,omitempty"` // minified, lock file or marked as generated
+}
+
+// PHPFileScan holds the per-file PHP observations. Require targets are kept
+// raw; whether they resolve depends on the rest of the tree, so that is
+// decided when the index is queried.
+type PHPFileScan struct {
+	HasStrict bool         `json:"has_strict"`
+	HasDoc    bool         `json:"has_doc"`
+	Requires  []PHPRequire `json:"requires,omitempty"`
+	Symbols   []PHPSymbol  `json:"symbols,omitempty"`
+	Uses      []PHPUse     `json:"uses,omitempty"`
+	Refs      []PHPRef     `json:"refs,omitempty"`
+}
+
+// PHPRequire is one literal require/include target found in a file.
+type PHPRequire struct {
+	Line      int    `json:"line"`
+	Target    string `json:"target"`    // as written
+	Candidate string `json:"candidate"` // project-relative path it points at
+}
+
+// indexVersion is bumped whenever FileEntry gains fields or the scanners
+// change what they record; an index with another version is rebuilt from
+// scratch.
+const indexVersion = 8
+
+// ProjectIndex is the persistent per-project file index kept under
+// data/index/<project>.json.
+type ProjectIndex struct {
+	Version   int                   `json:"version"`
+	Project   string                `json:"project"`
+	Root      string                `json:"root"`
+	UpdatedAt string                `json:"updated_at"`
+	Dirs      []string              `json:"dirs"` // every walked directory, "." included
+	Files     map[string]*FileEntry `json:"files"`
+	Skipped   SkipStats             `json:"skipped"`
+}
+
+// RefreshStats says how much work a refresh did.
+type RefreshStats struct {
+	Files     int           `json:"files"`
+	Reused    int           `json:"reused"`    // unchanged size and mtime
+	Rehashed  int           `json:"rehashed"`  // mtime moved, content the same
+	Rescanned int           `json:"rescanned"` // new or changed content
+	Removed   int           `json:"removed"`
+	Took      time.Duration `json:"took"`
+}
+
+// Has reports whether the index holds a file.
+func (ix *ProjectIndex) Has(rel string) bool {
+	_, ok := ix.Files[filepath.ToSlash(rel)]
+	return ok
+}
+
+// Sorted returns the entries ordered by path.
+func (ix *ProjectIndex) Sorted() []*FileEntry {
+	out := make([]*FileEntry, 0, len(ix.Files))
+	for _, e := range ix.Files {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// Lang returns the entries for one language, ordered by path.
+func (ix *ProjectIndex) Lang(lang string) []*FileEntry {
+	out := make([]*FileEntry, 0)
+	for _, e := range ix.Sorted() {
+		if e.Lang == lang {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Indexer maintains project indexes. Indexers are shared per data directory,
+// so the Mapper, the PHPScanner and the patch engine all see one cache.
+type Indexer struct {
+	core *core.Core
+	dir  string
+
+	mu     sync.Mutex
+	cache  map[string]*ProjectIndex // project name -> index
+	locks  map[string]*sync.Mutex   // project name -> refresh lock
+	active map[string]Progress      // project name -> running refresh
+}
+
+var (
+	indexersMu sync.Mutex
+	indexers   = make(map[string]*Indexer)
+)
+
+// NewIndexer returns the Indexer for the daemon's data directory.
+func NewIndexer(c *core.Core) *Indexer {
+	indexersMu.Lock()
+	defer indexersMu.Unlock()
+
+	if ix, ok := indexers[c.Data]; ok {
+		return ix
+	}
+	ix := &Indexer{
+		core:   c,
+		dir:    filepath.Join(c.Data, "index"),
+		cache:  make(map[string]*ProjectIndex),
+		locks:  make(map[string]*sync.Mutex),
+		active: make(map[string]Progress),
+	}
+	indexers[c.Data] = ix
+	return ix
+}
+
+// Refresh brings a project's index up to date and returns it. Files whose
+// size and mtime are unchanged are reused without being read; the others are
+// hashed, and only files whose content actually changed are rescanned.
+// The returned index must be treated as read-only.
+func (x *Indexer) Refresh(p core.Project) (*ProjectIndex, RefreshStats, error) {
+	return x.RefreshContext(context.Background(), p, nil)
+}
+
+// RefreshContext is Refresh with cancellation and progress reporting. A
+// cancelled refresh leaves the previous index in place. progress may be nil.
+func (x *Indexer) RefreshContext(ctx context.Context, p core.Project, progress ProgressFunc) (*ProjectIndex, RefreshStats, error) {
+	lock := x.projectLock(p.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	x.mu.Lock()
+	prev := x.load(p)
+	x.mu.Unlock()
+
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		x.core.Log.Warnf("index: %s: %v; using defaults", p.Name, err)
+	}
+
+	next, stats, err := x.scan(ctx, p, prev, NewIgnore(p.Path, cfg), cfg.Architecture, progress)
+	if err != nil {
+		return prev, stats, err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.cache[p.Name] = next
+
+	changed := stats.Rescanned+stats.Rehashed+stats.Removed > 0 || len(prev.Dirs) != len(next.Dirs) || prev.Skipped.Total() != next.Skipped.Total()
+	if changed {
+		if err := x.save(next); err != nil {
+			return next, stats, err
+		}
+	}
+
+	x.core.Log.Infof("index: %s refreshed in %s (files=%d reused=%d rehashed=%d rescanned=%d removed=%d)",
+		p.Name, stats.Took, stats.Files, stats.Reused, stats.Rehashed, stats.Rescanned, stats.Removed)
+
+	return next, stats, nil
+}
+
+// projectLock serializes refreshes of one project while letting different
+// projects refresh side by side.
+func (x *Indexer) projectLock(name string) *sync.Mutex {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	l, ok := x.locks[name]
+	if !ok {
+		l = &sync.Mutex{}
+		x.locks[name] = l
+	}
+	return l
+}
+
+// Entry refreshes and returns a single file's entry without walking the
+// project. It is meant for commands that only care about one file.
+func (x *Indexer) Entry(p core.Project, rel string) (*FileEntry, error) {
+	lock := x.projectLock(p.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	key := filepath.ToSlash(filepath.Clean(rel))
+	full, err := core.ResolveProjectPath(p.Path, key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", rel)
+	}
+
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		x.core.Log.Warnf("index: %s: %v; using defaults", p.Name, err)
+	}
+
+	idx := x.load(p)
+	old := idx.Files[key]
+	base := keepable(old, cfg.Architecture)
+	if base != nil && base.Size == info.Size() && base.ModTime == info.ModTime().UnixNano() && pinRole(base, cfg.Architecture) == base {
+		return base, nil
+	}
+
+	entry, _, err := x.indexFile(p.Path, key, info, base)
+	if err != nil {
+		return nil, err
+	}
+	entry = pinRole(entry, cfg.Architecture)
+
+	// Only files the walk would have indexed belong in the index. Indexes
+	// handed out by Refresh are never modified, so swap in a copy.
+	if old != nil {
+		upd := *idx
+		upd.Files = make(map[string]*FileEntry, len(idx.Files))
+		for k, v := range idx.Files {
+			upd.Files[k] = v
+		}
+		upd.Files[key] = entry
+		x.cache[p.Name] = &upd
+		if err := x.save(&upd); err != nil {
+			x.core.Log.Warnf("index: %v", err)
+		}
+	}
+	return entry, nil
+}
+
+// Forget drops a project's index from memory and disk.
+func (x *Indexer) Forget(name string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	delete(x.cache, name)
+	if err := os.Remove(x.path(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("index: remove: %w", err)
+	}
+	return nil
+}
+
+// Rename moves a project's index to its new name.
+func (x *Indexer) Rename(oldName, newName string) error {
+	if oldName == newName {
+		return nil
+	}
+	for _, name := range []string{oldName, newName} {
+		lock := x.projectLock(name)
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	// Indexes handed out by Refresh are never modified, so cache a copy.
+	if idx, ok := x.cache[oldName]; ok {
+		delete(x.cache, oldName)
+		upd := *idx
+		upd.Project = newName
+		x.cache[newName] = &upd
+	}
+	if err := os.Rename(x.path(oldName), x.path(newName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("index: rename: %w", err)
+	}
+	return nil
+}
+
+// indexFile reads, hashes and, when the content changed, scans one file.
+// rehashed is true when old could be kept apart from its size and mtime.
+func (x *Indexer) indexFile(root, key string, info fs.FileInfo, old *FileEntry) (*FileEntry, bool, error) {
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(key)))
+	if err != nil {
+		return nil, false, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if old != nil && old.Hash == hash {
+		e := *old
+		e.Size = info.Size()
+		e.ModTime = info.ModTime().UnixNano()
+		return &e, true, nil
+	}
+
+	e := &FileEntry{
+		Path:      key,
+		Size:      info.Size(),
+		ModTime:   info.ModTime().UnixNano(),
+		Hash:      hash,
+		Lang:      LangOf(key),
+		Role:      ClassifyPath(key),
+		Binary:    IsBinary(data),
+		Generated: IsGenerated(key, data),
+	}
+	if e.Binary {
+		e.Lang = ""
+		return e, false, nil
+	}
+	e.Lines = CountLines(e.Lang, data)
+	if e.Lang == "php" {
+		scan := scanPHP(data, key)
+		e.PHP = &scan
+	}
+	e.Role = ClassifyFile(key, data, e.PHP)
+	return e, false, nil
+}
+
+// pinnedRole returns the role the project's config pins for a file.
+func pinnedRole(key string, arch core.ArchitectureConfig) (Role, bool) {
+	name, ok := arch.RoleFor(key)
+	if !ok {
+		return RoleUnknown, false
+	}
+	return ParseRole(name)
+}
+
+// pinRole applies the config's pinned role to an entry, copying the entry
+// when that changes it.
+func pinRole(e *FileEntry, arch core.ArchitectureConfig) *FileEntry {
+	if e == nil {
+		return nil
+	}
+	r, ok := pinnedRole(e.Path, arch)
+	if !ok || (e.Pinned && e.Role == r) {
+		return e
+	}
+	pinned := *e
+	pinned.Role, pinned.Pinned = r, true
+	return &pinned
+}
+
+// keepable returns old unless its role was pinned by config that no longer
+// pins it; such an entry is rescanned to get its classified role back.
+func keepable(old *FileEntry, arch core.ArchitectureConfig) *FileEntry {
+	if old != nil && old.Pinned {
+		if _, ok := pinnedRole(old.Path, arch); !ok {
+			return nil
+		}
+	}
+	return old
+}
+
+// load returns the cached index, reading it from disk on first use. An index
+// for another root (the project was relocated) is discarded.
+func (x *Indexer) load(p core.Project) *ProjectIndex {
+	if idx, ok := x.cache[p.Name]; ok && idx.Root == p.Path {
+		return idx
+	}
+
+	empty := &ProjectIndex{Version: indexVersion, Project: p.Name, Root: p.Path, Files: make(map[string]*FileEntry)}
+
+	data, err := os.ReadFile(x.path(p.Name))
+	if err != nil {
+		return empty
+	}
+	var idx ProjectIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		x.core.Log.Warnf("index: %s: unreadable index, rebuilding: %v", p.Name, err)
+		return empty
+	}
+	if idx.Root != p.Path || idx.Files == nil || idx.Version != indexVersion {
+		return empty
+	}
+
+	x.cache[p.Name] = &idx
+	return &idx
+}
+
+func (x *Indexer) save(idx *ProjectIndex) error {
+	if err := os.MkdirAll(x.dir, 0o755); err != nil {
+		return fmt.Errorf("index: create dir: %w", err)
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("index: encode: %w", err)
+	}
+
+	final := x.path(idx.Project)
+	tmp := final + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("index: write temp: %w", err)
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return fmt.Errorf("index: rename: %w", err)
+	}
+	return nil
+}
+
+func (x *Indexer) path(name string) string {
+	return filepath.Join(x.dir, name+".json")
+}
@@ -0,0 +1,264 @@
+package brain
+
+import (
+	"bytes"
+	"strings"
+)
+
+// JSTokenKind classifies a JavaScript or TypeScript token.
+type JSTokenKind int
+
+const (
+	JSComment  JSTokenKind = iota // // and /* */ comments, and a leading #! line
+	JSString                      // '...' or "..."
+	JSTemplate                    // `...`, with any ${...} inside
+	JSRegex                       // /.../flags
+	JSName                        // identifiers and keywords
+	JSNumber                      // numeric literals
+	JSPunct                       // operators and punctuation
+)
+
+// JSToken is one lexeme of a JavaScript or TypeScript file. Whitespace is
+// not kept.
+type JSToken struct {
+	Kind JSTokenKind
+	Text string // the source text, quotes and markers included
+	Line int    // 1-based line the token starts on
+}
+
+// Is reports whether t is a name or punctuation matching text.
+func (t JSToken) Is(text string) bool {
+	return (t.Kind == JSName || t.Kind == JSPunct) && t.Text == text
+}
+
+// StringValue returns the contents of a JSString token, or of a template
+// without substitutions, with quotes removed and the common escapes
+// resolved. ok is false for any other token.
+func (t JSToken) StringValue() (string, bool) {
+	if len(t.Text) < 2 {
+		return "", false
+	}
+	switch {
+	case t.Kind == JSString:
+	case t.Kind == JSTemplate && !strings.Contains(t.Text, "${"):
+	default:
+		return "", false
+	}
+	body := t.Text[1 : len(t.Text)-1]
+	return strings.NewReplacer(`\\`, `\`, `\'`, `'`, `\"`, `"`, "\\`", "`", `\n`, "\n", `\t`, "\t").Replace(body), true
+}
+
+// jsOps are the multi-character operators, longest first, so the lexer can
+// take the longest match.
+var jsOps = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=",
+	"*=", "/=", "%=", "&=", "|=", "^=", "**", "<<", ">>",
+}
+
+// jsRegexAfter are the keywords after which a slash starts a regular
+// expression rather than a division.
+var jsRegexAfter = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "yield": true, "await": true, "instanceof": true,
+}
+
+// LexJS splits JavaScript or TypeScript source into tokens. It understands
+// comments, quoted strings, template literals and regular expression
+// literals, so names inside them are never mistaken for code. JSX text is
+// lexed as if it were code; a quote in it ends at the line's end rather
+// than swallowing the file. Malformed input never fails.
+func LexJS(src []byte) []JSToken {
+	lx := &jsLexer{src: src, line: 1}
+	if bytes.HasPrefix(src, []byte("#!")) {
+		end := bytes.IndexByte(src, '\n')
+		if end < 0 {
+			end = len(src)
+		}
+		lx.emit(JSComment, end)
+	}
+	lx.run()
+	return lx.toks
+}
+
+// JSCode returns the tokens that carry code, dropping comments.
+func JSCode(toks []JSToken) []JSToken {
+	out := make([]JSToken, 0, len(toks))
+	for _, t := range toks {
+		if t.Kind != JSComment {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+type jsLexer struct {
+	src  []byte
+	pos  int
+	line int
+	toks []JSToken
+	last JSToken // the previous code token, to tell regexes from division
+	seen bool    // whether there was one
+}
+
+func (lx *jsLexer) emit(kind JSTokenKind, end int) {
+	text := string(lx.src[lx.pos:end])
+	lx.toks = append(lx.toks, JSToken{Kind: kind, Text: text, Line: lx.line})
+	if kind != JSComment {
+		lx.last, lx.seen = lx.toks[len(lx.toks)-1], true
+	}
+	lx.line += strings.Count(text, "\n")
+	lx.pos = end
+}
+
+func (lx *jsLexer) run() {
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		rest := lx.src[lx.pos:]
+
+		switch {
+		case isPHPSpace(c):
+			if c == '\n' {
+				lx.line++
+			}
+			lx.pos++
+
+		case bytes.HasPrefix(rest, []byte("//")):
+			end := bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			lx.emit(JSComment, lx.pos+end)
+
+		case bytes.HasPrefix(rest, []byte("/*")):
+			end := bytes.Index(rest[2:], []byte("*/"))
+			if end < 0 {
+				end = len(lx.src)
+			} else {
+				end = lx.pos + 2 + end + 2
+			}
+			lx.emit(JSComment, end)
+
+		case c == '\'' || c == '"':
+			lx.emit(JSString, lx.quoted(c))
+
+		case c == '`':
+			lx.emit(JSTemplate, lx.template())
+
+		case c == '/' && lx.regexAllowed():
+			if end, ok := lx.regex(); ok {
+				lx.emit(JSRegex, end)
+			} else {
+				lx.emit(JSPunct, lx.pos+1)
+			}
+
+		case isPHPNameStart(c) || c == '$':
+			end := lx.pos
+			for end < len(lx.src) && (isPHPNameByte(lx.src[end]) || lx.src[end] == '$') {
+				end++
+			}
+			lx.emit(JSName, end)
+
+		case c >= '0' && c <= '9':
+			end := lx.pos
+			for end < len(lx.src) && (isPHPNameByte(lx.src[end]) || lx.src[end] == '.') {
+				end++
+			}
+			lx.emit(JSNumber, end)
+
+		default:
+			end := lx.pos + 1
+			for _, op := range jsOps {
+				if bytes.HasPrefix(rest, []byte(op)) {
+					end = lx.pos + len(op)
+					break
+				}
+			}
+			lx.emit(JSPunct, end)
+		}
+	}
+}
+
+// quoted returns the end of a string opened by q at lx.pos. Strings cannot
+// span lines, so an unterminated one stops at the newline.
+func (lx *jsLexer) quoted(q byte) int {
+	i := lx.pos + 1
+	for i < len(lx.src) {
+		switch lx.src[i] {
+		case '\\':
+			i += 2
+			continue
+		case q:
+			return i + 1
+		case '\n':
+			return i
+		}
+		i++
+	}
+	return len(lx.src)
+}
+
+// template returns the end of a template literal opened at lx.pos,
+// stepping over its ${...} substitutions.
+func (lx *jsLexer) template() int {
+	i := lx.pos + 1
+	for i < len(lx.src) {
+		switch c := lx.src[i]; {
+		case c == '\\':
+			i += 2
+			continue
+		case c == '`':
+			return i + 1
+		case c == '$' && i+1 < len(lx.src) && lx.src[i+1] == '{':
+			i = skipBraces(lx.src, i+1)
+			continue
+		}
+		i++
+	}
+	return len(lx.src)
+}
+
+// regexAllowed reports whether a slash at lx.pos starts a regular
+// expression: at the start of the file, after an operator, or after a
+// keyword that takes an expression.
+func (lx *jsLexer) regexAllowed() bool {
+	switch {
+	case !lx.seen:
+		return true
+	case lx.last.Kind == JSName:
+		return jsRegexAfter[lx.last.Text]
+	case lx.last.Kind == JSPunct:
+		return lx.last.Text != ")" && lx.last.Text != "]" && lx.last.Text != "}"
+	}
+	return false
+}
+
+// regex returns the end of a regular expression literal at lx.pos, flags
+// included. ok is false when the line ends first.
+func (lx *jsLexer) regex() (int, bool) {
+	i := lx.pos + 1
+	class := false
+	for i < len(lx.src) {
+		switch lx.src[i] {
+		case '\\':
+			i += 2
+			continue
+		case '\n':
+			return 0, false
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				i++
+				for i < len(lx.src) && isPHPNameByte(lx.src[i]) {
+					i++
+				}
+				return i, true
+			}
+		}
+		i++
+	}
+	return 0, false
+}
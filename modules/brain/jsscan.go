@@ -0,0 +1,360 @@
+package brain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rictusd/modules/core"
+)
+
+// JSImport is one module a JavaScript or TypeScript file pulls in.
+type JSImport struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Spec     string `json:"spec"`               // the specifier as written
+	Kind     string `json:"kind"`               // "import", "export", "dynamic" or "require"
+	Resolved string `json:"resolved,omitempty"` // the project file a relative specifier points at
+}
+
+// JSIssue is one JavaScript finding, tied to a file and line.
+type JSIssue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Name    string `json:"name,omitempty"` // the package or statement involved
+	Message string `json:"message"`
+}
+
+// JSReport summarizes JavaScript and TypeScript observations for a
+// project, in the same shape as PHPReport.
+type JSReport struct {
+	TotalFiles        int        `json:"total_files"`
+	HasManifest       bool       `json:"has_manifest"` // a package.json at the project root
+	Imports           []JSImport `json:"imports"`
+	UnresolvedImports []JSImport `json:"unresolved_imports"` // relative specifiers that match no file
+	MissingDocHint    int        `json:"missing_doc_hint"`   // files that don't open with a comment
+	SampleNoDoc       []string   `json:"sample_no_doc"`
+	NoDoc             []string   `json:"no_doc"`          // every file without a leading comment
+	Leftovers         []JSIssue  `json:"leftovers"`       // console.log and debugger statements
+	UnusedDeps        []string   `json:"unused_deps"`     // declared in dependencies but never imported
+	UndeclaredDeps    []JSIssue  `json:"undeclared_deps"` // imported but not in package.json
+	Skipped           SkipStats  `json:"skipped"`         // left out by ignore rules
+}
+
+// JSScanner performs read-only JavaScript and TypeScript analysis.
+type JSScanner struct {
+	core *core.Core
+}
+
+// NewJSScanner constructs a JSScanner bound to the daemon core.
+func NewJSScanner(c *core.Core) *JSScanner {
+	return &JSScanner{core: c}
+}
+
+// jsResolveExts are tried, in order, after a relative specifier that
+// doesn't name a file outright.
+var jsResolveExts = []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".tsx", ".json", ".vue", ".css"}
+
+// jsLeftovers are the console methods that are debugging residue.
+var jsLeftovers = map[string]bool{"log": true, "debug": true, "trace": true}
+
+// nodeBuiltins are the Node.js core modules, which need no declaration.
+var nodeBuiltins = map[string]bool{
+	"assert": true, "buffer": true, "child_process": true, "cluster": true, "crypto": true, "dns": true,
+	"events": true, "fs": true, "http": true, "http2": true, "https": true, "module": true, "net": true,
+	"os": true, "path": true, "perf_hooks": true, "process": true, "querystring": true, "readline": true,
+	"stream": true, "string_decoder": true, "timers": true, "tls": true, "url": true, "util": true,
+	"vm": true, "worker_threads": true, "zlib": true,
+}
+
+// jsManifest is the part of package.json the analyzer reads.
+type jsManifest struct {
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+func (m jsManifest) declares(pkg string) bool {
+	for _, deps := range []map[string]string{m.Dependencies, m.DevDependencies, m.PeerDependencies, m.OptionalDependencies} {
+		if _, ok := deps[pkg]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeProject scans the project's JavaScript and TypeScript files for
+// imports, leftover debugging statements and missing header comments, and
+// checks the imports against package.json.
+func (s *JSScanner) AnalyzeProject(p core.Project) (JSReport, error) {
+	return s.AnalyzeProjectContext(context.Background(), p)
+}
+
+// AnalyzeProjectContext is AnalyzeProject with cancellation.
+func (s *JSScanner) AnalyzeProjectContext(ctx context.Context, p core.Project) (JSReport, error) {
+	report := JSReport{
+		Imports:           make([]JSImport, 0),
+		UnresolvedImports: make([]JSImport, 0),
+		SampleNoDoc:       make([]string, 0),
+		NoDoc:             make([]string, 0),
+		Leftovers:         make([]JSIssue, 0),
+		UnusedDeps:        make([]string, 0),
+		UndeclaredDeps:    make([]JSIssue, 0),
+	}
+
+	idx, _, err := NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return report, fmt.Errorf("jsscan: %w", err)
+	}
+	report.Skipped = idx.Skipped
+
+	var manifest jsManifest
+	if data, err := os.ReadFile(filepath.Join(p.Path, "package.json")); err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			s.core.Log.Warnf("jsscan: package.json in %s: %v", p.Name, err)
+		}
+		report.HasManifest = true
+	}
+	aliases := jsAliases(p.Path)
+
+	files := append(idx.Lang("javascript"), idx.Lang("typescript")...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	used := make(map[string]bool)
+	for _, e := range files {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if e.Generated || strings.HasSuffix(e.Path, ".d.ts") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(p.Path, filepath.FromSlash(e.Path)))
+		if err != nil {
+			continue
+		}
+		report.TotalFiles++
+
+		toks := LexJS(data)
+		head := toks
+		if len(head) > 0 && strings.HasPrefix(head[0].Text, "#!") {
+			head = head[1:]
+		}
+		if len(head) > 0 && head[0].Kind != JSComment {
+			report.MissingDocHint++
+			report.NoDoc = append(report.NoDoc, e.Path)
+			if len(report.SampleNoDoc) < 5 {
+				report.SampleNoDoc = append(report.SampleNoDoc, e.Path)
+			}
+		}
+
+		code := JSCode(toks)
+		report.Leftovers = append(report.Leftovers, jsLeftoversIn(code, e.Path)...)
+
+		for _, imp := range jsImports(code, e.Path) {
+			switch {
+			case strings.HasPrefix(imp.Spec, "./") || strings.HasPrefix(imp.Spec, "../") || imp.Spec == "." || imp.Spec == "..":
+				imp.Resolved, _ = resolveJSImport(idx, e.Path, imp.Spec)
+				if imp.Resolved == "" {
+					report.UnresolvedImports = append(report.UnresolvedImports, imp)
+				}
+			case jsExternal(imp.Spec, aliases):
+				pkg := jsPackage(imp.Spec)
+				if report.HasManifest && !used[pkg] && !manifest.declares(pkg) && !manifest.declares("@types/"+pkg) {
+					report.UndeclaredDeps = append(report.UndeclaredDeps, JSIssue{File: imp.File, Line: imp.Line, Name: pkg,
+						Message: pkg + " is imported but not declared in package.json"})
+				}
+				used[pkg] = true
+			}
+			report.Imports = append(report.Imports, imp)
+		}
+	}
+
+	for pkg := range manifest.Dependencies {
+		if !used[pkg] && !strings.HasPrefix(pkg, "@types/") {
+			report.UnusedDeps = append(report.UnusedDeps, pkg)
+		}
+	}
+	sort.Strings(report.UnusedDeps)
+
+	return report, nil
+}
+
+// jsImports finds the static imports, re-exports, dynamic imports and
+// require() calls with a literal specifier.
+func jsImports(code []JSToken, rel string) []JSImport {
+	var out []JSImport
+	add := func(t JSToken, kind string) {
+		if spec, ok := t.StringValue(); ok && spec != "" {
+			out = append(out, JSImport{File: rel, Line: t.Line, Spec: spec, Kind: kind})
+		}
+	}
+	at := func(i int) JSToken {
+		if i < len(code) {
+			return code[i]
+		}
+		return JSToken{}
+	}
+
+	for i, t := range code {
+		if t.Kind != JSName || (i > 0 && (code[i-1].Is(".") || code[i-1].Is("?."))) {
+			continue
+		}
+		switch t.Text {
+		case "import":
+			switch next := at(i + 1); {
+			case next.Kind == JSString:
+				add(next, "import")
+			case next.Is("("):
+				if at(i+3).Is(")") || at(i+3).Is(",") {
+					add(at(i+2), "dynamic")
+				}
+			case next.Is("."):
+				// import.meta
+			default:
+				if j := jsFrom(code, i+1); j > 0 {
+					add(code[j], "import")
+				}
+			}
+
+		case "export":
+			if next := at(i + 1); next.Is("*") || next.Is("{") || next.Is("type") {
+				if j := jsFrom(code, i+1); j > 0 {
+					add(code[j], "export")
+				}
+			}
+
+		case "require":
+			if at(i+1).Is("(") && at(i+3).Is(")") {
+				add(at(i+2), "require")
+			}
+		}
+	}
+	return out
+}
+
+// jsFrom returns the index of the specifier after the "from" that closes
+// the import or export clause starting at i, or -1 when the statement ends
+// without one.
+func jsFrom(code []JSToken, i int) int {
+	for j := i; j < len(code)-1 && j < i+200; j++ {
+		t := code[j]
+		if t.Is("from") && code[j+1].Kind == JSString {
+			return j + 1
+		}
+		if t.Is(";") || t.Is("=") || t.Is("(") {
+			return -1
+		}
+		if j > i && t.Kind == JSName && (t.Text == "import" || t.Text == "export" || t.Text == "const" ||
+			t.Text == "let" || t.Text == "var" || t.Text == "function" || t.Text == "class") {
+			return -1
+		}
+	}
+	return -1
+}
+
+// jsLeftoversIn finds console.log-style calls and debugger statements.
+func jsLeftoversIn(code []JSToken, rel string) []JSIssue {
+	var out []JSIssue
+	for i, t := range code {
+		if t.Kind != JSName || (i > 0 && code[i-1].Is(".")) {
+			continue
+		}
+		switch {
+		case t.Text == "debugger":
+			out = append(out, JSIssue{File: rel, Line: t.Line, Name: "debugger", Message: "a debugger statement is left in"})
+		case t.Text == "console" && i+2 < len(code) && code[i+1].Is(".") && jsLeftovers[code[i+2].Text]:
+			name := "console." + code[i+2].Text
+			out = append(out, JSIssue{File: rel, Line: t.Line, Name: name, Message: name + "() is left in"})
+		}
+	}
+	return out
+}
+
+// resolveJSImport finds the project file a relative specifier points at,
+// trying the extensions and index files a bundler would.
+func resolveJSImport(idx *ProjectIndex, from, spec string) (string, bool) {
+	if q := strings.IndexAny(spec, "?#"); q >= 0 {
+		spec = spec[:q]
+	}
+	base := path.Join(path.Dir(from), spec)
+	if strings.HasPrefix(base, "../") || base == ".." {
+		return "", false
+	}
+
+	candidates := []string{base}
+	// TypeScript sources import their compiled names: "./x.js" is x.ts.
+	if stem, ok := strings.CutSuffix(base, ".js"); ok {
+		candidates = append(candidates, stem+".ts", stem+".tsx")
+	}
+	for _, ext := range jsResolveExts {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range jsResolveExts {
+		candidates = append(candidates, base+"/index"+ext)
+	}
+	for _, c := range candidates {
+		if idx.Has(c) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// jsExternal reports whether a specifier names a package: not a path, URL,
+// Node built-in, or alias from the project's tsconfig or jsconfig.
+func jsExternal(spec string, aliases []string) bool {
+	if strings.HasPrefix(spec, "/") || strings.HasPrefix(spec, "node:") || strings.HasPrefix(spec, "#") ||
+		strings.HasPrefix(spec, "~/") || strings.HasPrefix(spec, "@/") || strings.Contains(spec, "://") {
+		return false
+	}
+	if nodeBuiltins[jsPackage(spec)] {
+		return false
+	}
+	for _, a := range aliases {
+		if spec == strings.TrimSuffix(a, "/") || strings.HasPrefix(spec, a) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsPackage returns the package part of a bare specifier: the first path
+// segment, or the first two for a scoped package.
+func jsPackage(spec string) string {
+	parts := strings.SplitN(spec, "/", 3)
+	if strings.HasPrefix(spec, "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// jsAliases reads the path aliases declared under compilerOptions.paths in
+// tsconfig.json or jsconfig.json, as prefixes. Files with comments, which
+// TypeScript allows, are skipped.
+func jsAliases(root string) []string {
+	var out []string
+	for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		var cfg struct {
+			CompilerOptions struct {
+				Paths map[string][]string `json:"paths"`
+			} `json:"compilerOptions"`
+		}
+		if json.Unmarshal(data, &cfg) != nil {
+			continue
+		}
+		for key := range cfg.CompilerOptions.Paths {
+			out = append(out, strings.TrimSuffix(key, "*"))
+		}
+	}
+	return out
+}
@@ -0,0 +1,245 @@
+package brain
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// LineCounts splits a file's lines into code, comments and blanks. A line
+// with any code on it counts as code, even if it also carries a comment.
+type LineCounts struct {
+	Code    int `json:"code"`
+	Comment int `json:"comment"`
+	Blank   int `json:"blank"`
+}
+
+// Total is the number of lines counted.
+func (lc LineCounts) Total() int { return lc.Code + lc.Comment + lc.Blank }
+
+// Add returns the sum of two counts.
+func (lc LineCounts) Add(o LineCounts) LineCounts {
+	return LineCounts{Code: lc.Code + o.Code, Comment: lc.Comment + o.Comment, Blank: lc.Blank + o.Blank}
+}
+
+// langSpec describes a language: the extensions that identify it and its
+// comment syntax.
+type langSpec struct {
+	name  string
+	exts  []string
+	line  []string    // line comment markers
+	block [][2]string // block comment open/close pairs
+}
+
+var cStyle = [][2]string{{"/*", "*/"}}
+
+// languages is the extension table the index and the mapper use. Only source
+// and markup languages are listed; anything else has no language.
+var languages = []langSpec{
+	{name: "php", exts: []string{".php"}, line: []string{"//", "#"}, block: cStyle},
+	{name: "go", exts: []string{".go"}, line: []string{"//"}, block: cStyle},
+	{name: "javascript", exts: []string{".js", ".mjs", ".cjs", ".jsx"}, line: []string{"//"}, block: cStyle},
+	{name: "typescript", exts: []string{".ts", ".tsx"}, line: []string{"//"}, block: cStyle},
+	{name: "python", exts: []string{".py"}, line: []string{"#"}},
+	{name: "ruby", exts: []string{".rb"}, line: []string{"#"}},
+	{name: "shell", exts: []string{".sh", ".bash"}, line: []string{"#"}},
+	{name: "c", exts: []string{".c", ".h"}, line: []string{"//"}, block: cStyle},
+	{name: "java", exts: []string{".java"}, line: []string{"//"}, block: cStyle},
+	{name: "rust", exts: []string{".rs"}, line: []string{"//"}, block: cStyle},
+	{name: "sql", exts: []string{".sql"}, line: []string{"--"}, block: cStyle},
+	{name: "css", exts: []string{".css", ".scss", ".less"}, block: cStyle},
+	{name: "html", exts: []string{".html", ".htm"}, block: [][2]string{{"<!--", "-->"}}},
+	{name: "xml", exts: []string{".xml"}, block: [][2]string{{"<!--", "-->"}}},
+	{name: "yaml", exts: []string{".yml", ".yaml"}, line: []string{"#"}},
+	{name: "json", exts: []string{".json"}},
+	{name: "markdown", exts: []string{".md", ".markdown"}},
+}
+
+var langByExt = func() map[string]*langSpec {
+	out := make(map[string]*langSpec)
+	for i := range languages {
+		for _, ext := range languages[i].exts {
+			out[ext] = &languages[i]
+		}
+	}
+	return out
+}()
+
+// LangOf names the language of a file by its extension, or "" when it is
+// not a language RictusD tracks.
+func LangOf(rel string) string {
+	if spec, ok := langByExt[strings.ToLower(filepath.Ext(rel))]; ok {
+		return spec.name
+	}
+	return ""
+}
+
+// CountLines classifies each line of a file in the given language. Comment
+// markers inside string literals are not recognised; the counts are meant
+// for proportions, not exact figures.
+func CountLines(lang string, data []byte) LineCounts {
+	var spec *langSpec
+	for i := range languages {
+		if languages[i].name == lang {
+			spec = &languages[i]
+			break
+		}
+	}
+
+	var lc LineCounts
+	closer := "" // non-empty while inside a block comment
+
+	for len(data) > 0 {
+		var raw []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			raw, data = data[:i], data[i+1:]
+		} else {
+			raw, data = data, nil
+		}
+
+		line := strings.TrimSpace(string(raw))
+		if line == "" {
+			if closer != "" {
+				lc.Comment++
+			} else {
+				lc.Blank++
+			}
+			continue
+		}
+		if spec == nil {
+			lc.Code++
+			continue
+		}
+
+		code, rest := false, line
+		for rest != "" {
+			if closer != "" {
+				end := strings.Index(rest, closer)
+				if end < 0 {
+					rest = ""
+					break
+				}
+				rest = strings.TrimSpace(rest[end+len(closer):])
+				closer = ""
+				continue
+			}
+			if hasAnyPrefix(rest, spec.line) {
+				break
+			}
+			if open, cl, ok := blockOpen(rest, spec.block); ok {
+				rest = rest[len(open):]
+				closer = cl
+				continue
+			}
+			code = true
+			// Skip to the next possible comment start on this line.
+			next := nextMarker(rest[1:], spec)
+			if next < 0 {
+				break
+			}
+			rest = strings.TrimSpace(rest[1+next:])
+		}
+
+		if code {
+			lc.Code++
+		} else {
+			lc.Comment++
+		}
+	}
+
+	return lc
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func blockOpen(s string, pairs [][2]string) (string, string, bool) {
+	for _, p := range pairs {
+		if strings.HasPrefix(s, p[0]) {
+			return p[0], p[1], true
+		}
+	}
+	return "", "", false
+}
+
+// nextMarker finds the earliest block comment opener in s, or -1. Line
+// comments after code don't change how the line is counted, so only block
+// openers matter here.
+func nextMarker(s string, spec *langSpec) int {
+	best := -1
+	for _, p := range spec.block {
+		if i := strings.Index(s, p[0]); i >= 0 && (best < 0 || i < best) {
+			best = i
+		}
+	}
+	return best
+}
+
+// binarySniff is how much of a file IsBinary looks at, the same amount git
+// uses for its own check.
+const binarySniff = 8000
+
+// IsBinary reports whether data looks binary: a NUL byte near the start.
+func IsBinary(data []byte) bool {
+	if len(data) > binarySniff {
+		data = data[:binarySniff]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// generatedNames are files produced by tools rather than written by hand.
+var generatedNames = []string{
+	"composer.lock", "package-lock.json", "yarn.lock", "pnpm-lock.yaml", "go.sum",
+}
+
+// generatedSuffixes mark minified bundles, source maps and codegen output.
+var generatedSuffixes = []string{
+	".min.js", ".min.css", ".js.map", ".css.map", ".pb.go", "_generated.go", ".generated.php",
+}
+
+// generatedMarkers are header comments code generators leave behind.
+var generatedMarkers = []string{
+	"code generated", "do not edit", "@generated", "auto-generated", "autogenerated",
+}
+
+// IsGenerated reports whether a file was produced by a tool: a lock file, a
+// minified bundle, or a file whose header says it was generated. Long lines
+// on average also give minified code away.
+func IsGenerated(rel string, data []byte) bool {
+	lower := strings.ToLower(rel)
+	base := lower[strings.LastIndex(lower, "/")+1:]
+	for _, n := range generatedNames {
+		if base == n {
+			return true
+		}
+	}
+	for _, suf := range generatedSuffixes {
+		if strings.HasSuffix(base, suf) {
+			return true
+		}
+	}
+
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	headLower := strings.ToLower(string(head))
+	for _, mk := range generatedMarkers {
+		if strings.Contains(headLower, mk) {
+			return true
+		}
+	}
+
+	switch LangOf(rel) {
+	case "javascript", "css":
+		lines := bytes.Count(data, []byte{'\n'}) + 1
+		return len(data) > 4096 && len(data)/lines > 500
+	}
+	return false
+}
@@ -1,11 +1,12 @@
 package brain
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"rictusd/modules/core"
@@ -13,18 +14,45 @@ import (
 
 // ProjectMap is a high-level structural summary of a project.
 type ProjectMap struct {
-	Name        string   `json:"name"`
-	Path        string   `json:"path"`
-	TotalFiles  int      `json:"total_files"`
-	TotalDirs   int      `json:"total_dirs"`
-	Languages   []string `json:"languages"`
-	PHPFiles    int      `json:"php_files"`
-	GoFiles     int      `json:"go_files"`
-	JSFiles     int      `json:"js_files"`
-	OtherFiles  int      `json:"other_files"`
-	MaxDepth    int      `json:"max_depth"`
-	RootEntries int      `json:"root_entries"`
-	HasReadme   bool     `json:"has_readme"`
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	TotalFiles  int       `json:"total_files"`
+	TotalDirs   int       `json:"total_dirs"`
+	Languages   []string  `json:"languages"`
+	PHPFiles    int       `json:"php_files"`
+	GoFiles     int       `json:"go_files"`
+	JSFiles     int       `json:"js_files"`
+	OtherFiles  int       `json:"other_files"`
+	MaxDepth    int       `json:"max_depth"`
+	RootEntries int       `json:"root_entries"`
+	HasReadme   bool      `json:"has_readme"`
+	Skipped     SkipStats `json:"skipped"` // left out by ignore rules
+
+	Lines          map[string]LineCounts `json:"lines"`       // per language, generated files left out
+	TotalLines     LineCounts            `json:"total_lines"` // sum of Lines
+	TotalBytes     int64                 `json:"total_bytes"`
+	BinaryFiles    int                   `json:"binary_files"`
+	GeneratedFiles int                   `json:"generated_files"`
+	Largest        []FileSize            `json:"largest"`     // up to MapLargest, biggest first
+	Directories    []DirStats            `json:"directories"` // top-level directories, "." for root files
+}
+
+// MapLargest is how many of the largest files a ProjectMap lists.
+const MapLargest = 10
+
+// FileSize is a file and its size in bytes.
+type FileSize struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// DirStats breaks a ProjectMap down by top-level directory.
+type DirStats struct {
+	Path      string     `json:"path"`
+	Files     int        `json:"files"`
+	Bytes     int64      `json:"bytes"`
+	Lines     LineCounts `json:"lines"`
+	Languages []string   `json:"languages"`
 }
 
 // Mapper performs read-only mapping of project directory structures.
@@ -37,9 +65,38 @@ func NewMapper(c *core.Core) *Mapper {
 	return &Mapper{core: c}
 }
 
-// MapProject walks the project's directory tree, builds a ProjectMap, and
-// writes it to data/maps/<project-name>.json.
+// MapProject builds a ProjectMap from the project index, refreshing the
+// index first, and writes it to data/maps/<project-name>.json.
 func (m *Mapper) MapProject(p core.Project) (ProjectMap, error) {
+	return m.MapProjectContext(context.Background(), p)
+}
+
+// MapProjectContext is MapProject with cancellation.
+func (m *Mapper) MapProjectContext(ctx context.Context, p core.Project) (ProjectMap, error) {
+	pm, idx, err := m.BuildMapContext(ctx, p)
+	if err != nil {
+		return pm, err
+	}
+
+	if err := m.writeMap(pm); err != nil {
+		return pm, err
+	}
+	if _, err := m.snapshot(p, pm, idx); err != nil {
+		m.core.Log.Warnf("mapper: %v", err)
+	}
+	if err := m.writeGraph(buildIncludeGraph(p, idx)); err != nil {
+		m.core.Log.Warnf("mapper: %v", err)
+	}
+
+	m.core.Log.Infof("mapper: mapped project %q at %s (files=%d, dirs=%d, has_readme=%v, skipped=%d)",
+		pm.Name, pm.Path, pm.TotalFiles, pm.TotalDirs, pm.HasReadme, pm.Skipped.Total())
+
+	return pm, nil
+}
+
+// BuildMapContext builds a ProjectMap from the refreshed index without
+// writing it, a snapshot or the include graph.
+func (m *Mapper) BuildMapContext(ctx context.Context, p core.Project) (ProjectMap, *ProjectIndex, error) {
 	pm := ProjectMap{
 		Name: p.Name,
 		Path: p.Path,
@@ -47,7 +104,7 @@ func (m *Mapper) MapProject(p core.Project) (ProjectMap, error) {
 
 	info, err := os.ReadDir(p.Path)
 	if err != nil {
-		return pm, fmt.Errorf("read root dir: %w", err)
+		return pm, nil, fmt.Errorf("read root dir: %w", err)
 	}
 	pm.RootEntries = len(info)
 
@@ -64,76 +121,113 @@ func (m *Mapper) MapProject(p core.Project) (ProjectMap, error) {
 	}
 	pm.HasReadme = hasReadme
 
-	langs := make(map[string]struct{})
+	idx, _, err := NewIndexer(m.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return pm, nil, fmt.Errorf("map project: %w", err)
+	}
 
-	maxDepth := 0
-	err = filepath.WalkDir(p.Path, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			// Log and continue rather than aborting the entire walk.
-			m.core.Log.Warnf("mapper: walk error on %s: %v", path, err)
-			return nil
-		}
+	fillMap(&pm, idx)
+	return pm, idx, nil
+}
 
-		// Skip hidden .git/.svn/etc if desired; for now, skip .git.
-		if d.IsDir() && d.Name() == ".git" {
-			return filepath.SkipDir
-		}
+// fillMap derives the structural counts of a ProjectMap from the index.
+func fillMap(pm *ProjectMap, idx *ProjectIndex) {
+	langs := make(map[string]struct{})
+	pm.Lines = make(map[string]LineCounts)
 
-		rel, relErr := filepath.Rel(p.Path, path)
-		if relErr == nil {
-			depth := depthOf(rel)
-			if depth > maxDepth {
-				maxDepth = depth
-			}
-		}
+	type dirAcc struct {
+		stats DirStats
+		langs map[string]struct{}
+	}
+	dirs := make(map[string]*dirAcc)
 
-		if d.IsDir() {
-			pm.TotalDirs++
-			return nil
+	maxDepth := 0
+	for _, dir := range idx.Dirs {
+		pm.TotalDirs++
+		if depth := depthOf(filepath.FromSlash(dir)); depth > maxDepth {
+			maxDepth = depth
 		}
+	}
 
+	sizes := make([]FileSize, 0, len(idx.Files))
+	for _, e := range idx.Files {
 		pm.TotalFiles++
+		pm.TotalBytes += e.Size
+		if depth := depthOf(filepath.FromSlash(e.Path)); depth > maxDepth {
+			maxDepth = depth
+		}
+		sizes = append(sizes, FileSize{Path: e.Path, Size: e.Size})
 
-		ext := filepath.Ext(d.Name())
-		switch ext {
-		case ".php":
+		switch e.Lang {
+		case "php":
 			pm.PHPFiles++
-			langs["php"] = struct{}{}
-		case ".go":
+		case "go":
 			pm.GoFiles++
-			langs["go"] = struct{}{}
-		case ".js":
+		case "javascript":
 			pm.JSFiles++
-			langs["javascript"] = struct{}{}
 		default:
 			pm.OtherFiles++
-			if ext != "" {
-				langs[ext] = struct{}{}
-			}
 		}
 
-		return nil
-	})
+		top := "."
+		if i := strings.IndexByte(e.Path, '/'); i >= 0 {
+			top = e.Path[:i]
+		}
+		acc, ok := dirs[top]
+		if !ok {
+			acc = &dirAcc{stats: DirStats{Path: top}, langs: make(map[string]struct{})}
+			dirs[top] = acc
+		}
+		acc.stats.Files++
+		acc.stats.Bytes += e.Size
 
-	if err != nil {
-		return pm, fmt.Errorf("walk project: %w", err)
+		switch {
+		case e.Binary:
+			pm.BinaryFiles++
+			continue
+		case e.Generated:
+			pm.GeneratedFiles++
+			continue
+		}
+		if e.Lang == "" {
+			continue
+		}
+
+		langs[e.Lang] = struct{}{}
+		acc.langs[e.Lang] = struct{}{}
+		pm.Lines[e.Lang] = pm.Lines[e.Lang].Add(e.Lines)
+		pm.TotalLines = pm.TotalLines.Add(e.Lines)
+		acc.stats.Lines = acc.stats.Lines.Add(e.Lines)
 	}
 
 	pm.MaxDepth = maxDepth
+	pm.Skipped = idx.Skipped
 
 	// Flatten language set.
-	for k := range langs {
-		pm.Languages = append(pm.Languages, k)
-	}
+	pm.Languages = sortedKeys(langs)
 
-	if err := m.writeMap(pm); err != nil {
-		return pm, err
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Size != sizes[j].Size {
+			return sizes[i].Size > sizes[j].Size
+		}
+		return sizes[i].Path < sizes[j].Path
+	})
+	if len(sizes) > MapLargest {
+		sizes = sizes[:MapLargest]
 	}
+	pm.Largest = sizes
 
-	m.core.Log.Infof("mapper: mapped project %q at %s (files=%d, dirs=%d, has_readme=%v)",
-		pm.Name, pm.Path, pm.TotalFiles, pm.TotalDirs, pm.HasReadme)
-
-	return pm, nil
+	pm.Directories = make([]DirStats, 0, len(dirs))
+	for _, acc := range dirs {
+		acc.stats.Languages = sortedKeys(acc.langs)
+		pm.Directories = append(pm.Directories, acc.stats)
+	}
+	sort.Slice(pm.Directories, func(i, j int) bool {
+		if pm.Directories[i].Bytes != pm.Directories[j].Bytes {
+			return pm.Directories[i].Bytes > pm.Directories[j].Bytes
+		}
+		return pm.Directories[i].Path < pm.Directories[j].Path
+	})
 }
 
 // writeMap persists the ProjectMap to the data/maps directory as JSON.
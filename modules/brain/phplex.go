@@ -0,0 +1,395 @@
+package brain
+
+import (
+	"bytes"
+	"strings"
+)
+
+// PHPTokenKind classifies a PHP token.
+type PHPTokenKind int
+
+const (
+	PHPInlineHTML PHPTokenKind = iota // text outside <?php ... ?>
+	PHPOpenTag                        // <?php, <?= or <?
+	PHPCloseTag                       // ?>, with the newline it swallows
+	PHPWhitespace
+	PHPComment    // //, # and /* */ comments
+	PHPDocComment // /** */ comments
+	PHPString     // a string with no interpolation: '...', nowdoc, or "..." without variables
+	PHPTemplate   // a string with interpolation: "...$x...", heredoc with variables, `...`
+	PHPVariable   // $name
+	PHPName       // identifiers, keywords and namespaced names (Foo\Bar)
+	PHPNumber
+	PHPPunct // operators and punctuation
+)
+
+// PHPToken is one lexeme of a PHP file.
+type PHPToken struct {
+	Kind PHPTokenKind
+	Text string // the source text, quotes and markers included
+	Line int    // 1-based line the token starts on
+}
+
+// Is reports whether t is a name or punctuation matching text. Names are
+// compared case-insensitively, as PHP does for keywords and functions.
+func (t PHPToken) Is(text string) bool {
+	switch t.Kind {
+	case PHPName:
+		return strings.EqualFold(t.Text, text)
+	case PHPPunct:
+		return t.Text == text
+	}
+	return false
+}
+
+// Trivia reports whether the token carries no code: whitespace and comments.
+func (t PHPToken) Trivia() bool {
+	return t.Kind == PHPWhitespace || t.Kind == PHPComment || t.Kind == PHPDocComment
+}
+
+// StringValue returns the contents of a PHPString token with quotes removed
+// and the common escapes resolved. ok is false for any other token.
+func (t PHPToken) StringValue() (string, bool) {
+	if t.Kind != PHPString || len(t.Text) < 2 {
+		return "", false
+	}
+
+	switch t.Text[0] {
+	case '\'':
+		body := t.Text[1 : len(t.Text)-1]
+		return strings.NewReplacer(`\\`, `\`, `\'`, `'`).Replace(body), true
+	case '"':
+		body := t.Text[1 : len(t.Text)-1]
+		return strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\$`, `$`, `\n`, "\n", `\t`, "\t", `\r`, "\r").Replace(body), true
+	case '<':
+		// Nowdoc or plain heredoc: the body sits between the opening line and
+		// the closing label's line.
+		nl := strings.IndexByte(t.Text, '\n')
+		end := strings.LastIndexByte(t.Text, '\n')
+		if nl < 0 || end <= nl {
+			return "", true
+		}
+		return t.Text[nl+1 : end], true
+	}
+	return "", false
+}
+
+// phpOps are the multi-character operators, longest first, so the lexer can
+// take the longest match.
+var phpOps = []string{
+	"<<=", ">>=", "**=", "...", "<=>", "===", "!==", "??=", "?->",
+	"->", "::", "=>", "==", "!=", "<>", "<=", ">=", "&&", "||", "??", "++", "--",
+	"+=", "-=", "*=", "/=", ".=", "%=", "&=", "|=", "^=", "<<", ">>", "**", "#[",
+}
+
+// LexPHP splits PHP source into tokens. It understands inline HTML, open and
+// close tags, all comment forms, single- and double-quoted strings,
+// heredocs, nowdocs and backticks, so keywords inside strings and comments
+// are never mistaken for code. Malformed input never fails: an unterminated
+// string or comment runs to the end of the file.
+func LexPHP(src []byte) []PHPToken {
+	lx := &phpLexer{src: src, line: 1}
+	lx.run()
+	return lx.toks
+}
+
+// PHPCode returns the tokens that carry code, dropping whitespace, comments
+// and inline HTML.
+func PHPCode(toks []PHPToken) []PHPToken {
+	out := make([]PHPToken, 0, len(toks))
+	for _, t := range toks {
+		if t.Trivia() || t.Kind == PHPInlineHTML {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+type phpLexer struct {
+	src  []byte
+	pos  int
+	line int
+	toks []PHPToken
+}
+
+func (lx *phpLexer) emit(kind PHPTokenKind, end int) {
+	text := string(lx.src[lx.pos:end])
+	lx.toks = append(lx.toks, PHPToken{Kind: kind, Text: text, Line: lx.line})
+	lx.line += strings.Count(text, "\n")
+	lx.pos = end
+}
+
+func (lx *phpLexer) run() {
+	for lx.pos < len(lx.src) {
+		lx.html()
+		lx.php()
+	}
+}
+
+// html consumes inline HTML up to and including the next open tag.
+func (lx *phpLexer) html() {
+	rest := lx.src[lx.pos:]
+	i := bytes.Index(rest, []byte("<?"))
+	if i < 0 {
+		lx.emit(PHPInlineHTML, len(lx.src))
+		return
+	}
+	if i > 0 {
+		lx.emit(PHPInlineHTML, lx.pos+i)
+	}
+
+	rest = lx.src[lx.pos:]
+	switch {
+	case len(rest) >= 5 && strings.EqualFold(string(rest[:5]), "<?php"):
+		lx.emit(PHPOpenTag, lx.pos+5)
+	case bytes.HasPrefix(rest, []byte("<?=")):
+		lx.emit(PHPOpenTag, lx.pos+3)
+	default:
+		lx.emit(PHPOpenTag, lx.pos+2)
+	}
+}
+
+// php consumes code up to and including the next close tag.
+func (lx *phpLexer) php() {
+	for lx.pos < len(lx.src) {
+		c := lx.src[lx.pos]
+		rest := lx.src[lx.pos:]
+
+		switch {
+		case isPHPSpace(c):
+			end := lx.pos
+			for end < len(lx.src) && isPHPSpace(lx.src[end]) {
+				end++
+			}
+			lx.emit(PHPWhitespace, end)
+
+		case bytes.HasPrefix(rest, []byte("?>")):
+			end := lx.pos + 2
+			if end < len(lx.src) && lx.src[end] == '\n' {
+				end++
+			} else if bytes.HasPrefix(lx.src[end:], []byte("\r\n")) {
+				end += 2
+			}
+			lx.emit(PHPCloseTag, end)
+			return
+
+		case bytes.HasPrefix(rest, []byte("#[")):
+			lx.emit(PHPPunct, lx.pos+2)
+
+		case c == '#' || bytes.HasPrefix(rest, []byte("//")):
+			lx.lineComment()
+
+		case bytes.HasPrefix(rest, []byte("/*")):
+			end := bytes.Index(rest[2:], []byte("*/"))
+			if end < 0 {
+				end = len(lx.src)
+			} else {
+				end = lx.pos + 2 + end + 2
+			}
+			kind := PHPComment
+			if len(rest) > 3 && rest[2] == '*' && isPHPSpace(rest[3]) {
+				kind = PHPDocComment
+			}
+			lx.emit(kind, end)
+
+		case c == '\'':
+			lx.emit(PHPString, lx.quoted('\''))
+
+		case c == '"' || c == '`':
+			end := lx.quoted(c)
+			kind := PHPString
+			if c == '`' || hasInterpolation(lx.src[lx.pos+1:end-1]) {
+				kind = PHPTemplate
+			}
+			lx.emit(kind, end)
+
+		case bytes.HasPrefix(rest, []byte("<<<")):
+			if !lx.heredoc() {
+				lx.emit(PHPPunct, lx.pos+2)
+			}
+
+		case c == '$' && lx.pos+1 < len(lx.src) && isPHPNameStart(lx.src[lx.pos+1]):
+			end := lx.pos + 1
+			for end < len(lx.src) && isPHPNameByte(lx.src[end]) {
+				end++
+			}
+			lx.emit(PHPVariable, end)
+
+		case isPHPNameStart(c) || (c == '\\' && lx.pos+1 < len(lx.src) && isPHPNameStart(lx.src[lx.pos+1])):
+			end := lx.pos
+			for end < len(lx.src) && (isPHPNameByte(lx.src[end]) || lx.src[end] == '\\') {
+				end++
+			}
+			lx.emit(PHPName, end)
+
+		case c >= '0' && c <= '9':
+			end := lx.pos
+			for end < len(lx.src) && (isPHPNameByte(lx.src[end]) || lx.src[end] == '.') {
+				end++
+			}
+			lx.emit(PHPNumber, end)
+
+		default:
+			end := lx.pos + 1
+			for _, op := range phpOps {
+				if bytes.HasPrefix(rest, []byte(op)) {
+					end = lx.pos + len(op)
+					break
+				}
+			}
+			lx.emit(PHPPunct, end)
+		}
+	}
+}
+
+// lineComment consumes a // or # comment. A close tag ends it, as in PHP.
+func (lx *phpLexer) lineComment() {
+	end := lx.pos
+	for end < len(lx.src) && lx.src[end] != '\n' {
+		if bytes.HasPrefix(lx.src[end:], []byte("?>")) {
+			break
+		}
+		end++
+	}
+	lx.emit(PHPComment, end)
+}
+
+// quoted returns the end of a string opened by q at lx.pos. In double-quoted
+// strings and backticks, {$...} blocks may hold quotes of their own.
+func (lx *phpLexer) quoted(q byte) int {
+	i := lx.pos + 1
+	for i < len(lx.src) {
+		switch c := lx.src[i]; {
+		case c == '\\':
+			i += 2
+			continue
+		case c == q:
+			return i + 1
+		case q != '\'' && c == '{' && i+1 < len(lx.src) && lx.src[i+1] == '$':
+			i = skipBraces(lx.src, i)
+			continue
+		}
+		i++
+	}
+	return len(lx.src)
+}
+
+// skipBraces returns the position after the brace block opened at i,
+// stepping over quoted strings inside it.
+func skipBraces(src []byte, i int) int {
+	depth := 0
+	for i < len(src) {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '\'', '"':
+			q := src[i]
+			i++
+			for i < len(src) && src[i] != q {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+		}
+		i++
+	}
+	return len(src)
+}
+
+// heredoc consumes a heredoc or nowdoc starting at lx.pos. It reports false
+// when "<<<" isn't followed by a valid label.
+func (lx *phpLexer) heredoc() bool {
+	i := lx.pos + 3
+	for i < len(lx.src) && (lx.src[i] == ' ' || lx.src[i] == '\t') {
+		i++
+	}
+
+	quote := byte(0)
+	if i < len(lx.src) && (lx.src[i] == '\'' || lx.src[i] == '"') {
+		quote = lx.src[i]
+		i++
+	}
+	start := i
+	for i < len(lx.src) && isPHPNameByte(lx.src[i]) {
+		i++
+	}
+	label := string(lx.src[start:i])
+	if label == "" || !isPHPNameStart(label[0]) {
+		return false
+	}
+	if quote != 0 {
+		if i >= len(lx.src) || lx.src[i] != quote {
+			return false
+		}
+		i++
+	}
+	nl := bytes.IndexByte(lx.src[i:], '\n')
+	if nl < 0 {
+		return false
+	}
+	bodyStart := i + nl + 1
+
+	// The closing label sits on its own line, possibly indented (PHP 7.3+),
+	// and must not run on into a longer name.
+	end := len(lx.src)
+	for p := bodyStart; p < len(lx.src); {
+		q := p
+		for q < len(lx.src) && (lx.src[q] == ' ' || lx.src[q] == '\t') {
+			q++
+		}
+		if bytes.HasPrefix(lx.src[q:], []byte(label)) {
+			after := q + len(label)
+			if after >= len(lx.src) || !isPHPNameByte(lx.src[after]) {
+				end = after
+				break
+			}
+		}
+		next := bytes.IndexByte(lx.src[p:], '\n')
+		if next < 0 {
+			break
+		}
+		p += next + 1
+	}
+
+	kind := PHPString
+	if quote != '\'' && end > bodyStart && hasInterpolation(lx.src[bodyStart:end]) {
+		kind = PHPTemplate
+	}
+	lx.emit(kind, end)
+	return true
+}
+
+// hasInterpolation reports whether a double-quoted or heredoc body contains
+// an unescaped variable.
+func hasInterpolation(body []byte) bool {
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '$':
+			if i+1 < len(body) && (isPHPNameStart(body[i+1]) || body[i+1] == '{') {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isPHPSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isPHPNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isPHPNameByte(c byte) bool {
+	return isPHPNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,101 @@
+package brain
+
+import (
+	"strings"
+	"testing"
+)
+
+// kinds renders tokens as "kind:text" pairs, skipping whitespace.
+func kinds(toks []PHPToken) string {
+	names := map[PHPTokenKind]string{
+		PHPInlineHTML: "html", PHPOpenTag: "open", PHPCloseTag: "close", PHPComment: "comment",
+		PHPDocComment: "doc", PHPString: "str", PHPTemplate: "tpl", PHPVariable: "var",
+		PHPName: "name", PHPNumber: "num", PHPPunct: "punct",
+	}
+	var parts []string
+	for _, t := range toks {
+		if t.Kind == PHPWhitespace {
+			continue
+		}
+		parts = append(parts, names[t.Kind]+":"+t.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestLexPHP(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"open and code", "<?php echo 1;", "open:<?php name:echo num:1 punct:;"},
+		{"upper open tag", "<?PHP $a;", "open:<?PHP var:$a punct:;"},
+		{"echo tag", "<?= $x ?>", "open:<?= var:$x close:?>"},
+		{"short tag", "<? $x;", "open:<? var:$x punct:;"},
+		{"inline html", "<p>hi</p><?php $a; ?>\n<b>", "html:<p>hi</p> open:<?php var:$a punct:; close:?>\n html:<b>"},
+		{"line comment", "<?php // require 'x';\n$a;", "open:<?php comment:// require 'x'; var:$a punct:;"},
+		{"hash comment", "<?php # x\n$a;", "open:<?php comment:# x var:$a punct:;"},
+		{"attribute", "<?php #[Attr]\nclass A {}", "open:<?php punct:#[ name:Attr punct:] name:class name:A punct:{ punct:}"},
+		{"comment ends at close tag", "<?php // x ?>y", "open:<?php comment:// x  close:?> html:y"},
+		{"block comment", "<?php /* a */ $b;", "open:<?php comment:/* a */ var:$b punct:;"},
+		{"doc comment", "<?php /** Doc. */ $b;", "open:<?php doc:/** Doc. */ var:$b punct:;"},
+		{"single quoted", `<?php 'it\'s $x';`, `open:<?php str:'it\'s $x' punct:;`},
+		{"double quoted", `<?php "plain";`, `open:<?php str:"plain" punct:;`},
+		{"interpolated", `<?php "hi $name";`, `open:<?php tpl:"hi $name" punct:;`},
+		{"escaped dollar", `<?php "cost \$5";`, `open:<?php str:"cost \$5" punct:;`},
+		{"braced interpolation", `<?php "a {$b["c"]} d";`, `open:<?php tpl:"a {$b["c"]} d" punct:;`},
+		{"backtick", "<?php `ls`;", "open:<?php tpl:`ls` punct:;"},
+		{"heredoc", "<?php $a = <<<EOT\nhi $x\nEOT;\n", "open:<?php var:$a punct:= tpl:<<<EOT\nhi $x\nEOT punct:;"},
+		{"plain heredoc", "<?php $a = <<<EOT\nrequire 'x.php';\nEOT;\n", "open:<?php var:$a punct:= str:<<<EOT\nrequire 'x.php';\nEOT punct:;"},
+		{"nowdoc", "<?php $a = <<<'EOT'\n$x\nEOT;\n", "open:<?php var:$a punct:= str:<<<'EOT'\n$x\nEOT punct:;"},
+		{"indented closing label", "<?php $a = <<<EOT\n  x\n  EOT;\n", "open:<?php var:$a punct:= str:<<<EOT\n  x\n  EOT punct:;"},
+		{"label prefix is not the end", "<?php $a = <<<EOT\nEOTX\nEOT;\n", "open:<?php var:$a punct:= str:<<<EOT\nEOTX\nEOT punct:;"},
+		{"namespaced name", `<?php \App\Foo::bar();`, `open:<?php name:\App\Foo punct::: name:bar punct:( punct:) punct:;`},
+		{"operators", "<?php $a?->b ?? $c <=> 1;", "open:<?php var:$a punct:?-> name:b punct:?? var:$c punct:<=> num:1 punct:;"},
+		{"unterminated string", "<?php 'abc", "open:<?php str:'abc"},
+		{"unterminated comment", "<?php /* abc", "open:<?php comment:/* abc"},
+	}
+	for _, tt := range tests {
+		if got := kinds(LexPHP([]byte(tt.src))); got != tt.want {
+			t.Errorf("%s: LexPHP(%q)\n got %q\nwant %q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestLexPHPRoundTrip(t *testing.T) {
+	src := "<p>\n<?php\n/** Doc. */\n$a = <<<EOT\n{$b}\nEOT;\necho \"x $y\"; // c\n?>\n</p>\n"
+	var b strings.Builder
+	for _, tok := range LexPHP([]byte(src)) {
+		b.WriteString(tok.Text)
+	}
+	if b.String() != src {
+		t.Errorf("tokens reassemble to %q; want %q", b.String(), src)
+	}
+}
+
+func TestLexPHPLines(t *testing.T) {
+	src := "<?php\n/*\n*/\n$a = 'x\ny';\n$b;\n"
+	want := map[string]int{"$a": 4, "$b": 6}
+	for _, tok := range LexPHP([]byte(src)) {
+		if line, ok := want[tok.Text]; ok && tok.Line != line {
+			t.Errorf("%s on line %d; want %d", tok.Text, tok.Line, line)
+		}
+	}
+}
+
+func TestStringValue(t *testing.T) {
+	tests := []struct {
+		src, want string
+		ok        bool
+	}{
+		{`'a\'b\\c'`, `a'b\c`, true},
+		{`"a\"b\n"`, "a\"b\n", true},
+		{"<<<'EOT'\nbody\nEOT", "body", true},
+		{`"hi $x"`, "", false},
+	}
+	for _, tt := range tests {
+		toks := PHPCode(LexPHP([]byte("<?php " + tt.src + ";")))
+		got, ok := toks[1].StringValue()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("StringValue(%q) = %q, %v; want %q, %v", tt.src, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package brain
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"rictusd/modules/core"
@@ -14,18 +14,23 @@ import (
 // MissingRequire represents a require/include that points to a file
 // RictusD could not resolve relative to the project.
 type MissingRequire struct {
-	File   string `json:"file"`   // e.g., "index.php"
-	Target string `json:"target"` // e.g., "/app/bootstrap"
+	File     string `json:"file"`     // e.g., "index.php"
+	Line     int    `json:"line"`     // 1-based line of the require
+	Target   string `json:"target"`   // e.g., "/app/bootstrap"
+	Resolved string `json:"resolved"` // project-relative path we looked for
 }
 
 // PHPReport summarizes basic PHP-level observations for a project.
 type PHPReport struct {
-	TotalFiles         int              `json:"total_files"`
-	MissingStrict      int              `json:"missing_strict"`
-	MissingDocHint     int              `json:"missing_doc_hint"`
-	SampleNoDoc        []string         `json:"sample_no_doc"` // some example paths
-	MissingRequireCount int             `json:"missing_require_count"`
-	MissingRequires    []MissingRequire `json:"missing_requires"`
+	TotalFiles          int              `json:"total_files"`
+	MissingStrict       int              `json:"missing_strict"`
+	MissingDocHint      int              `json:"missing_doc_hint"`
+	SampleNoDoc         []string         `json:"sample_no_doc"` // some example paths
+	NoStrict            []string         `json:"no_strict"`     // every file missing strict_types
+	NoDoc               []string         `json:"no_doc"`        // every file missing a docblock
+	MissingRequireCount int              `json:"missing_require_count"`
+	MissingRequires     []MissingRequire `json:"missing_requires"`
+	Skipped             SkipStats        `json:"skipped"` // left out by ignore rules
 }
 
 // PHPScanner performs read-only PHP file analysis.
@@ -38,166 +43,378 @@ func NewPHPScanner(c *core.Core) *PHPScanner {
 	return &PHPScanner{core: c}
 }
 
-// AnalyzeProject walks the project directory and inspects PHP files for
-// simple signals: presence of strict_types, docblock hints, and unresolved
-// require/include paths.
+// AnalyzeProject inspects the project's PHP files for simple signals:
+// presence of strict_types, docblock hints, and unresolved require/include
+// paths. Per-file results come from the project index, so only files that
+// changed since the last call are read.
 func (s *PHPScanner) AnalyzeProject(p core.Project) (PHPReport, error) {
+	return s.AnalyzeProjectContext(context.Background(), p)
+}
+
+// AnalyzeProjectContext is AnalyzeProject with cancellation.
+func (s *PHPScanner) AnalyzeProjectContext(ctx context.Context, p core.Project) (PHPReport, error) {
 	report := PHPReport{
 		SampleNoDoc:     make([]string, 0),
+		NoStrict:        make([]string, 0),
+		NoDoc:           make([]string, 0),
 		MissingRequires: make([]MissingRequire, 0),
 	}
 
-	root := p.Path
-
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			s.core.Log.Warnf("phpscan: walk error on %s: %v", path, err)
-			return nil
-		}
+	idx, _, err := NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return report, fmt.Errorf("phpscan: %w", err)
+	}
 
-		if d.IsDir() {
-			// Skip vendor-style dirs in the future if needed.
-			return nil
-		}
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		s.core.Log.Warnf("phpscan: %s: %v; using defaults", p.Name, err)
+	}
 
-		if filepath.Ext(d.Name()) != ".php" {
-			return nil
+	for _, e := range idx.Lang("php") {
+		if e.PHP == nil {
+			continue
 		}
-
 		report.TotalFiles++
 
-		rel, relErr := filepath.Rel(root, path)
-		if relErr != nil {
-			rel = path
-		}
-
-		hasStrict, hasDoc, missingReqs, err := s.inspectPHPFile(root, rel, path)
-		if err != nil {
-			s.core.Log.Warnf("phpscan: inspect error on %s: %v", path, err)
-			return nil
-		}
-
-		if !hasStrict {
+		if !e.PHP.HasStrict && cfg.RequireStrict() {
 			report.MissingStrict++
+			report.NoStrict = append(report.NoStrict, e.Path)
 		}
-		if !hasDoc {
+		if !e.PHP.HasDoc && cfg.RequireDocblock() {
 			report.MissingDocHint++
+			report.NoDoc = append(report.NoDoc, e.Path)
 			if len(report.SampleNoDoc) < 5 {
-				report.SampleNoDoc = append(report.SampleNoDoc, rel)
+				report.SampleNoDoc = append(report.SampleNoDoc, filepath.FromSlash(e.Path))
 			}
 		}
 
-		if len(missingReqs) > 0 {
-			report.MissingRequireCount += len(missingReqs)
-			report.MissingRequires = append(report.MissingRequires, missingReqs...)
-		}
+		missing := s.unresolved(p.Path, idx, e, cfg.IncludePaths)
+		report.MissingRequireCount += len(missing)
+		report.MissingRequires = append(report.MissingRequires, missing...)
+	}
+
+	report.Skipped = idx.Skipped
+
+	return report, nil
+}
 
-		return nil
-	})
+// MissingRequiresIn returns the unresolved require/include targets of one
+// file without scanning the rest of the project.
+func (s *PHPScanner) MissingRequiresIn(p core.Project, relPath string) ([]MissingRequire, error) {
+	ix := NewIndexer(s.core)
 
+	e, err := ix.Entry(p, relPath)
 	if err != nil {
-		return report, fmt.Errorf("phpscan: walk project: %w", err)
+		return nil, fmt.Errorf("phpscan: %w", err)
+	}
+	if e.PHP == nil {
+		return nil, nil
 	}
 
-	return report, nil
-}
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		s.core.Log.Warnf("phpscan: %s: %v; using defaults", p.Name, err)
+	}
 
-// inspectPHPFile performs a very lightweight scan of the top of a PHP file
-// to check for strict_types, docblock-like content, and unresolved requires.
-func (s *PHPScanner) inspectPHPFile(root, relPath, fullPath string) (hasStrict bool, hasDoc bool, missing []MissingRequire, err error) {
-	missing = make([]MissingRequire, 0)
+	return s.unresolved(p.Path, nil, e, cfg.IncludePaths), nil
+}
 
-	f, err := os.Open(fullPath)
+// CheckSource runs the per-file PHP checks over content that may not be on
+// disk yet, such as an editor buffer, and returns what they find. Require
+// targets are resolved against the filesystem.
+func (s *PHPScanner) CheckSource(p core.Project, relPath string, data []byte) []Finding {
+	cfg, err := core.LoadProjectConfig(p.Path)
 	if err != nil {
-		return false, false, missing, err
+		s.core.Log.Warnf("phpscan: %s: %v; using defaults", p.Name, err)
 	}
-	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	lineCount := 0
+	rel := filepath.ToSlash(relPath)
+	scan := scanPHP(data, rel)
+	report := PHPReport{}
+	if !scan.HasStrict && cfg.RequireStrict() {
+		report.NoStrict = append(report.NoStrict, rel)
+	}
+	if !scan.HasDoc && cfg.RequireDocblock() {
+		report.NoDoc = append(report.NoDoc, rel)
+	}
+	report.MissingRequires = s.unresolved(p.Path, nil, &FileEntry{Path: rel, PHP: &scan}, cfg.IncludePaths)
+	return report.Findings()
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineCount++
+// unresolved resolves a file's recorded require targets against the index
+// (when given) and the filesystem. Targets in ignored directories such as
+// vendor/ are not in the index, so the filesystem has the last word.
+func (s *PHPScanner) unresolved(root string, idx *ProjectIndex, e *FileEntry, includePaths []string) []MissingRequire {
+	out := make([]MissingRequire, 0)
+	for _, r := range e.PHP.Requires {
+		if idx != nil && (idx.Has(r.Candidate) || idx.Has(r.Candidate+".php")) {
+			continue
+		}
+		if resolveRequire(root, r.Candidate) || resolveOnIncludePath(root, r.Target, includePaths) {
+			continue
+		}
+		out = append(out, MissingRequire{
+			File:     filepath.FromSlash(e.Path),
+			Line:     r.Line,
+			Target:   r.Target,
+			Resolved: r.Candidate,
+		})
+	}
+	return out
+}
 
-		trim := strings.TrimSpace(line)
-		if strings.Contains(trim, "declare(strict_types=1") {
-			hasStrict = true
+// scanPHP lexes a PHP file and records whether its header declares
+// strict_types, whether it has a file docblock, every require/include with a
+// target that can be worked out statically, and its symbols.
+func scanPHP(data []byte, relPath string) PHPFileScan {
+	var scan PHPFileScan
+
+	relDir := filepath.Dir(filepath.FromSlash(relPath))
+	toks := LexPHP(data)
+
+	scan.HasStrict, scan.HasDoc = phpHeader(toks)
+
+	code := PHPCode(toks)
+	scan.Symbols, scan.Uses, scan.Refs = extractPHPSymbols(code)
+
+	for i, t := range code {
+		if !isRequireKeyword(t) {
+			continue
 		}
-		if strings.HasPrefix(trim, "/**") {
-			hasDoc = true
+		// $obj->require(...), Foo::include(...) and function require() are
+		// method names, not language constructs.
+		if i > 0 && (code[i-1].Is("->") || code[i-1].Is("?->") || code[i-1].Is("::") || code[i-1].Is("function")) {
+			continue
 		}
 
-		// Very simple require/include detection.
-		if strings.HasPrefix(trim, "require ") ||
-			strings.HasPrefix(trim, "require(") ||
-			strings.HasPrefix(trim, "require_once ") ||
-			strings.HasPrefix(trim, "require_once(") ||
-			strings.HasPrefix(trim, "include ") ||
-			strings.HasPrefix(trim, "include(") ||
-			strings.HasPrefix(trim, "include_once ") ||
-			strings.HasPrefix(trim, "include_once(") {
+		if target, candidate, ok := requireTarget(requireExpr(code[i+1:]), relDir); ok {
+			scan.Requires = append(scan.Requires, PHPRequire{
+				Line:      t.Line,
+				Target:    target,
+				Candidate: filepath.ToSlash(candidate),
+			})
+		}
+	}
 
-			target := extractRequireTarget(trim)
-			if target != "" && !s.resolveRequire(root, fullPath, target) {
-				missing = append(missing, MissingRequire{
-					File:   relPath,
-					Target: target,
-				})
+	return scan
+}
+
+// phpHeader looks at the file header: everything after the opening tag up to
+// the first statement that isn't a declare. strict is true when a declare
+// there sets strict_types=1; doc is true when a docblock comes before that
+// first statement, which is where the patch engine puts file docblocks.
+func phpHeader(toks []PHPToken) (strict, doc bool) {
+	i := 0
+	for i < len(toks) && toks[i].Kind != PHPOpenTag {
+		i++
+	}
+
+	for i++; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.Kind == PHPDocComment:
+			doc = true
+		case t.Trivia():
+		case t.Is("declare"):
+			end := i + 1
+			for end < len(toks) && !toks[end].Is(";") && !toks[end].Is("{") && toks[end].Kind != PHPCloseTag {
+				end++
+			}
+			if declaresStrict(PHPCode(toks[i+1 : end])) {
+				strict = true
 			}
+			i = end
+		default:
+			return strict, doc
 		}
+	}
+	return strict, doc
+}
 
-		// We only need a quick impression from the top of the file.
-		if lineCount > 200 {
-			break
+// declaresStrict reports whether declare arguments, e.g.
+// "(ticks=1, strict_types=1)", turn strict_types on.
+func declaresStrict(args []PHPToken) bool {
+	for i := 0; i+2 < len(args); i++ {
+		if args[i].Is("strict_types") && args[i+1].Is("=") && args[i+2].Kind == PHPNumber && args[i+2].Text == "1" {
+			return true
 		}
 	}
+	return false
+}
 
-	if err := scanner.Err(); err != nil {
-		return hasStrict, hasDoc, missing, err
+func isRequireKeyword(t PHPToken) bool {
+	return t.Is("require") || t.Is("require_once") || t.Is("include") || t.Is("include_once")
+}
+
+// requireExpr returns the code tokens of a require's operand: up to the
+// terminating ";", a close tag, or a bracket or comma that belongs to an
+// enclosing expression.
+func requireExpr(code []PHPToken) []PHPToken {
+	depth := 0
+	for i, t := range code {
+		switch {
+		case t.Is("(") || t.Is("["):
+			depth++
+		case t.Is(")") || t.Is("]"):
+			if depth == 0 {
+				return code[:i]
+			}
+			depth--
+		case depth == 0 && (t.Is(";") || t.Is(",") || t.Kind == PHPCloseTag):
+			return code[:i]
+		}
 	}
+	return code
+}
 
-	return hasStrict, hasDoc, missing, nil
+// RequirePath works out which project-relative path a require/include
+// statement points at, given the including file's directory relative to the
+// project root. It understands plain string literals (a leading "/" is read as
+// project-root-relative, web-style), __DIR__ . '...', dirname(__FILE__) . '...'
+// and dirname(__DIR__, n) . '...', with literals that may be concatenated.
+// target is the literal path as written; ok is false for targets built from
+// variables or constants.
+func RequirePath(stmt, relDir string) (target, candidate string, ok bool) {
+	code := PHPCode(LexPHP([]byte("<?php " + stmt)))[1:]
+	if len(code) == 0 || !isRequireKeyword(code[0]) {
+		return "", "", false
+	}
+	return requireTarget(requireExpr(code[1:]), relDir)
 }
 
-// extractRequireTarget pulls out a simple string literal from a require/include line.
-func extractRequireTarget(line string) string {
-	// Look for first quote after the keyword.
-	idx := strings.IndexAny(line, "\"'")
-	if idx == -1 {
-		return ""
+// requireTarget evaluates a require operand.
+func requireTarget(expr []PHPToken, relDir string) (target, candidate string, ok bool) {
+	expr = unwrapParens(expr)
+
+	anchored, levels := false, 0
+	switch {
+	case len(expr) > 0 && expr[0].Is("__DIR__"):
+		anchored = true
+		expr = expr[1:]
+	case len(expr) > 0 && expr[0].Is("dirname"):
+		var n int
+		levels, n, ok = parseDirname(expr)
+		if !ok {
+			return "", "", false
+		}
+		anchored = true
+		expr = expr[n:]
 	}
-	quote := line[idx]
-	rest := line[idx+1:]
-	end := strings.IndexRune(rest, rune(quote))
-	if end == -1 {
-		return ""
+
+	if anchored {
+		if len(expr) == 0 || !expr[0].Is(".") {
+			return "", "", false
+		}
+		expr = expr[1:]
+	}
+
+	// One or more string literals joined with ".".
+	var b strings.Builder
+	for i, t := range expr {
+		if i%2 == 1 {
+			if !t.Is(".") {
+				return "", "", false
+			}
+			continue
+		}
+		v, isStr := t.StringValue()
+		if !isStr {
+			return "", "", false
+		}
+		b.WriteString(v)
+	}
+	if len(expr)%2 == 0 {
+		return "", "", false
+	}
+	target = b.String()
+
+	switch {
+	case anchored:
+		dir := relDir
+		for i := 0; i < levels; i++ {
+			dir = filepath.Dir(dir)
+		}
+		candidate = filepath.Join(dir, target)
+	case strings.HasPrefix(target, "/"):
+		candidate = filepath.Clean(target[1:])
+	default:
+		candidate = filepath.Join(relDir, target)
+	}
+
+	return target, candidate, true
+}
+
+// unwrapParens strips parentheses that enclose the whole expression.
+func unwrapParens(expr []PHPToken) []PHPToken {
+	for len(expr) >= 2 && expr[0].Is("(") && expr[len(expr)-1].Is(")") {
+		depth := 0
+		whole := true
+		for i, t := range expr {
+			if t.Is("(") {
+				depth++
+			} else if t.Is(")") {
+				depth--
+				if depth == 0 && i != len(expr)-1 {
+					whole = false
+					break
+				}
+			}
+		}
+		if !whole {
+			break
+		}
+		expr = expr[1 : len(expr)-1]
 	}
-	return rest[:end]
+	return expr
 }
 
-// resolveRequire tries to determine whether a require/include target resolves
-// to an existing file relative to the project root. It returns true if it can
-// find a plausible file, false otherwise.
-func (s *PHPScanner) resolveRequire(root, fullPath, target string) bool {
-	// Treat leading "/" as project-root-relative (web-style), not filesystem root.
-	var candidateRel string
-	if strings.HasPrefix(target, "/") {
-		candidateRel = target[1:]
-	} else {
-		// Relative to the directory of the current file.
-		dir := filepath.Dir(fullPath)
-		relFromRoot, err := filepath.Rel(root, dir)
-		if err != nil {
-			relFromRoot = ""
+// parseDirname reads a possibly nested dirname(__FILE__|__DIR__[, n]) call
+// and returns how many directories above the including file's directory it
+// points, plus how many tokens it spans.
+func parseDirname(expr []PHPToken) (levels, n int, ok bool) {
+	i, depth := 0, 0
+	for i+1 < len(expr) && expr[i].Is("dirname") && expr[i+1].Is("(") {
+		i += 2
+		depth++
+	}
+	if i >= len(expr) {
+		return 0, 0, false
+	}
+
+	switch {
+	case expr[i].Is("__DIR__"):
+		levels = depth
+	case expr[i].Is("__FILE__"):
+		levels = depth - 1
+	default:
+		return 0, 0, false
+	}
+	i++
+
+	// The innermost call may carry a levels argument: dirname(__DIR__, 2).
+	if i+1 < len(expr) && expr[i].Is(",") {
+		k, err := strconv.Atoi(expr[i+1].Text)
+		if err != nil || k < 1 || expr[i+1].Kind != PHPNumber {
+			return 0, 0, false
 		}
-		if relFromRoot == "." || relFromRoot == "" {
-			candidateRel = target
-		} else {
-			candidateRel = filepath.Join(relFromRoot, target)
+		levels += k - 1
+		i += 2
+	}
+
+	for d := 0; d < depth; d++ {
+		if i >= len(expr) || !expr[i].Is(")") {
+			return 0, 0, false
 		}
+		i++
 	}
 
+	return levels, i, true
+}
+
+// resolveRequire reports whether a project-relative require candidate points
+// at an existing file, allowing the ".php" extension to be implied.
+func resolveRequire(root, candidateRel string) bool {
+	candidateRel = filepath.FromSlash(candidateRel)
 	candidates := []string{
 		filepath.Join(root, candidateRel),
 		filepath.Join(root, candidateRel+".php"),
@@ -211,3 +428,44 @@ func (s *PHPScanner) resolveRequire(root, fullPath, target string) bool {
 
 	return false
 }
+
+// resolveOnIncludePath reports whether a bare relative target (not "./",
+// "../" or "/"-prefixed) resolves against one of the configured include
+// paths, the way PHP's include_path would find it.
+func resolveOnIncludePath(root, target string, includePaths []string) bool {
+	if target == "" || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") {
+		return false
+	}
+
+	for _, inc := range includePaths {
+		base := filepath.Join(root, filepath.FromSlash(inc))
+		for _, cpath := range []string{filepath.Join(base, target), filepath.Join(base, target+".php")} {
+			if st, err := os.Stat(cpath); err == nil && !st.IsDir() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// issueKeys lists every PHP issue in the index as a stable, readable key, so
+// snapshots taken at different times can be compared.
+func (s *PHPScanner) issueKeys(root string, idx *ProjectIndex, cfg core.ProjectConfig) []string {
+	out := make([]string, 0)
+	for _, e := range idx.Lang("php") {
+		if e.PHP == nil {
+			continue
+		}
+		if !e.PHP.HasStrict && cfg.RequireStrict() {
+			out = append(out, "missing strict_types: "+e.Path)
+		}
+		if !e.PHP.HasDoc && cfg.RequireDocblock() {
+			out = append(out, "missing docblock: "+e.Path)
+		}
+		for _, mr := range s.unresolved(root, idx, e, cfg.IncludePaths) {
+			out = append(out, "unresolved require: "+e.Path+" → "+mr.Target)
+		}
+	}
+	return out
+}
@@ -0,0 +1,454 @@
+package brain
+
+import (
+	"strings"
+)
+
+// PHPSymbol is a declaration found in a PHP file.
+type PHPSymbol struct {
+	Kind   string `json:"kind"`             // namespace, class, interface, trait, enum, function, method, constant
+	Name   string `json:"name"`             // as declared, e.g. "index"
+	FQN    string `json:"fqn"`              // e.g. "App\\Http\\UserController::index"
+	Parent string `json:"parent,omitempty"` // enclosing class FQN for methods and class constants
+	Line   int    `json:"line"`
+
+	Extends string   `json:"extends,omitempty"` // parent class FQN, for classes
+	Traits  []string `json:"traits,omitempty"`  // FQNs of the traits a class uses
+}
+
+// PHPUse is a use import at file or namespace level.
+type PHPUse struct {
+	Kind  string `json:"kind"` // class, function or const
+	Name  string `json:"name"` // fully qualified
+	Alias string `json:"alias"`
+	Line  int    `json:"line"`
+}
+
+// PHPRef is a place a file refers to a symbol: a call, an instantiation, a
+// static access, an inheritance clause or an import.
+type PHPRef struct {
+	Kind string `json:"kind"` // call, method, new, static, extends, implements, trait, instanceof, attribute, import
+	Name string `json:"name"` // as written, e.g. "render_view" or "UserController::index"
+	FQN  string `json:"fqn"`  // resolved against the namespace and imports where possible
+	Line int    `json:"line"`
+}
+
+// notCalls are names that look like calls when followed by "(" but are
+// language constructs.
+var notCalls = map[string]bool{
+	"if": true, "elseif": true, "while": true, "for": true, "foreach": true, "switch": true,
+	"match": true, "catch": true, "array": true, "list": true, "isset": true, "empty": true,
+	"unset": true, "eval": true, "exit": true, "die": true, "return": true, "echo": true,
+	"print": true, "fn": true, "function": true, "declare": true, "and": true, "or": true,
+	"xor": true, "new": true, "clone": true, "instanceof": true, "yield": true, "use": true,
+	"require": true, "require_once": true, "include": true, "include_once": true,
+	"static": true, "self": true, "parent": true, "throw": true, "case": true, "else": true,
+}
+
+// phpScope is one open brace in the symbol walk.
+type phpScope struct {
+	kind   string // namespace, class, function or block
+	class  string // class FQN for class scopes, "" for anonymous classes
+	parent string // the class's parent FQN for class scopes, "" without one
+	sym    int    // index of the class's symbol in syms, -1 for anonymous classes
+}
+
+// symbolWalker carries the state of extractPHPSymbols.
+type symbolWalker struct {
+	code []PHPToken
+
+	ns      string
+	imports map[string]string // lower-cased alias -> class FQN
+	funcs   map[string]string // lower-cased alias -> function FQN
+	scopes  []phpScope
+	pending *phpScope // scope the next "{" opens
+
+	syms []PHPSymbol
+	uses []PHPUse
+	refs []PHPRef
+}
+
+// extractPHPSymbols walks a file's code tokens and returns its declarations,
+// imports and references. Names are resolved the way PHP resolves them at
+// compile time; calls to unqualified functions keep their namespaced FQN
+// even though PHP falls back to the global function at run time.
+func extractPHPSymbols(code []PHPToken) ([]PHPSymbol, []PHPUse, []PHPRef) {
+	w := &symbolWalker{
+		code:    code,
+		imports: make(map[string]string),
+		funcs:   make(map[string]string),
+		syms:    make([]PHPSymbol, 0),
+		uses:    make([]PHPUse, 0),
+		refs:    make([]PHPRef, 0),
+	}
+	w.walk()
+	return w.syms, w.uses, w.refs
+}
+
+func (w *symbolWalker) at(i int) PHPToken {
+	if i < 0 || i >= len(w.code) {
+		return PHPToken{Kind: PHPWhitespace}
+	}
+	return w.code[i]
+}
+
+// class returns the innermost enclosing class scope, if any. Method bodies
+// and closures inside them count as enclosed, as they are for $this.
+func (w *symbolWalker) class() (phpScope, bool) {
+	for i := len(w.scopes) - 1; i >= 0; i-- {
+		if w.scopes[i].kind == "class" {
+			return w.scopes[i], true
+		}
+	}
+	return phpScope{}, false
+}
+
+// inClassBody reports whether the walk is directly inside a class body.
+func (w *symbolWalker) inClassBody() bool {
+	return len(w.scopes) > 0 && w.scopes[len(w.scopes)-1].kind == "class"
+}
+
+func (w *symbolWalker) walk() {
+	for i := 0; i < len(w.code); i++ {
+		t := w.code[i]
+		prev := w.at(i - 1)
+
+		switch {
+		case t.Is("{"):
+			s := phpScope{kind: "block"}
+			if w.pending != nil {
+				s = *w.pending
+				w.pending = nil
+			}
+			w.scopes = append(w.scopes, s)
+
+		case t.Is("}"):
+			if len(w.scopes) > 0 {
+				w.scopes = w.scopes[:len(w.scopes)-1]
+			}
+
+		case t.Is(";"):
+			// Abstract and interface methods have no body.
+			if w.pending != nil && w.pending.kind == "function" {
+				w.pending = nil
+			}
+
+		case t.Kind != PHPName:
+
+		case t.Is("namespace") && !w.at(i+1).Is("("):
+			i = w.namespace(i)
+
+		case t.Is("use") && prev.Is(")"):
+			// function () use ($x) { ... }
+
+		case t.Is("use") && (len(w.scopes) == 0 || w.scopes[len(w.scopes)-1].kind == "namespace"):
+			i = w.useImport(i)
+
+		case t.Is("use") && w.inClassBody():
+			for i++; i < len(w.code) && !w.code[i].Is(";") && !w.code[i].Is("{"); i++ {
+				if w.code[i].Kind == PHPName {
+					fqn := w.resolveClass(w.code[i].Text)
+					w.ref("trait", w.code[i].Text, fqn, w.code[i].Line)
+					if cls, _ := w.class(); cls.sym >= 0 {
+						w.syms[cls.sym].Traits = append(w.syms[cls.sym].Traits, fqn)
+					}
+				}
+			}
+			i--
+
+		case (t.Is("class") || t.Is("interface") || t.Is("trait") || t.Is("enum")) && !prev.Is("::") && !prev.Is("->") && !prev.Is("?->"):
+			i = w.classDecl(i)
+
+		case t.Is("extends") || t.Is("implements"):
+			kind := strings.ToLower(t.Text)
+			for i++; w.at(i).Kind == PHPName || w.at(i).Is(","); i++ {
+				if n := w.code[i]; n.Kind == PHPName && !n.Is("implements") {
+					w.ref(kind, n.Text, w.resolveClass(n.Text), n.Line)
+				} else if n.Is("implements") {
+					kind = "implements"
+				}
+			}
+			i--
+
+		case t.Is("function") || t.Is("fn"):
+			i = w.function(i)
+
+		case t.Is("const") && !prev.Is("use"):
+			i = w.constants(i)
+
+		case t.Is("define") && w.at(i+1).Is("("):
+			if v, ok := w.at(i + 2).StringValue(); ok && w.at(i+3).Is(",") {
+				w.syms = append(w.syms, PHPSymbol{Kind: "constant", Name: v, FQN: strings.TrimPrefix(v, `\`), Line: t.Line})
+			}
+
+		case t.Is("new"):
+			if n := w.at(i + 1); n.Kind == PHPName && !n.Is("class") {
+				w.ref("new", n.Text, w.resolveClass(n.Text), n.Line)
+				i++
+			}
+
+		case t.Is("instanceof"):
+			if n := w.at(i + 1); n.Kind == PHPName {
+				w.ref("instanceof", n.Text, w.resolveClass(n.Text), n.Line)
+				i++
+			}
+
+		case prev.Is("#[") || prev.Is(",") && w.inAttribute(i):
+			w.ref("attribute", t.Text, w.resolveClass(t.Text), t.Line)
+
+		case w.at(i + 1).Is("::"):
+			member := w.at(i + 2)
+			cls := w.resolveClass(t.Text)
+			switch {
+			case member.Kind == PHPName && member.Is("class"):
+				w.ref("static", t.Text, cls, t.Line)
+			case member.Kind == PHPName:
+				w.ref("static", t.Text+"::"+member.Text, cls+"::"+member.Text, t.Line)
+			default:
+				w.ref("static", t.Text, cls, t.Line)
+			}
+			i += 2
+
+		case w.at(i + 1).Is("("):
+			if prev.Is("->") || prev.Is("?->") {
+				fqn := t.Text
+				if cls, ok := w.class(); ok && cls.class != "" && w.at(i-2).Text == "$this" {
+					fqn = cls.class + "::" + t.Text
+				}
+				w.ref("method", t.Text, fqn, t.Line)
+			} else if !notCalls[strings.ToLower(t.Text)] {
+				w.ref("call", t.Text, w.resolveFunc(t.Text), t.Line)
+			}
+		}
+	}
+}
+
+// inAttribute reports whether token i sits directly inside an attribute
+// group, as in #[A, B].
+func (w *symbolWalker) inAttribute(i int) bool {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		t := w.code[j]
+		switch {
+		case t.Is(")") || t.Is("]"):
+			depth++
+		case t.Is("("):
+			depth--
+		case t.Is("["):
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case t.Is("#["):
+			return depth == 0
+		case t.Is(";") || t.Is("{") || t.Is("}"):
+			return false
+		}
+	}
+	return false
+}
+
+// namespace handles "namespace Foo\Bar;" and "namespace Foo\Bar { ... }".
+func (w *symbolWalker) namespace(i int) int {
+	w.ns = ""
+	w.imports = make(map[string]string)
+	w.funcs = make(map[string]string)
+
+	if n := w.at(i + 1); n.Kind == PHPName {
+		w.ns = strings.Trim(n.Text, `\`)
+		w.syms = append(w.syms, PHPSymbol{Kind: "namespace", Name: w.ns, FQN: w.ns, Line: n.Line})
+		i++
+	}
+	if w.at(i + 1).Is("{") {
+		w.pending = &phpScope{kind: "namespace"}
+	}
+	return i
+}
+
+// useImport handles use statements, including "use function", "use const"
+// and group uses such as "use App\{Foo, Bar as Baz};".
+func (w *symbolWalker) useImport(i int) int {
+	kind := "class"
+	i++
+	if w.at(i).Is("function") || w.at(i).Is("const") {
+		kind = strings.ToLower(w.at(i).Text)
+		i++
+	}
+
+	prefix := ""
+	for ; i < len(w.code) && !w.code[i].Is(";"); i++ {
+		t := w.code[i]
+		switch {
+		case t.Kind == PHPName && w.at(i+1).Is("{"):
+			prefix = strings.Trim(t.Text, `\`) + `\`
+			i++
+		case t.Kind == PHPName && strings.HasSuffix(t.Text, `\`) && w.at(i+1).Is("{"):
+			prefix = strings.Trim(t.Text, `\`) + `\`
+			i++
+		case t.Kind == PHPName && (t.Is("function") || t.Is("const")):
+			kind = strings.ToLower(t.Text)
+		case t.Kind == PHPName:
+			name := prefix + strings.Trim(t.Text, `\`)
+			alias := name[strings.LastIndex(name, `\`)+1:]
+			if w.at(i+1).Is("as") && w.at(i+2).Kind == PHPName {
+				alias = w.at(i + 2).Text
+				i += 2
+			}
+			w.uses = append(w.uses, PHPUse{Kind: kind, Name: name, Alias: alias, Line: t.Line})
+			w.ref("import", name, name, t.Line)
+			switch kind {
+			case "class":
+				w.imports[strings.ToLower(alias)] = name
+			case "function":
+				w.funcs[strings.ToLower(alias)] = name
+			}
+		case t.Is("}"):
+			prefix = ""
+		}
+	}
+	return i
+}
+
+// classDecl handles class, interface, trait and enum declarations, named or
+// anonymous.
+func (w *symbolWalker) classDecl(i int) int {
+	t := w.code[i]
+	kind := strings.ToLower(t.Text)
+	parent := w.parentOf(i)
+
+	n := w.at(i + 1)
+	if n.Kind != PHPName || n.Is("extends") || n.Is("implements") {
+		// new class(...) extends Foo { ... }
+		w.pending = &phpScope{kind: "class", parent: parent, sym: -1}
+		return i
+	}
+
+	fqn := w.qualify(n.Text)
+	w.syms = append(w.syms, PHPSymbol{Kind: kind, Name: n.Text, FQN: fqn, Line: n.Line, Extends: parent})
+	w.pending = &phpScope{kind: "class", class: fqn, parent: parent, sym: len(w.syms) - 1}
+	return i + 1
+}
+
+// parentOf returns the resolved parent named in the extends clause of the
+// class declared at token i, or "" when it has none. Interfaces may extend
+// several; the first is taken.
+func (w *symbolWalker) parentOf(i int) string {
+	for j := i + 1; j < len(w.code); j++ {
+		t := w.code[j]
+		switch {
+		case t.Is("{") || t.Is(";"):
+			return ""
+		case t.Is("extends"):
+			if n := w.at(j + 1); n.Kind == PHPName {
+				return w.resolveClass(n.Text)
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// function handles named functions, methods and closures.
+func (w *symbolWalker) function(i int) int {
+	j := i + 1
+	if w.at(j).Is("&") {
+		j++
+	}
+	n := w.at(j)
+
+	if n.Kind != PHPName || w.code[i].Is("fn") {
+		// Closures and arrow functions: the body is a plain scope.
+		w.pending = &phpScope{kind: "function"}
+		return i
+	}
+
+	if cls, ok := w.class(); ok && w.inClassBody() {
+		if cls.class != "" {
+			w.syms = append(w.syms, PHPSymbol{Kind: "method", Name: n.Text, FQN: cls.class + "::" + n.Text, Parent: cls.class, Line: n.Line})
+		}
+	} else {
+		w.syms = append(w.syms, PHPSymbol{Kind: "function", Name: n.Text, FQN: w.qualify(n.Text), Line: n.Line})
+	}
+	w.pending = &phpScope{kind: "function"}
+	return j
+}
+
+// constants handles "const A = 1, B = 2;" at namespace or class level.
+func (w *symbolWalker) constants(i int) int {
+	cls, inClass := w.class()
+	depth := 0
+	for i++; i < len(w.code); i++ {
+		t := w.code[i]
+		switch {
+		case t.Is("(") || t.Is("[") || t.Is("{"):
+			depth++
+		case t.Is(")") || t.Is("]") || t.Is("}"):
+			depth--
+		case depth == 0 && t.Is(";"):
+			return i
+		case depth == 0 && t.Kind == PHPName && w.at(i+1).Is("="):
+			switch {
+			case inClass && w.inClassBody() && cls.class != "":
+				w.syms = append(w.syms, PHPSymbol{Kind: "constant", Name: t.Text, FQN: cls.class + "::" + t.Text, Parent: cls.class, Line: t.Line})
+			case !inClass:
+				w.syms = append(w.syms, PHPSymbol{Kind: "constant", Name: t.Text, FQN: w.qualify(t.Text), Line: t.Line})
+			}
+		}
+	}
+	return i
+}
+
+func (w *symbolWalker) ref(kind, name, fqn string, line int) {
+	w.refs = append(w.refs, PHPRef{Kind: kind, Name: name, FQN: fqn, Line: line})
+}
+
+// qualify prefixes a declared name with the current namespace.
+func (w *symbolWalker) qualify(name string) string {
+	if w.ns == "" {
+		return name
+	}
+	return w.ns + `\` + name
+}
+
+// resolveClass resolves a class name as written against the imports and
+// the current namespace.
+func (w *symbolWalker) resolveClass(name string) string {
+	switch strings.ToLower(name) {
+	case "self", "static":
+		if cls, ok := w.class(); ok && cls.class != "" {
+			return cls.class
+		}
+		return name
+	case "parent":
+		if cls, ok := w.class(); ok && cls.parent != "" {
+			return cls.parent
+		}
+		return name
+	}
+
+	if strings.HasPrefix(name, `\`) {
+		return name[1:]
+	}
+	first, rest, qualified := strings.Cut(name, `\`)
+	if full, ok := w.imports[strings.ToLower(first)]; ok {
+		if qualified {
+			return full + `\` + rest
+		}
+		return full
+	}
+	return w.qualify(name)
+}
+
+// resolveFunc resolves a function name as written.
+func (w *symbolWalker) resolveFunc(name string) string {
+	if strings.HasPrefix(name, `\`) {
+		return name[1:]
+	}
+	if full, ok := w.funcs[strings.ToLower(name)]; ok {
+		return full
+	}
+	if strings.Contains(name, `\`) {
+		return w.resolveClass(name)
+	}
+	return w.qualify(name)
+}
@@ -33,3 +33,78 @@ func ClassifyPath(relPath string) Role {
 		return RoleUnknown
 	}
 }
+
+// ParseRole turns a role name from config into a Role; ok is false for
+// names that aren't roles.
+func ParseRole(name string) (Role, bool) {
+	switch r := Role(strings.ToLower(strings.TrimSpace(name))); r {
+	case RoleRouter, RoleController, RoleView, RoleConfig, RoleModel, RoleUnknown:
+		return r, true
+	}
+	return RoleUnknown, false
+}
+
+// ClassifyFile guesses a file's role from what it contains, falling back to
+// ClassifyPath when the content says nothing. Content wins because it is
+// harder to get wrong than a name: a class called UserController is a
+// controller wherever it lives. scan may be nil; PHP files are then scanned
+// here.
+func ClassifyFile(relPath string, data []byte, scan *PHPFileScan) Role {
+	l := strings.ToLower(relPath)
+	if LangOf(relPath) != "php" {
+		return ClassifyPath(relPath)
+	}
+	if strings.HasSuffix(l, ".blade.php") || strings.HasSuffix(l, ".phtml") {
+		return RoleView
+	}
+	if scan == nil {
+		s := scanPHP(data, relPath)
+		scan = &s
+	}
+
+	for _, s := range scan.Symbols {
+		if s.Kind != "class" {
+			continue
+		}
+		name := strings.ToLower(s.Name)
+		parent := ""
+		for _, ref := range scan.Refs {
+			if ref.Kind == "extends" && ref.Line == s.Line {
+				parent = strings.ToLower(lastSegment(ref.FQN))
+			}
+		}
+		fqn := strings.ToLower(s.FQN)
+		switch {
+		case strings.HasSuffix(name, "controller") || strings.HasSuffix(parent, "controller"):
+			return RoleController
+		case strings.HasSuffix(name, "model") || strings.HasSuffix(name, "repository") ||
+			strings.HasSuffix(parent, "model") || parent == "eloquent" || parent == "activerecord" ||
+			strings.Contains(fqn, `\models\`) || strings.Contains(fqn, `\entity\`) || strings.Contains(fqn, `\entities\`):
+			return RoleModel
+		}
+	}
+
+	toks := LexPHP(data)
+	code := PHPCode(toks)
+	if len(extractRoutes(code, relPath)) >= 2 {
+		return RoleRouter
+	}
+	// A file that only returns an array: config/app.php and friends.
+	if len(code) > 2 && code[0].Kind == PHPOpenTag && code[1].Is("return") && (code[2].Is("[") || code[2].Is("array")) {
+		return RoleConfig
+	}
+
+	html, php := 0, 0
+	for _, t := range toks {
+		if t.Kind == PHPInlineHTML {
+			html += len(strings.TrimSpace(t.Text))
+		} else if !t.Trivia() {
+			php += len(t.Text)
+		}
+	}
+	if html > 0 && html >= php {
+		return RoleView
+	}
+
+	return ClassifyPath(relPath)
+}
@@ -0,0 +1,337 @@
+package brain
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"rictusd/modules/law"
+)
+
+// snippetMax caps the length of a finding's source snippet.
+const snippetMax = 100
+
+// RouterFinding is one Router Law violation in a router file.
+type RouterFinding struct {
+	Rule      int    `json:"rule"`
+	RuleTitle string `json:"rule_title"`
+	Check     string `json:"check"`
+	Line      int    `json:"line"`
+	Snippet   string `json:"snippet"`
+	Message   string `json:"message"`
+}
+
+// routerHit is a detector's raw result, before it is tied to a rule.
+type routerHit struct {
+	line int
+	msg  string
+}
+
+// routerDetectors implement the checks a Router Law rule can name.
+var routerDetectors = map[string]func(code, toks []PHPToken, limit int) []routerHit{
+	law.CheckDB:        detectDB,
+	law.CheckHTML:      detectHTML,
+	law.CheckHeader:    callDetector(headerCalls, "sends a header"),
+	law.CheckFileIO:    callDetector(fileCalls, "touches the filesystem"),
+	law.CheckLogging:   callDetector(logCalls, "writes a log"),
+	law.CheckBootstrap: detectBootstrap,
+	law.CheckBranches:  detectBranches,
+	law.CheckLength:    detectLength,
+}
+
+var (
+	dbPrefixes  = []string{"mysqli_", "mysql_", "pg_", "sqlite_", "oci_", "sqlsrv_", "db2_"}
+	dbClasses   = map[string]bool{"pdo": true, "mysqli": true, "sqlite3": true}
+	dbMethods   = map[string]bool{"query": true, "prepare": true, "exec": true}
+	sqlStarts   = []string{"select ", "insert into ", "update ", "delete from ", "replace into "}
+	headerCalls = map[string]bool{"header": true, "header_remove": true, "setcookie": true, "setrawcookie": true, "http_response_code": true}
+	fileCalls   = map[string]bool{
+		"file_put_contents": true, "fopen": true, "fwrite": true, "fputs": true, "unlink": true,
+		"mkdir": true, "rmdir": true, "rename": true, "copy": true, "touch": true, "chmod": true,
+		"tempnam": true, "tmpfile": true, "move_uploaded_file": true,
+	}
+	logCalls = map[string]bool{"error_log": true, "syslog": true, "openlog": true}
+)
+
+// CheckRouterLaw runs the checks each rule of the Router Law names against
+// a router file. A check named by several rules runs once and is cited
+// under the first of them.
+func CheckRouterLaw(src []byte, rl law.RouterLaw) []RouterFinding {
+	toks := LexPHP(src)
+	code := PHPCode(toks)
+	lines := strings.Split(string(src), "\n")
+
+	out := make([]RouterFinding, 0)
+	ran := make(map[string]bool)
+	for _, rule := range rl.Rules {
+		for _, c := range rule.Checks {
+			detect, ok := routerDetectors[c.Name]
+			if !ok || ran[c.Name] {
+				continue
+			}
+			ran[c.Name] = true
+
+			for _, h := range detect(code, toks, c.Limit) {
+				out = append(out, RouterFinding{
+					Rule:      rule.Number,
+					RuleTitle: rule.Title,
+					Check:     c.Name,
+					Line:      h.line,
+					Snippet:   snippetAt(lines, h.line),
+					Message:   h.msg,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Rule != out[j].Rule {
+			return out[i].Rule < out[j].Rule
+		}
+		return out[i].Line < out[j].Line
+	})
+	return out
+}
+
+func snippetAt(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	s := strings.TrimSpace(lines[line-1])
+	if utf8.RuneCountInString(s) > snippetMax {
+		s = string([]rune(s)[:snippetMax]) + "…"
+	}
+	return s
+}
+
+// callName returns the lowercased name of a plain function call at code[i]:
+// a name followed by "(" that is not a method, static call, declaration or
+// instantiation.
+func callName(code []PHPToken, i int) (string, bool) {
+	if code[i].Kind != PHPName || i+1 >= len(code) || !code[i+1].Is("(") {
+		return "", false
+	}
+	if i > 0 {
+		prev := code[i-1]
+		if prev.Is("->") || prev.Is("?->") || prev.Is("::") || prev.Is("function") || prev.Is("new") {
+			return "", false
+		}
+	}
+	return strings.ToLower(strings.TrimPrefix(code[i].Text, `\`)), true
+}
+
+func callDetector(names map[string]bool, what string) func(code, toks []PHPToken, limit int) []routerHit {
+	return func(code, _ []PHPToken, _ int) []routerHit {
+		var hits []routerHit
+		for i := range code {
+			if name, ok := callName(code, i); ok && names[name] {
+				hits = append(hits, routerHit{code[i].Line, name + "() " + what})
+			}
+		}
+		return hits
+	}
+}
+
+func detectDB(code, _ []PHPToken, _ int) []routerHit {
+	var hits []routerHit
+	seen := make(map[int]bool)
+	add := func(line int, msg string) {
+		if !seen[line] {
+			seen[line] = true
+			hits = append(hits, routerHit{line, msg})
+		}
+	}
+
+	for i, t := range code {
+		if name, ok := callName(code, i); ok {
+			for _, p := range dbPrefixes {
+				if strings.HasPrefix(name, p) {
+					add(t.Line, name+"() talks to the database")
+				}
+			}
+		}
+		if t.Is("new") && i+1 < len(code) && code[i+1].Kind == PHPName {
+			if cls := strings.ToLower(strings.TrimPrefix(code[i+1].Text, `\`)); dbClasses[cls] {
+				add(t.Line, "opens a "+code[i+1].Text+" connection")
+			}
+		}
+		if (t.Is("->") || t.Is("?->")) && i+2 < len(code) && code[i+2].Is("(") {
+			if m := strings.ToLower(code[i+1].Text); dbMethods[m] {
+				add(t.Line, "runs ->"+code[i+1].Text+"()")
+			}
+		}
+		if t.Kind == PHPString || t.Kind == PHPTemplate {
+			body := strings.ToLower(strings.TrimLeft(t.Text, "'\"` \t\n"))
+			for _, s := range sqlStarts {
+				if strings.HasPrefix(body, s) {
+					add(t.Line, "holds a raw SQL query")
+					break
+				}
+			}
+		}
+	}
+	return hits
+}
+
+func detectHTML(code, toks []PHPToken, _ int) []routerHit {
+	var hits []routerHit
+
+	opened := false
+	for _, t := range toks {
+		if t.Kind == PHPOpenTag {
+			opened = true
+			continue
+		}
+		if t.Kind != PHPInlineHTML || !opened || !strings.Contains(t.Text, "<") {
+			continue
+		}
+		lead := len(t.Text) - len(strings.TrimLeft(t.Text, " \t\r\n"))
+		hits = append(hits, routerHit{t.Line + strings.Count(t.Text[:lead], "\n"), "switches to inline HTML"})
+	}
+
+	for i, t := range code {
+		echo := t.Is("echo") || t.Is("print") || (t.Kind == PHPOpenTag && t.Text == "<?=")
+		if !echo || i+1 >= len(code) {
+			continue
+		}
+		next := code[i+1]
+		switch {
+		case (next.Kind == PHPString || next.Kind == PHPTemplate) && hasMarkup(next.Text):
+			hits = append(hits, routerHit{t.Line, "echoes HTML"})
+		case next.Kind == PHPName && strings.EqualFold(strings.TrimPrefix(next.Text, `\`), "json_encode"):
+			hits = append(hits, routerHit{t.Line, "echoes hand-built JSON"})
+		case next.Kind == PHPString && (strings.HasPrefix(next.Text[1:], "{\"") || strings.HasPrefix(next.Text[1:], "[{")):
+			hits = append(hits, routerHit{t.Line, "echoes a JSON string"})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].line < hits[j].line })
+	return hits
+}
+
+// hasMarkup reports whether a string literal holds an HTML tag. A heredoc's
+// opening "<<<" line is skipped.
+func hasMarkup(s string) bool {
+	if strings.HasPrefix(s, "<<<") {
+		nl := strings.IndexByte(s, '\n')
+		if nl < 0 {
+			return false
+		}
+		s = s[nl+1:]
+	}
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] == '<' {
+			c := s[i+1]
+			if c == '/' || c == '!' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isBranch reports whether a token opens a routing branch.
+func isBranch(t PHPToken) bool {
+	return t.Is("if") || t.Is("switch") || t.Is("match")
+}
+
+// detectBootstrap flags a dispatch branch that runs before the first
+// require. Guards such as if (!defined('APP')) don't route, so they may
+// come first.
+func detectBootstrap(code, _ []PHPToken, _ int) []routerHit {
+	firstDispatch, firstRequire := -1, -1
+	for i, t := range code {
+		if firstDispatch < 0 && isBranch(t) && isDispatch(code, i) {
+			firstDispatch = i
+		}
+		if firstRequire < 0 && isRequireKeyword(t) {
+			firstRequire = i
+		}
+	}
+	if firstDispatch < 0 || firstRequire < 0 || firstRequire < firstDispatch {
+		return nil
+	}
+	return []routerHit{{code[firstDispatch].Line, "starts routing before any bootstrap is included"}}
+}
+
+// requestVars are the variables a router dispatches on.
+var requestVars = map[string]bool{
+	"$_get": true, "$_post": true, "$_request": true,
+	"$uri": true, "$url": true, "$path": true, "$route": true, "$method": true,
+	"$action": true, "$page": true, "$request": true, "$controller": true,
+}
+
+// requestServerKeys are the $_SERVER entries that identify a request.
+var requestServerKeys = map[string]bool{"REQUEST_URI": true, "REQUEST_METHOD": true, "PATH_INFO": true, "QUERY_STRING": true}
+
+// isDispatch reports whether the branch at code[i] decides on the request:
+// its condition reads the URL, method or query, or a variable named for one.
+func isDispatch(code []PHPToken, i int) bool {
+	if i+1 >= len(code) || !code[i+1].Is("(") {
+		return false
+	}
+	depth := 0
+	for j := i + 1; j < len(code); j++ {
+		t := code[j]
+		switch {
+		case t.Is("("):
+			depth++
+		case t.Is(")"):
+			depth--
+			if depth == 0 {
+				return false
+			}
+		case t.Kind == PHPVariable && requestVars[strings.ToLower(t.Text)]:
+			return true
+		case t.Kind == PHPVariable && strings.EqualFold(t.Text, "$_SERVER"):
+			if j+2 < len(code) && code[j+1].Is("[") {
+				if key, ok := code[j+2].StringValue(); ok && requestServerKeys[strings.ToUpper(key)] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func detectBranches(code, _ []PHPToken, limit int) []routerHit {
+	if limit <= 0 {
+		return nil
+	}
+	count, passedAt := 0, 0
+	for _, t := range code {
+		if t.Is("if") || t.Is("elseif") || t.Is("case") || t.Is("for") || t.Is("foreach") || t.Is("while") ||
+			t.Is("catch") || t.Is("match") || t.Is("?") || t.Is("&&") || t.Is("||") || t.Is("and") || t.Is("or") {
+			count++
+			if count == limit+1 {
+				passedAt = t.Line
+			}
+		}
+	}
+	if count <= limit {
+		return nil
+	}
+	return []routerHit{{passedAt, strconv.Itoa(count) + " decision points; the limit is " + strconv.Itoa(limit) + " and this is where it’s passed"}}
+}
+
+func detectLength(code, _ []PHPToken, limit int) []routerHit {
+	if limit <= 0 {
+		return nil
+	}
+	count, passedAt, last := 0, 0, 0
+	for _, t := range code {
+		if t.Line == last {
+			continue
+		}
+		last = t.Line
+		count++
+		if count == limit+1 {
+			passedAt = t.Line
+		}
+	}
+	if count <= limit {
+		return nil
+	}
+	return []routerHit{{passedAt, strconv.Itoa(count) + " lines of code; the limit is " + strconv.Itoa(limit) + " and this is where it’s passed"}}
+}
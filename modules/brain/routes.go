@@ -0,0 +1,761 @@
+package brain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rictusd/modules/core"
+)
+
+// Route is one route a router file defines.
+type Route struct {
+	Method  string `json:"method"` // GET, POST, ... or ANY
+	Path    string `json:"path"`   // as written; a regular expression for preg_match routes
+	Handler string `json:"handler"`
+	File    string `json:"file"` // the router file, project-relative
+	Line    int    `json:"line"`
+	Style   string `json:"style"` // switch, if, regex, array or call
+
+	Target string `json:"target,omitempty"` // handler file, project-relative
+	Class  string `json:"class,omitempty"`  // handler class as written
+	Action string `json:"action,omitempty"` // handler method
+	// Problem says why the handler can't be reached; empty when it can, or
+	// when it can't be checked (closures, variables, vendor classes).
+	Problem string `json:"problem,omitempty"`
+}
+
+// RouteTable is every route found in a project's router files.
+type RouteTable struct {
+	Project  string   `json:"project"`
+	Files    []string `json:"files"` // router files that define routes
+	Routes   []Route  `json:"routes"`
+	Problems int      `json:"problems"`
+}
+
+// routeVerbs are the router methods of Slim, Laravel and similar routers.
+var routeVerbs = map[string]bool{
+	"get": true, "post": true, "put": true, "patch": true, "delete": true,
+	"options": true, "any": true, "match": true, "map": true,
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true, "HEAD": true,
+}
+
+// Routes extracts the routes of the given router files and checks that
+// each handler file, class and method exists. Files that don't exist or
+// define no routes are left out.
+func (s *PHPScanner) Routes(ctx context.Context, p core.Project, files []string) (RouteTable, error) {
+	table := RouteTable{Project: p.Name, Files: make([]string, 0), Routes: make([]Route, 0)}
+
+	idx, _, err := NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return table, fmt.Errorf("routes: %w", err)
+	}
+	packages := installedPrefixes(p.Path)
+
+	seen := make(map[string]bool)
+	for _, rel := range files {
+		rel = filepath.ToSlash(filepath.Clean(rel))
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		data, err := os.ReadFile(filepath.Join(p.Path, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		routes := ExtractRoutes(data, rel)
+		if len(routes) == 0 {
+			continue
+		}
+		table.Files = append(table.Files, rel)
+
+		var scan PHPFileScan
+		if e, ok := idx.Files[rel]; ok && e.PHP != nil {
+			scan = *e.PHP
+		}
+		for i := range routes {
+			checkRoute(idx, scan, packages, &routes[i])
+			if routes[i].Problem != "" {
+				table.Problems++
+			}
+		}
+		table.Routes = append(table.Routes, routes...)
+	}
+	return table, nil
+}
+
+// ExtractRoutes finds the routes a PHP router file defines: switch and
+// if/elseif chains on the request path, preg_match routes, arrays keyed by
+// path, and $app->get() / Route::get() style calls. rel is the file's
+// project-relative path, used to resolve required handler files.
+func ExtractRoutes(src []byte, rel string) []Route {
+	return extractRoutes(PHPCode(LexPHP(src)), rel)
+}
+
+func extractRoutes(code []PHPToken, rel string) []Route {
+	x := &routeExtractor{code: code, file: rel, dir: path.Dir(rel)}
+	x.methodScopes()
+	for i := range x.code {
+		switch t := x.code[i]; {
+		case t.Is("switch"):
+			x.switchRoutes(i)
+		case t.Is("if") || t.Is("elseif"):
+			x.ifRoute(i)
+		case t.Is("=>"):
+			x.arrayRoute(i)
+		case t.Is("->") || t.Is("?->") || t.Is("::"):
+			x.callRoute(i)
+		}
+	}
+	sort.SliceStable(x.routes, func(i, j int) bool { return x.routes[i].Line < x.routes[j].Line })
+	return x.routes
+}
+
+type methodScope struct {
+	start, end int
+	method     string
+}
+
+type routeExtractor struct {
+	code   []PHPToken
+	file   string
+	dir    string
+	scopes []methodScope
+	routes []Route
+}
+
+// closing returns the index of the bracket matching the one at i, or
+// len(code) when it is never closed.
+func (x *routeExtractor) closing(i int) int {
+	open := x.code[i].Text
+	shut := map[string]string{"(": ")", "[": "]", "{": "}"}[open]
+	depth := 0
+	for j := i; j < len(x.code); j++ {
+		switch {
+		case x.code[j].Is(open):
+			depth++
+		case x.code[j].Is(shut):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(x.code)
+}
+
+// body returns the token range a branch runs: a brace block, an alternative
+// syntax block up to the next branch keyword, or a single statement.
+func (x *routeExtractor) body(i int) (start, end int) {
+	if i >= len(x.code) {
+		return i, i
+	}
+	if x.code[i].Is("{") {
+		return i + 1, x.closing(i)
+	}
+	if x.code[i].Is(":") {
+		for j := i + 1; j < len(x.code); j++ {
+			if t := x.code[j]; t.Is("elseif") || t.Is("else") || t.Is("endif") {
+				return i + 1, j
+			}
+		}
+		return i + 1, len(x.code)
+	}
+	for j := i; j < len(x.code); j++ {
+		if x.code[j].Is(";") {
+			return i, j + 1
+		}
+	}
+	return i, len(x.code)
+}
+
+// isMethodExpr reports whether tokens read the request method:
+// $_SERVER['REQUEST_METHOD'] or a variable named like one.
+func isMethodExpr(toks []PHPToken) bool {
+	for _, t := range toks {
+		if v, ok := t.StringValue(); ok && v == "REQUEST_METHOD" {
+			return true
+		}
+		if t.Kind == PHPVariable {
+			switch strings.ToLower(t.Text) {
+			case "$method", "$verb", "$httpmethod", "$http_method", "$requestmethod", "$request_method":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// comparedStrings returns the string literals compared with == or === in a
+// condition.
+func comparedStrings(cond []PHPToken) []string {
+	var vals []string
+	for i, t := range cond {
+		v, ok := t.StringValue()
+		if !ok {
+			continue
+		}
+		prev := i > 0 && (cond[i-1].Is("==") || cond[i-1].Is("==="))
+		next := i+1 < len(cond) && (cond[i+1].Is("==") || cond[i+1].Is("==="))
+		if prev || next {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+// methodScopes records the token ranges guarded by a request method check,
+// so routes nested inside them get that method.
+func (x *routeExtractor) methodScopes() {
+	for i, t := range x.code {
+		switch {
+		case t.Is("if") || t.Is("elseif"):
+			if i+1 >= len(x.code) || !x.code[i+1].Is("(") {
+				continue
+			}
+			end := x.closing(i + 1)
+			if end >= len(x.code) {
+				continue
+			}
+			cond := x.code[i+2 : end]
+			if !isMethodExpr(cond) {
+				continue
+			}
+			for _, v := range comparedStrings(cond) {
+				if m := strings.ToUpper(v); httpMethods[m] {
+					s, e := x.body(end + 1)
+					x.scopes = append(x.scopes, methodScope{s, e, m})
+					break
+				}
+			}
+		case t.Is("switch"):
+			if i+1 >= len(x.code) || !x.code[i+1].Is("(") {
+				continue
+			}
+			end := x.closing(i + 1)
+			if end+1 >= len(x.code) || !isMethodExpr(x.code[i+2:end]) {
+				continue
+			}
+			for _, c := range x.cases(end + 1) {
+				for _, v := range c.values {
+					if m := strings.ToUpper(v); httpMethods[m] {
+						x.scopes = append(x.scopes, methodScope{c.start, c.end, m})
+					}
+				}
+			}
+		}
+	}
+}
+
+// methodAt returns the method of the innermost method scope holding token i.
+func (x *routeExtractor) methodAt(i int) string {
+	method, width := "ANY", -1
+	for _, s := range x.scopes {
+		if i >= s.start && i < s.end && (width < 0 || s.end-s.start < width) {
+			method, width = s.method, s.end-s.start
+		}
+	}
+	return method
+}
+
+type switchCase struct {
+	values     []string
+	line       int
+	start, end int // body tokens
+}
+
+// cases splits the switch block opening at i into its cases. Consecutive
+// labels share the body that follows them.
+func (x *routeExtractor) cases(i int) []switchCase {
+	if i >= len(x.code) || !x.code[i].Is("{") {
+		return nil
+	}
+	end := x.closing(i)
+
+	var out []switchCase
+	var cur *switchCase
+	depth := 0
+	for j := i + 1; j < end; j++ {
+		t := x.code[j]
+		switch {
+		case t.Is("{") || t.Is("(") || t.Is("["):
+			depth++
+		case t.Is("}") || t.Is(")") || t.Is("]"):
+			depth--
+		case depth == 0 && (t.Is("case") || t.Is("default")):
+			label := j + 1
+			for label < end && !x.code[label].Is(":") && !x.code[label].Is(";") {
+				label++
+			}
+			if cur != nil && cur.start < j {
+				cur.end = j
+				out = append(out, *cur)
+				cur = nil
+			}
+			if cur == nil {
+				cur = &switchCase{line: t.Line}
+			}
+			if t.Is("case") && label == j+2 {
+				if v, ok := x.code[j+1].StringValue(); ok {
+					cur.values = append(cur.values, v)
+				}
+			}
+			cur.start = label + 1
+			j = label
+		}
+	}
+	if cur != nil {
+		cur.end = end
+		out = append(out, *cur)
+	}
+	return out
+}
+
+// isPathExpr reports whether a switch subject looks like the request path.
+func isPathExpr(toks []PHPToken) bool {
+	for _, t := range toks {
+		if v, ok := t.StringValue(); ok && (v == "REQUEST_URI" || v == "PATH_INFO") {
+			return true
+		}
+		if t.Kind == PHPVariable {
+			name := strings.ToLower(strings.TrimPrefix(t.Text, "$"))
+			for _, w := range []string{"uri", "path", "route", "url", "page", "action", "request"} {
+				if strings.Contains(name, w) {
+					return true
+				}
+			}
+		}
+		if t.Is("parse_url") {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *routeExtractor) switchRoutes(i int) {
+	if i+1 >= len(x.code) || !x.code[i+1].Is("(") {
+		return
+	}
+	end := x.closing(i + 1)
+	if end+1 >= len(x.code) {
+		return
+	}
+	subject := x.code[i+2 : end]
+	if isMethodExpr(subject) {
+		return
+	}
+	pathy := isPathExpr(subject)
+
+	for _, c := range x.cases(end + 1) {
+		for _, v := range c.values {
+			if !pathy && !strings.HasPrefix(v, "/") {
+				continue
+			}
+			r := Route{Method: x.methodAt(c.start), Path: v, File: x.file, Line: c.line, Style: "switch"}
+			x.dispatch(&r, x.code[c.start:c.end])
+			x.routes = append(x.routes, r)
+		}
+	}
+}
+
+func (x *routeExtractor) ifRoute(i int) {
+	if i+1 >= len(x.code) || !x.code[i+1].Is("(") {
+		return
+	}
+	end := x.closing(i + 1)
+	if end >= len(x.code) {
+		return
+	}
+	cond := x.code[i+2 : end]
+
+	r := Route{Method: x.methodAt(i), File: x.file, Line: x.code[i].Line, Style: "if"}
+	for _, v := range comparedStrings(cond) {
+		switch {
+		case httpMethods[strings.ToUpper(v)] && isMethodExpr(cond):
+			r.Method = strings.ToUpper(v)
+		case r.Path == "" && strings.HasPrefix(v, "/"):
+			r.Path = v
+		}
+	}
+	for k := 0; k+2 < len(cond) && r.Path == ""; k++ {
+		if cond[k].Is("preg_match") && cond[k+1].Is("(") {
+			if v, ok := cond[k+2].StringValue(); ok {
+				r.Path, r.Style = v, "regex"
+			}
+		}
+	}
+	if r.Path == "" {
+		return
+	}
+
+	s, e := x.body(end + 1)
+	x.dispatch(&r, x.code[s:e])
+	x.routes = append(x.routes, r)
+}
+
+// arrayRoute handles "'/path' => handler" entries of a route map.
+func (x *routeExtractor) arrayRoute(i int) {
+	if i == 0 || i+1 >= len(x.code) {
+		return
+	}
+	key, ok := x.code[i-1].StringValue()
+	if !ok {
+		return
+	}
+	method, p := "ANY", key
+	if sp := strings.IndexByte(key, ' '); sp > 0 && httpMethods[strings.ToUpper(key[:sp])] {
+		method, p = strings.ToUpper(key[:sp]), strings.TrimSpace(key[sp+1:])
+	}
+	if !strings.HasPrefix(p, "/") {
+		return
+	}
+
+	end := i + 1
+	depth := 0
+	for ; end < len(x.code); end++ {
+		t := x.code[end]
+		if t.Is("(") || t.Is("[") || t.Is("{") {
+			depth++
+		} else if t.Is(")") || t.Is("]") || t.Is("}") {
+			if depth == 0 {
+				break
+			}
+			depth--
+		} else if depth == 0 && (t.Is(",") || t.Is(";")) {
+			break
+		}
+	}
+
+	if method == "ANY" {
+		method = x.methodAt(i)
+	}
+	r := Route{Method: method, Path: p, File: x.file, Line: x.code[i-1].Line, Style: "array"}
+	x.handlerExpr(&r, x.code[i+1:end])
+	x.routes = append(x.routes, r)
+}
+
+// callRoute handles $app->get('/path', handler) and Route::get(...) calls.
+func (x *routeExtractor) callRoute(i int) {
+	if i == 0 || i+2 >= len(x.code) || x.code[i+1].Kind != PHPName || !x.code[i+2].Is("(") {
+		return
+	}
+	verb := strings.ToLower(x.code[i+1].Text)
+	if !routeVerbs[verb] {
+		return
+	}
+	recv := x.code[i-1]
+	static := x.code[i].Is("::")
+	if static && !strings.HasSuffix(strings.ToLower(recv.Text), "route") && !strings.HasSuffix(strings.ToLower(recv.Text), "router") {
+		return
+	}
+	if !static && recv.Kind != PHPVariable && !recv.Is(")") {
+		return
+	}
+
+	args := x.args(i + 2)
+	methods := []string{strings.ToUpper(verb)}
+	if verb == "match" || verb == "map" {
+		if len(args) == 0 {
+			return
+		}
+		methods = methods[:0]
+		for _, t := range args[0] {
+			if v, ok := t.StringValue(); ok {
+				methods = append(methods, strings.ToUpper(v))
+			}
+		}
+		args = args[1:]
+	}
+	if len(args) < 2 || len(args[0]) != 1 {
+		return
+	}
+	p, ok := args[0][0].StringValue()
+	if !ok || (!static && !strings.HasPrefix(p, "/")) {
+		return
+	}
+
+	r := Route{Method: strings.Join(methods, "|"), Path: p, File: x.file, Line: x.code[i+1].Line, Style: "call"}
+	if verb == "any" || len(methods) == 0 {
+		r.Method = "ANY"
+	}
+	x.handlerExpr(&r, args[1])
+	x.routes = append(x.routes, r)
+}
+
+// args splits the argument list of the call whose "(" is at i.
+func (x *routeExtractor) args(i int) [][]PHPToken {
+	end := x.closing(i)
+	var out [][]PHPToken
+	start, depth := i+1, 0
+	for j := i + 1; j < end; j++ {
+		t := x.code[j]
+		switch {
+		case t.Is("(") || t.Is("[") || t.Is("{"):
+			depth++
+		case t.Is(")") || t.Is("]") || t.Is("}"):
+			depth--
+		case depth == 0 && t.Is(","):
+			out = append(out, x.code[start:j])
+			start = j + 1
+		}
+	}
+	if start < end {
+		out = append(out, x.code[start:end])
+	}
+	return out
+}
+
+// handlerExpr reads a handler given as a value: "Class@method",
+// "Class:method", "file.php", [Class::class, 'method'], Class::class or a
+// closure.
+func (x *routeExtractor) handlerExpr(r *Route, expr []PHPToken) {
+	if len(expr) == 0 {
+		return
+	}
+	if v, ok := expr[0].StringValue(); ok && len(expr) == 1 {
+		switch {
+		case strings.HasSuffix(strings.ToLower(v), ".php"):
+			r.Target = path.Clean(path.Join(x.dir, v))
+			if strings.HasPrefix(v, "/") {
+				r.Target = path.Clean(v[1:])
+			}
+			r.Handler = v
+		case strings.Contains(v, "@"):
+			r.Class, r.Action = v[:strings.IndexByte(v, '@')], v[strings.IndexByte(v, '@')+1:]
+		case strings.Count(v, ":") == 1 && !strings.Contains(v, "::"):
+			r.Class, r.Action = v[:strings.IndexByte(v, ':')], v[strings.IndexByte(v, ':')+1:]
+		default:
+			r.Handler = v
+		}
+		r.setHandler()
+		return
+	}
+
+	switch first := expr[0]; {
+	case first.Is("function") || first.Is("fn") || first.Is("static"):
+		r.Handler = "closure"
+		return
+	case first.Kind == PHPName && len(expr) == 3 && expr[1].Is("::") && expr[2].Is("class"):
+		r.Class, r.Action = first.Text, "__invoke"
+		r.setHandler()
+		return
+	case first.Kind == PHPName && len(expr) == 5 && expr[1].Is("::") && expr[2].Is("class") && expr[3].Is("."):
+		// Slim's Class::class . ':method'.
+		if v, ok := expr[4].StringValue(); ok && (strings.HasPrefix(v, ":") || strings.HasPrefix(v, "@")) {
+			r.Class, r.Action = first.Text, v[1:]
+			r.setHandler()
+			return
+		}
+	case first.Is("[") || (first.Is("array") && len(expr) > 1 && expr[1].Is("(")):
+		open := 0
+		if first.Is("array") {
+			open = 1
+		}
+		var parts []string
+		for k := open + 1; k < len(expr)-1; k++ {
+			t := expr[k]
+			if v, ok := t.StringValue(); ok {
+				parts = append(parts, v)
+			} else if t.Kind == PHPName && k+2 < len(expr) && expr[k+1].Is("::") && expr[k+2].Is("class") {
+				parts = append(parts, t.Text)
+				k += 2
+			}
+		}
+		if len(parts) == 2 {
+			r.Class, r.Action = parts[0], parts[1]
+			r.setHandler()
+			return
+		}
+	}
+
+	r.Handler = joinTokens(expr)
+}
+
+// dispatch finds what a branch body hands the request to: a required file,
+// Class::method(), (new Class)->method(), or the first function the body
+// calls.
+func (x *routeExtractor) dispatch(r *Route, body []PHPToken) {
+	for k := 0; k < len(body); k++ {
+		t := body[k]
+		switch {
+		case isRequireKeyword(t):
+			if target, cand, ok := requireTarget(requireExpr(body[k+1:]), x.dir); ok {
+				r.Target, r.Handler = filepath.ToSlash(cand), target
+				return
+			}
+		case t.Is("new") && k+1 < len(body) && body[k+1].Kind == PHPName:
+			r.Class = body[k+1].Text
+			for j := k + 2; j+2 < len(body); j++ {
+				if (body[j].Is("->") || body[j].Is("?->")) && body[j+1].Kind == PHPName && body[j+2].Is("(") {
+					r.Action = body[j+1].Text
+					break
+				}
+			}
+			r.setHandler()
+			return
+		case t.Kind == PHPName && k+3 < len(body) && body[k+1].Is("::") && body[k+2].Kind == PHPName && body[k+3].Is("("):
+			if t.Is("self") || t.Is("static") || t.Is("parent") {
+				continue
+			}
+			r.Class, r.Action = t.Text, body[k+2].Text
+			r.setHandler()
+			return
+		}
+	}
+	for k := 0; k+1 < len(body); k++ {
+		if name, ok := callName(body, k); ok && !dispatchNoise[name] {
+			r.Handler = body[k].Text + "()"
+			return
+		}
+	}
+}
+
+// dispatchNoise are calls a route body makes that aren't its handler.
+var dispatchNoise = map[string]bool{
+	"header": true, "http_response_code": true, "isset": true, "empty": true, "exit": true, "die": true,
+	"preg_match": true, "strpos": true, "str_starts_with": true, "trim": true, "rtrim": true, "ltrim": true,
+	"parse_url": true, "explode": true, "implode": true, "count": true, "array_shift": true, "intval": true,
+	"session_start": true, "ob_start": true, "define": true, "defined": true, "array": true, "list": true,
+}
+
+func (r *Route) setHandler() {
+	switch {
+	case r.Class != "" && r.Action != "":
+		r.Handler = r.Class + "::" + r.Action
+	case r.Class != "":
+		r.Handler = r.Class
+	}
+}
+
+func joinTokens(toks []PHPToken) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 && t.Kind != PHPPunct && toks[i-1].Kind != PHPPunct {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String()
+}
+
+// checkRoute fills in r.Problem when the handler file, class or method
+// doesn't exist. scan is the router file's own scan, for its imports.
+func checkRoute(idx *ProjectIndex, scan PHPFileScan, packages []string, r *Route) {
+	if r.Target != "" {
+		if !idx.Has(r.Target) && !idx.Has(r.Target+".php") {
+			r.Problem = "handler file " + r.Target + " doesn’t exist"
+		}
+		return
+	}
+	if r.Class == "" {
+		return
+	}
+
+	fqn := resolveRouteClass(r.Class, scan)
+	for _, p := range packages {
+		if strings.HasPrefix(strings.ToLower(fqn), strings.ToLower(p)) {
+			return // provided by a vendor package
+		}
+	}
+
+	cls, file, ok := findClass(idx, fqn)
+	if !ok {
+		r.Problem = "class " + r.Class + " isn’t defined in the project"
+		return
+	}
+	if r.Action == "" {
+		return
+	}
+
+	if classHandles(idx, cls, file, r.Action, 0) {
+		return
+	}
+	if strings.EqualFold(r.Action, "__invoke") {
+		r.Problem = "class " + r.Class + " isn’t invokable"
+		return
+	}
+	r.Problem = "method " + r.Class + "::" + r.Action + " doesn’t exist"
+}
+
+// resolveRouteClass qualifies a class name against the router file's
+// imports and namespace.
+func resolveRouteClass(name string, scan PHPFileScan) string {
+	if strings.HasPrefix(name, `\`) {
+		return name[1:]
+	}
+	first, rest, _ := strings.Cut(name, `\`)
+	for _, u := range scan.Uses {
+		if u.Kind == "class" && strings.EqualFold(u.Alias, first) {
+			if rest == "" {
+				return u.Name
+			}
+			return u.Name + `\` + rest
+		}
+	}
+	for _, s := range scan.Symbols {
+		if s.Kind == "namespace" && s.FQN != "" {
+			return s.FQN + `\` + name
+		}
+	}
+	return name
+}
+
+// findClass looks a class up by its qualified name, falling back to its
+// short name when only one class has it.
+func findClass(idx *ProjectIndex, fqn string) (PHPSymbol, *FileEntry, bool) {
+	var short []SymbolHit
+	for _, h := range idx.FindSymbol(lastSegment(fqn)) {
+		if !isClassKind(h.Kind) {
+			continue
+		}
+		if strings.EqualFold(h.FQN, fqn) {
+			return h.PHPSymbol, idx.Files[h.File], true
+		}
+		short = append(short, h)
+	}
+	if len(short) == 1 {
+		return short[0].PHPSymbol, idx.Files[short[0].File], true
+	}
+	return PHPSymbol{}, nil, false
+}
+
+// classHandles reports whether a class answers calls to method: it or a
+// trait it uses declares the method, or a __call catches it, here or up the
+// inheritance chain. Parents and traits outside the project are assumed to
+// provide it.
+func classHandles(idx *ProjectIndex, cls PHPSymbol, file *FileEntry, method string, depth int) bool {
+	if depth > 10 {
+		return false
+	}
+	for _, s := range file.PHP.Symbols {
+		if s.Kind != "method" || !strings.EqualFold(s.Parent, cls.FQN) {
+			continue
+		}
+		if strings.EqualFold(s.Name, method) || (strings.EqualFold(s.Name, "__call") && !strings.EqualFold(method, "__invoke")) {
+			return true
+		}
+	}
+
+	for _, t := range cls.Traits {
+		trait, traitFile, ok := findClass(idx, t)
+		if !ok || classHandles(idx, trait, traitFile, method, depth+1) {
+			return true
+		}
+	}
+
+	if cls.Extends == "" {
+		return false
+	}
+	parent, parentFile, ok := findClass(idx, cls.Extends)
+	if !ok {
+		return true // inherits from something outside the project
+	}
+	return classHandles(idx, parent, parentFile, method, depth+1)
+}
@@ -0,0 +1,133 @@
+package brain
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SARIFSchema is the schema URI written into SARIF logs.
+const SARIFSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// The SARIF 2.1.0 subset RictusD writes: one run, its rules, and results
+// located relative to the project root.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool               sarifTool                  `json:"tool"`
+		OriginalURIBaseIDs map[string]sarifArtifactID `json:"originalUriBaseIds,omitempty"`
+		Results            []sarifResult              `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string       `json:"id"`
+		ShortDescription     sarifMessage `json:"shortDescription"`
+		DefaultConfiguration struct {
+			Level Severity `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	sarifResult struct {
+		RuleID     string            `json:"ruleId"`
+		RuleIndex  int               `json:"ruleIndex"`
+		Level      Severity          `json:"level"`
+		Message    sarifMessage      `json:"message"`
+		Locations  []sarifLocation   `json:"locations,omitempty"`
+		Properties map[string]string `json:"properties,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysical `json:"physicalLocation"`
+	}
+	sarifPhysical struct {
+		ArtifactLocation sarifArtifactID `json:"artifactLocation"`
+		Region           *sarifRegion    `json:"region,omitempty"`
+	}
+	sarifArtifactID struct {
+		URI       string `json:"uri"`
+		URIBaseID string `json:"uriBaseId,omitempty"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+		EndLine   int `json:"endLine,omitempty"`
+	}
+)
+
+// SARIF renders findings as a SARIF 2.1.0 log. File paths stay relative to
+// root, which is recorded as the %SRCROOT% base. Suggested fixes and law
+// citations travel as result properties, since SARIF fixes must be edits.
+func SARIF(root string, findings []Finding) ([]byte, error) {
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		if !seen[f.RuleID] {
+			seen[f.RuleID] = true
+			ids = append(ids, f.RuleID)
+		}
+	}
+	sort.Strings(ids)
+
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "RictusD", Rules: make([]sarifRule, 0, len(ids))}},
+		Results: make([]sarifResult, 0, len(findings)),
+	}
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		rule := RuleFor(id)
+		sr := sarifRule{ID: id, ShortDescription: sarifMessage{Text: rule.Summary}}
+		sr.DefaultConfiguration.Level = rule.Severity
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sr)
+		index[id] = i
+	}
+
+	if root != "" {
+		abs, err := filepath.Abs(root)
+		if err == nil {
+			u := url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+			run.OriginalURIBaseIDs = map[string]sarifArtifactID{"%SRCROOT%": {URI: strings.TrimSuffix(u.String(), "/") + "/"}}
+		}
+	}
+
+	for _, f := range findings {
+		res := sarifResult{
+			RuleID:    f.RuleID,
+			RuleIndex: index[f.RuleID],
+			Level:     f.Severity,
+			Message:   sarifMessage{Text: f.Message},
+		}
+		if f.File != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysical{
+				ArtifactLocation: sarifArtifactID{URI: (&url.URL{Path: f.File}).EscapedPath(), URIBaseID: "%SRCROOT%"},
+			}}
+			if f.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line, EndLine: f.EndLine}
+			}
+			res.Locations = []sarifLocation{loc}
+		}
+		if f.Fix != "" || f.Law != "" {
+			res.Properties = make(map[string]string)
+			if f.Fix != "" {
+				res.Properties["fix"] = f.Fix
+			}
+			if f.Law != "" {
+				res.Properties["law"] = f.Law
+			}
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	return json.MarshalIndent(sarifLog{Schema: SARIFSchema, Version: "2.1.0", Runs: []sarifRun{run}}, "", "  ")
+}
@@ -0,0 +1,277 @@
+package brain
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"rictusd/modules/core"
+)
+
+// ScanWorkers bounds how many files are read and scanned at once.
+var ScanWorkers = runtime.NumCPU()
+
+// progressEvery throttles progress callbacks and log lines.
+const progressEvery = time.Second
+
+// Progress is a snapshot of a running refresh.
+type Progress struct {
+	Project string    `json:"project"`
+	Walked  int       `json:"walked"` // files found so far
+	Done    int       `json:"done"`   // files indexed so far
+	Walking bool      `json:"walking"`
+	Started time.Time `json:"started"`
+}
+
+// ProgressFunc receives progress snapshots, at most once per second plus a
+// final one. It is called from the refreshing goroutine.
+type ProgressFunc func(Progress)
+
+// scanJob is a file the walker found.
+type scanJob struct {
+	key  string
+	info fs.FileInfo
+	old  *FileEntry
+}
+
+type scanOutcome int
+
+const (
+	outcomeReused scanOutcome = iota
+	outcomeRehashed
+	outcomeRescanned
+	outcomeFailed
+)
+
+// scanResult is what a worker made of one job.
+type scanResult struct {
+	key     string
+	entry   *FileEntry
+	outcome scanOutcome
+}
+
+// Active returns the refreshes currently running, ordered by project.
+func (x *Indexer) Active() []Progress {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	out := make([]Progress, 0, len(x.active))
+	for _, pr := range x.active {
+		out = append(out, pr)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Project < out[j].Project })
+	return out
+}
+
+// scan builds a fresh index for p. One walker applies the ignore rules and
+// feeds files to a bounded pool of workers, which hash and scan them and
+// send results back to this goroutine. Cancelling ctx stops the walk and
+// the workers; the partial result is discarded.
+func (x *Indexer) scan(ctx context.Context, p core.Project, prev *ProjectIndex, ign *Ignore, arch core.ArchitectureConfig, progress ProgressFunc) (*ProjectIndex, RefreshStats, error) {
+	start := time.Now()
+	var stats RefreshStats
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	next := &ProjectIndex{
+		Version: indexVersion,
+		Project: p.Name,
+		Root:    p.Path,
+		Dirs:    make([]string, 0),
+		Files:   make(map[string]*FileEntry),
+	}
+
+	workers := ScanWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan scanJob, workers*4)
+	results := make(chan scanResult, workers*4)
+
+	var (
+		walkErr error
+		walked  int
+		walkMu  sync.Mutex
+		walking = true
+	)
+
+	// Walker: the only goroutine that touches ign and next.Dirs.
+	go func() {
+		defer close(jobs)
+
+		err := filepath.WalkDir(p.Path, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				x.core.Log.Warnf("index: walk error on %s: %v", path, err)
+				return nil
+			}
+
+			rel, relErr := filepath.Rel(p.Path, path)
+			if relErr != nil {
+				return nil
+			}
+			if ign.Skip(rel, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if d.IsDir() {
+				next.Dirs = append(next.Dirs, filepath.ToSlash(rel))
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+
+			key := filepath.ToSlash(rel)
+			walkMu.Lock()
+			walked++
+			walkMu.Unlock()
+
+			select {
+			case jobs <- scanJob{key: key, info: info, old: prev.Files[key]}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		walkMu.Lock()
+		walkErr = err
+		walking = false
+		walkMu.Unlock()
+	}()
+
+	// Workers.
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				results <- x.scanOne(p.Path, job, arch)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	snapshot := func() Progress {
+		walkMu.Lock()
+		defer walkMu.Unlock()
+		return Progress{
+			Project: p.Name,
+			Walked:  walked,
+			Done:    stats.Reused + stats.Rehashed + stats.Rescanned,
+			Walking: walking,
+			Started: start,
+		}
+	}
+	report := func(pr Progress) {
+		x.mu.Lock()
+		x.active[p.Name] = pr
+		x.mu.Unlock()
+		if progress != nil {
+			progress(pr)
+		}
+	}
+	defer func() {
+		x.mu.Lock()
+		delete(x.active, p.Name)
+		x.mu.Unlock()
+	}()
+
+	report(snapshot())
+	lastReport := time.Now()
+
+	for res := range results {
+		switch res.outcome {
+		case outcomeReused:
+			stats.Reused++
+		case outcomeRehashed:
+			stats.Rehashed++
+		case outcomeRescanned:
+			stats.Rescanned++
+		case outcomeFailed:
+			continue
+		}
+		next.Files[res.key] = res.entry
+
+		if time.Since(lastReport) >= progressEvery {
+			pr := snapshot()
+			report(pr)
+			x.core.Log.Infof("index: %s: %d/%d files indexed", p.Name, pr.Done, pr.Walked)
+			lastReport = time.Now()
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return prev, stats, fmt.Errorf("index: %s: %w", p.Name, err)
+	}
+	walkMu.Lock()
+	err := walkErr
+	walkMu.Unlock()
+	if err != nil {
+		return prev, stats, fmt.Errorf("index: walk project: %w", err)
+	}
+
+	for key := range prev.Files {
+		if _, ok := next.Files[key]; !ok {
+			stats.Removed++
+		}
+	}
+
+	sort.Strings(next.Dirs)
+	next.Skipped = ign.Stats()
+	next.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	stats.Files = len(next.Files)
+	stats.Took = time.Since(start)
+
+	report(snapshot())
+
+	return next, stats, nil
+}
+
+// scanOne reuses, rehashes or rescans one file. Roles pinned in the
+// project's config override the classified ones.
+func (x *Indexer) scanOne(root string, job scanJob, arch core.ArchitectureConfig) scanResult {
+	old := keepable(job.old, arch)
+	if old != nil && old.Size == job.info.Size() && old.ModTime == job.info.ModTime().UnixNano() {
+		if e := pinRole(old, arch); e != old {
+			return scanResult{key: job.key, entry: e, outcome: outcomeRehashed}
+		}
+		return scanResult{key: job.key, entry: old, outcome: outcomeReused}
+	}
+
+	entry, rehashed, err := x.indexFile(root, job.key, job.info, old)
+	if err != nil {
+		x.core.Log.Warnf("index: %s: %v", job.key, err)
+		return scanResult{key: job.key, outcome: outcomeFailed}
+	}
+	entry = pinRole(entry, arch)
+	if rehashed {
+		return scanResult{key: job.key, entry: entry, outcome: outcomeRehashed}
+	}
+	return scanResult{key: job.key, entry: entry, outcome: outcomeRescanned}
+}
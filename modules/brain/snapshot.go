@@ -0,0 +1,327 @@
+package brain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"rictusd/modules/core"
+)
+
+// DefaultSnapshotRetention is how many map snapshots are kept per project
+// when its .rictus config doesn't say otherwise.
+const DefaultSnapshotRetention = 20
+
+// snapshotStamp names snapshot files so they sort by time.
+const snapshotStamp = "20060102T150405.000Z"
+
+// MapSnapshot is a ProjectMap plus what is needed to diff it later: a hash
+// per file and the PHP issues seen at the time.
+type MapSnapshot struct {
+	TakenAt string            `json:"taken_at"` // RFC3339
+	Map     ProjectMap        `json:"map"`
+	Files   map[string]string `json:"files"`  // path -> sha256
+	Issues  []string          `json:"issues"` // sorted issue keys
+}
+
+// MapDiff is what changed between two snapshots.
+type MapDiff struct {
+	From             string   `json:"from"`
+	To               string   `json:"to"`
+	Added            []string `json:"added"`
+	Removed          []string `json:"removed"`
+	Modified         []string `json:"modified"`
+	LanguagesAdded   []string `json:"languages_added"`
+	LanguagesRemoved []string `json:"languages_removed"`
+	DepthBefore      int      `json:"depth_before"`
+	DepthAfter       int      `json:"depth_after"`
+	FilesBefore      int      `json:"files_before"`
+	FilesAfter       int      `json:"files_after"`
+	NewIssues        []string `json:"new_issues"`
+	ResolvedIssues   []string `json:"resolved_issues"`
+}
+
+// Empty reports whether nothing changed.
+func (d MapDiff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Modified)+
+		len(d.LanguagesAdded)+len(d.LanguagesRemoved)+
+		len(d.NewIssues)+len(d.ResolvedIssues) == 0 &&
+		d.DepthBefore == d.DepthAfter
+}
+
+// Changes maps the project again and compares the result with the snapshot
+// from the previous map, so calling it repeatedly reports what changed since
+// last time. ok is false when there was no earlier snapshot to compare with.
+func (m *Mapper) Changes(ctx context.Context, p core.Project) (diff MapDiff, ok bool, err error) {
+	stamps, err := m.snapshotStamps(p.Name)
+	if err != nil {
+		return MapDiff{}, false, err
+	}
+
+	var prev MapSnapshot
+	if len(stamps) > 0 {
+		if prev, err = m.loadSnapshot(p.Name, stamps[len(stamps)-1]); err != nil {
+			return MapDiff{}, false, err
+		}
+	}
+
+	if _, err := m.MapProjectContext(ctx, p); err != nil {
+		return MapDiff{}, false, err
+	}
+	if len(stamps) == 0 {
+		return MapDiff{}, false, nil
+	}
+
+	// A map only writes a snapshot when something changed, so the latest one
+	// is either the map just taken or prev itself.
+	stamps, err = m.snapshotStamps(p.Name)
+	if err != nil || len(stamps) == 0 {
+		return MapDiff{}, false, err
+	}
+	latest, err := m.loadSnapshot(p.Name, stamps[len(stamps)-1])
+	if err != nil {
+		return MapDiff{}, false, err
+	}
+	return DiffSnapshots(prev, latest), true, nil
+}
+
+// DiffLatest compares the two most recent saved snapshots without mapping
+// the project again.
+func (m *Mapper) DiffLatest(name string) (MapDiff, bool, error) {
+	stamps, err := m.snapshotStamps(name)
+	if err != nil || len(stamps) < 2 {
+		return MapDiff{}, false, err
+	}
+
+	prev, err := m.loadSnapshot(name, stamps[len(stamps)-2])
+	if err != nil {
+		return MapDiff{}, false, err
+	}
+	latest, err := m.loadSnapshot(name, stamps[len(stamps)-1])
+	if err != nil {
+		return MapDiff{}, false, err
+	}
+	return DiffSnapshots(prev, latest), true, nil
+}
+
+// DiffSnapshots compares two snapshots, older first.
+func DiffSnapshots(a, b MapSnapshot) MapDiff {
+	d := MapDiff{
+		From:             a.TakenAt,
+		To:               b.TakenAt,
+		Added:            make([]string, 0),
+		Removed:          make([]string, 0),
+		Modified:         make([]string, 0),
+		LanguagesAdded:   setDiff(b.Map.Languages, a.Map.Languages),
+		LanguagesRemoved: setDiff(a.Map.Languages, b.Map.Languages),
+		DepthBefore:      a.Map.MaxDepth,
+		DepthAfter:       b.Map.MaxDepth,
+		FilesBefore:      a.Map.TotalFiles,
+		FilesAfter:       b.Map.TotalFiles,
+		NewIssues:        setDiff(b.Issues, a.Issues),
+		ResolvedIssues:   setDiff(a.Issues, b.Issues),
+	}
+
+	for path, hash := range b.Files {
+		old, ok := a.Files[path]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, path)
+		case old != hash:
+			d.Modified = append(d.Modified, path)
+		}
+	}
+	for path := range a.Files {
+		if _, ok := b.Files[path]; !ok {
+			d.Removed = append(d.Removed, path)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Modified)
+	return d
+}
+
+// snapshot records the current map when it differs from the latest
+// snapshot, then prunes old ones. It returns whether a snapshot was written.
+func (m *Mapper) snapshot(p core.Project, pm ProjectMap, idx *ProjectIndex) (bool, error) {
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		m.core.Log.Warnf("mapper: %s: %v; using defaults", p.Name, err)
+	}
+
+	now := time.Now().UTC()
+	snap := MapSnapshot{
+		TakenAt: now.Format(time.RFC3339),
+		Map:     pm,
+		Files:   make(map[string]string, len(idx.Files)),
+		Issues:  NewPHPScanner(m.core).issueKeys(p.Path, idx, cfg),
+	}
+	for path, e := range idx.Files {
+		snap.Files[path] = e.Hash
+	}
+	sort.Strings(snap.Issues)
+
+	stamps, err := m.snapshotStamps(p.Name)
+	if err != nil {
+		return false, err
+	}
+	if len(stamps) > 0 {
+		if latest, err := m.loadSnapshot(p.Name, stamps[len(stamps)-1]); err == nil && sameSnapshot(latest, snap) {
+			return false, nil
+		}
+	}
+
+	dir := m.snapshotDir(p.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, fmt.Errorf("create snapshot dir: %w", err)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return false, fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	final := filepath.Join(dir, now.Format(snapshotStamp)+".json")
+	tmp := final + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return false, fmt.Errorf("write temp snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return false, fmt.Errorf("rename snapshot: %w", err)
+	}
+
+	keep := cfg.Snapshots
+	if keep <= 0 {
+		keep = DefaultSnapshotRetention
+	}
+	stamps = append(stamps, now.Format(snapshotStamp))
+	for len(stamps) > keep {
+		if err := os.Remove(filepath.Join(dir, stamps[0]+".json")); err != nil && !os.IsNotExist(err) {
+			m.core.Log.Warnf("mapper: prune snapshot: %v", err)
+		}
+		stamps = stamps[1:]
+	}
+
+	return true, nil
+}
+
+// mapExts are the per-project files kept next to the map: the include
+// graph and the findings exports.
+func mapExts() []string {
+	out := append([]string{}, graphExts...)
+	for _, ext := range findingsExts {
+		out = append(out, ext)
+	}
+	return out
+}
+
+// RenameSnapshots moves a project's map, graph, findings and snapshots to
+// its new name.
+func (m *Mapper) RenameSnapshots(oldName, newName string) error {
+	mapsDir := filepath.Join(m.core.Data, "maps")
+	pairs := [][2]string{
+		{m.snapshotDir(oldName), m.snapshotDir(newName)},
+		{filepath.Join(mapsDir, oldName+".json"), filepath.Join(mapsDir, newName+".json")},
+	}
+	for _, ext := range mapExts() {
+		pairs = append(pairs, [2]string{filepath.Join(mapsDir, oldName+ext), filepath.Join(mapsDir, newName+ext)})
+	}
+	for _, pair := range pairs {
+		if err := os.Rename(pair[0], pair[1]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rename maps: %w", err)
+		}
+	}
+	return nil
+}
+
+// ForgetSnapshots removes a project's map, graph, findings and snapshots.
+func (m *Mapper) ForgetSnapshots(name string) error {
+	if err := os.RemoveAll(m.snapshotDir(name)); err != nil {
+		return fmt.Errorf("remove snapshots: %w", err)
+	}
+	for _, ext := range append([]string{".json"}, mapExts()...) {
+		if err := os.Remove(filepath.Join(m.core.Data, "maps", name+ext)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove map: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *Mapper) snapshotDir(name string) string {
+	return filepath.Join(m.core.Data, "maps", name)
+}
+
+// snapshotStamps lists a project's snapshot stamps, oldest first.
+func (m *Mapper) snapshotStamps(name string) ([]string, error) {
+	entries, err := os.ReadDir(m.snapshotDir(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if stamp, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			out = append(out, stamp)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (m *Mapper) loadSnapshot(name, stamp string) (MapSnapshot, error) {
+	var snap MapSnapshot
+
+	data, err := os.ReadFile(filepath.Join(m.snapshotDir(name), stamp+".json"))
+	if err != nil {
+		return snap, fmt.Errorf("read snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snap, fmt.Errorf("decode snapshot %s: %w", stamp, err)
+	}
+	return snap, nil
+}
+
+// sameSnapshot reports whether two snapshots describe the same tree.
+func sameSnapshot(a, b MapSnapshot) bool {
+	if len(a.Files) != len(b.Files) || len(a.Issues) != len(b.Issues) ||
+		a.Map.TotalDirs != b.Map.TotalDirs || a.Map.MaxDepth != b.Map.MaxDepth {
+		return false
+	}
+	for path, hash := range a.Files {
+		if b.Files[path] != hash {
+			return false
+		}
+	}
+	for i := range a.Issues {
+		if a.Issues[i] != b.Issues[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setDiff returns the items of a that are not in b, sorted.
+func setDiff(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		in[s] = struct{}{}
+	}
+	out := make([]string, 0)
+	for _, s := range a {
+		if _, ok := in[s]; !ok {
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -17,49 +17,102 @@ func NewSuggestEngine(c *core.Core) *SuggestEngine {
 	return &SuggestEngine{core: c}
 }
 
+// suggestion is one structural observation: the sentence Mind weaves into
+// a reply and, when it points at something to act on, the finding it
+// exports as.
+type suggestion struct {
+	text    string
+	rule    string // empty for remarks that aren't findings
+	message string
+	fix     string
+}
+
 // SuggestionsForProject takes a ProjectMap and returns human-readable
 // suggestion strings that Mind can weave into natural language.
 func (s *SuggestEngine) SuggestionsForProject(pm ProjectMap) []string {
 	out := make([]string, 0)
+	for _, sg := range suggestionsFor(pm) {
+		out = append(out, sg.text)
+	}
+	return out
+}
+
+// FindingsForProject returns the structural observations that call for
+// action as findings.
+func (s *SuggestEngine) FindingsForProject(pm ProjectMap) []Finding {
+	out := make([]Finding, 0)
+	for _, sg := range suggestionsFor(pm) {
+		if sg.rule != "" {
+			out = append(out, newFinding(sg.rule, "", 0, sg.message, sg.fix))
+		}
+	}
+	return out
+}
+
+func suggestionsFor(pm ProjectMap) []suggestion {
+	out := make([]suggestion, 0)
 
 	// If there is nothing there, there's not much to say.
 	if pm.TotalFiles == 0 && pm.TotalDirs == 0 {
-		out = append(out, "the project appears to be effectively empty; you may want to confirm the path or initialize a proper structure.")
+		out = append(out, suggestion{
+			text:    "the project appears to be effectively empty; you may want to confirm the path or initialize a proper structure.",
+			rule:    "structure/empty",
+			message: "the project has no files",
+			fix:     "Check the project path, or initialize the project.",
+		})
 		return out
 	}
 
 	// Simple structural observations.
 	if pm.MaxDepth > 8 {
-		out = append(out,
-			fmt.Sprintf("the directory tree is quite deep (max depth %d); you may want to consider flattening or grouping modules more clearly.", pm.MaxDepth))
+		out = append(out, suggestion{
+			text:    fmt.Sprintf("the directory tree is quite deep (max depth %d); you may want to consider flattening or grouping modules more clearly.", pm.MaxDepth),
+			rule:    "structure/deep",
+			message: fmt.Sprintf("the directory tree is %d levels deep", pm.MaxDepth),
+			fix:     "Flatten the tree or group modules more clearly.",
+		})
 	}
 
 	// High-level language hints.
 	if pm.PHPFiles > 0 && pm.GoFiles == 0 && pm.JSFiles == 0 {
-		out = append(out,
-			"this looks primarily like a PHP project; once you are ready, I can focus future analysis on your PHP standards (strict_types, DocBlocks, PSR style).")
+		out = append(out, suggestion{
+			text: "this looks primarily like a PHP project; once you are ready, I can focus future analysis on your PHP standards (strict_types, DocBlocks, PSR style).",
+		})
 	}
 
 	if pm.PHPFiles > 0 && pm.GoFiles > 0 {
-		out = append(out,
-			"I see both PHP and Go in this tree; if these are separate concerns, you may want to isolate them into clearer module boundaries.")
+		out = append(out, suggestion{
+			text:    "I see both PHP and Go in this tree; if these are separate concerns, you may want to isolate them into clearer module boundaries.",
+			rule:    "structure/mixed-languages",
+			message: "PHP and Go share one tree",
+			fix:     "If they are separate concerns, give each its own module boundary.",
+		})
 	}
 
 	if pm.TotalFiles > 0 && pm.OtherFiles > pm.TotalFiles/2 {
-		out = append(out,
-			"there are quite a lot of non-PHP/Go/JS files; you may want to verify which of those are still relevant and which are legacy or stray artifacts.")
+		out = append(out, suggestion{
+			text:    "there are quite a lot of non-PHP/Go/JS files; you may want to verify which of those are still relevant and which are legacy or stray artifacts.",
+			rule:    "structure/stray-files",
+			message: fmt.Sprintf("%d of %d files are not PHP, Go or JavaScript", pm.OtherFiles, pm.TotalFiles),
+			fix:     "Check which of them are still relevant and remove stray artifacts.",
+		})
 	}
 
 	// Basic sanity hint if depth is shallow but file count is high.
 	if pm.MaxDepth <= 3 && pm.TotalFiles > 200 {
-		out = append(out,
-			"there are many files in a relatively shallow structure; introducing a small amount of modular grouping might make navigation cleaner.")
+		out = append(out, suggestion{
+			text:    "there are many files in a relatively shallow structure; introducing a small amount of modular grouping might make navigation cleaner.",
+			rule:    "structure/flat",
+			message: fmt.Sprintf("%d files sit at most %d levels deep", pm.TotalFiles, pm.MaxDepth),
+			fix:     "Group related files into directories.",
+		})
 	}
 
 	// If nothing triggered, still say something gentle.
 	if len(out) == 0 {
-		out = append(out,
-			"nothing alarming stands out structurally from this first pass; when you are ready, we can move into deeper, file-level analysis.")
+		out = append(out, suggestion{
+			text: "nothing alarming stands out structurally from this first pass; when you are ready, we can move into deeper, file-level analysis.",
+		})
 	}
 
 	return out
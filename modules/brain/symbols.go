@@ -0,0 +1,200 @@
+package brain
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SymbolHit is a declaration matching a symbol query.
+type SymbolHit struct {
+	File string `json:"file"`
+	PHPSymbol
+}
+
+// RefHit is a reference matching a symbol query. Loose is true for method
+// calls on objects, where the class can't be known without running the code.
+type RefHit struct {
+	File  string `json:"file"`
+	Loose bool   `json:"loose,omitempty"`
+	PHPRef
+}
+
+// normalizeSymbol turns "\App\Foo", "render_view()" or "Foo::bar()" into the
+// lower-cased form the matchers compare against.
+func normalizeSymbol(q string) string {
+	q = strings.TrimSpace(q)
+	q = strings.TrimSuffix(q, "()")
+	q = strings.TrimPrefix(q, `\`)
+	return strings.ToLower(q)
+}
+
+// lastSegment drops a namespace: "app\foo" -> "foo".
+func lastSegment(name string) string {
+	return name[strings.LastIndex(name, `\`)+1:]
+}
+
+// FindSymbol returns the declarations matching q, which may be a short name
+// ("UserController"), a fully qualified one ("App\Http\UserController") or a
+// member ("UserController::index").
+func (ix *ProjectIndex) FindSymbol(q string) []SymbolHit {
+	q = normalizeSymbol(q)
+	out := make([]SymbolHit, 0)
+	if q == "" {
+		return out
+	}
+
+	for _, e := range ix.Lang("php") {
+		if e.PHP == nil {
+			continue
+		}
+		for _, s := range e.PHP.Symbols {
+			if symbolMatches(s, q) {
+				out = append(out, SymbolHit{File: e.Path, PHPSymbol: s})
+			}
+		}
+	}
+	return out
+}
+
+func symbolMatches(s PHPSymbol, q string) bool {
+	fqn := strings.ToLower(s.FQN)
+	if fqn == q || strings.ToLower(s.Name) == q {
+		return true
+	}
+	if s.Parent != "" && strings.Contains(q, "::") {
+		// "UserController::index" for "App\Http\UserController::index".
+		return strings.ToLower(lastSegment(s.Parent))+"::"+strings.ToLower(s.Name) == q
+	}
+	return !strings.Contains(q, `\`) && lastSegment(fqn) == q
+}
+
+// FindRefs returns the references to q across the project: calls,
+// instantiations, static accesses, inheritance, attributes and imports.
+// Uses of a class include static calls on it.
+func (ix *ProjectIndex) FindRefs(q string) []RefHit {
+	q = normalizeSymbol(q)
+	out := make([]RefHit, 0)
+	if q == "" {
+		return out
+	}
+
+	class, member, isMember := strings.Cut(q, "::")
+
+	for _, e := range ix.Lang("php") {
+		if e.PHP == nil {
+			continue
+		}
+		for _, r := range e.PHP.Refs {
+			if r.Kind == "method" && !strings.Contains(r.FQN, "::") {
+				// $x->name(): only the method name is known.
+				name := strings.ToLower(r.Name)
+				if (isMember && name == member) || (!isMember && name == q) {
+					out = append(out, RefHit{File: e.Path, Loose: true, PHPRef: r})
+				}
+				continue
+			}
+
+			fqn := strings.ToLower(r.FQN)
+			base, refMember, _ := strings.Cut(fqn, "::")
+			switch {
+			case isMember:
+				if refMember == member && nameMatches(base, class) {
+					out = append(out, RefHit{File: e.Path, PHPRef: r})
+				}
+			case nameMatches(fqn, q) || nameMatches(base, q) || refMember == q:
+				out = append(out, RefHit{File: e.Path, PHPRef: r})
+			case r.Kind == "call" && !strings.Contains(r.Name, `\`) && strings.ToLower(r.Name) == lastSegment(q):
+				// Unqualified calls fall back to the global function.
+				out = append(out, RefHit{File: e.Path, PHPRef: r})
+			}
+		}
+	}
+	return out
+}
+
+// nameMatches compares a resolved name with a query that may or may not be
+// qualified.
+func nameMatches(fqn, q string) bool {
+	if fqn == q {
+		return true
+	}
+	return !strings.Contains(q, `\`) && lastSegment(fqn) == q
+}
+
+// Outline returns a PHP file's entry with its symbols in source order.
+func (ix *ProjectIndex) Outline(rel string) (*FileEntry, bool) {
+	e, ok := ix.Files[filepath.ToSlash(filepath.Clean(rel))]
+	if !ok || e.PHP == nil {
+		return nil, false
+	}
+	return e, true
+}
+
+// FindFile resolves a file name as a user might type it: a project-relative
+// path, or a bare name matched against the end of the indexed paths. Several
+// candidates come back when a bare name is ambiguous.
+func (ix *ProjectIndex) FindFile(name string) []string {
+	name = filepath.ToSlash(filepath.Clean(strings.TrimSpace(name)))
+	if ix.Has(name) {
+		return []string{name}
+	}
+
+	out := make([]string, 0)
+	for path := range ix.Files {
+		if strings.HasSuffix(path, "/"+name) {
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SymbolAt works out which symbol the name at a 1-based line and 0-based
+// byte column of PHP source refers to, in the form FindSymbol takes. Names
+// are resolved through the file's namespace and imports where the scan
+// recorded a reference; otherwise the name is returned as written. It
+// returns "" when the position isn't on a name.
+func SymbolAt(data []byte, relPath string, line, col int) string {
+	name := ""
+	l, c := 1, 0
+	for _, t := range LexPHP(data) {
+		if t.Kind == PHPName && l == line && col >= c && col <= c+len(t.Text) {
+			name = t.Text
+			break
+		}
+		if n := strings.Count(t.Text, "\n"); n > 0 {
+			l += n
+			c = len(t.Text) - strings.LastIndex(t.Text, "\n") - 1
+		} else {
+			c += len(t.Text)
+		}
+		if l > line {
+			break
+		}
+	}
+	if name == "" {
+		return ""
+	}
+
+	want := strings.ToLower(lastSegment(name))
+	scan := scanPHP(data, relPath)
+	for _, r := range scan.Refs {
+		if r.Line != line {
+			continue
+		}
+		base, member, isMember := strings.Cut(r.Name, "::")
+		fqnBase, _, _ := strings.Cut(r.FQN, "::")
+		switch {
+		case isMember && strings.ToLower(member) == want:
+			return r.FQN
+		case strings.ToLower(lastSegment(base)) == want:
+			if r.Kind == "method" {
+				// $x->name() or $this->name(): FQN is as precise as it gets.
+				return r.FQN
+			}
+			return fqnBase
+		}
+	}
+	return name
+}
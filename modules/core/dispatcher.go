@@ -14,9 +14,37 @@ const (
 	CommandRegisterProject
 	CommandMapProject
 	CommandSuggestProject
-	CommandAnalyze // Arg: project name or empty for default
+	CommandAnalyze // Arg: "<project>[ as sarif|json]" or empty for default
 	CommandPatch   // Arg: file name or empty for default
-	CommandApply   // Arg: file name or empty for default
+	CommandApply   // Arg: file name, "all", or empty for default
+	CommandFix     // Arg: "<project> <fixer>"
+	CommandFixers
+	CommandListProjects      // Arg: "", "tagged <tag>" or "in <group>"
+	CommandShowProject       // Arg: project name
+	CommandUnregisterProject // Arg: project name
+	CommandRenameProject     // Arg: "<name> to <new name>"
+	CommandAliasProject      // Arg: "<name> as <alias>"
+	CommandUnaliasProject    // Arg: "<name> as <alias>"
+	CommandTagProject        // Arg: "<name> with <tag>[, <tag>...]"
+	CommandUntagProject      // Arg: "<name> with <tag>[, <tag>...]"
+	CommandGroupProject      // Arg: "<name> as <group>" (empty group clears it)
+	CommandRelocateProject   // Arg: "<name> to <path>"
+	CommandSetProjectMeta    // Arg: "<name> <field> to <value>"
+	CommandDiscover          // Arg: "<dir> [depth N]"
+	CommandRegisterFound     // Arg: "all" or candidate numbers, e.g. "1, 3"
+	CommandWatch             // Arg: "<dir> [depth N]"
+	CommandUnwatch           // Arg: directory, or empty for all
+	CommandChanges           // Arg: project name or empty for the last one
+	CommandDiffMap           // Arg: project name or empty for the last one
+	CommandWhereIs           // Arg: "<symbol>[ in <project>]"
+	CommandWhoUses           // Arg: "<symbol>[ in <project>]"
+	CommandOutline           // Arg: "<file>[ in <project>]"
+	CommandAutoload          // Arg: project name or empty for the last one
+	CommandGraph             // Arg: "<project>[ as dot|mermaid]" or empty for the last one
+	CommandRoutes            // Arg: project name or empty for the last one
+	CommandArchitecture      // Arg: project name or empty for the last one
+	CommandRepairRequires    // Arg: file name or empty for the last patched file
+	CommandStubRequires      // Arg: file name or empty for the last patched file
 	CommandFeedbackApproved
 	CommandFeedbackRejected
 )
@@ -46,6 +74,8 @@ func DispatchCommand(message string) Command {
 		return Command{Kind: CommandRouter}
 	case "analyze router":
 		return Command{Kind: CommandAnalyzeRouter}
+	case "fixers", "list fixers":
+		return Command{Kind: CommandFixers}
 	}
 
 	// Law status variants.
@@ -71,6 +101,160 @@ func DispatchCommand(message string) Command {
 		return Command{Kind: CommandRegisterProject, Arg: arg}
 	}
 
+	// Workspace discovery.
+	if strings.HasPrefix(lower, "discover ") {
+		arg := strings.TrimSpace(raw[len("discover "):])
+		return Command{Kind: CommandDiscover, Arg: arg}
+	}
+	if strings.HasPrefix(lower, "register ") && isSelection(lower[len("register "):]) {
+		arg := strings.TrimSpace(raw[len("register "):])
+		return Command{Kind: CommandRegisterFound, Arg: arg}
+	}
+	if strings.HasPrefix(lower, "watch ") {
+		arg := strings.TrimSpace(raw[len("watch "):])
+		return Command{Kind: CommandWatch, Arg: arg}
+	}
+	if lower == "unwatch" || lower == "stop watching" {
+		return Command{Kind: CommandUnwatch}
+	}
+	if strings.HasPrefix(lower, "unwatch ") {
+		arg := strings.TrimSpace(raw[len("unwatch "):])
+		return Command{Kind: CommandUnwatch, Arg: arg}
+	}
+
+	// Project registry lifecycle.
+	if lower == "projects" || lower == "list projects" {
+		return Command{Kind: CommandListProjects}
+	}
+	if strings.HasPrefix(lower, "projects ") {
+		arg := strings.TrimSpace(raw[len("projects "):])
+		return Command{Kind: CommandListProjects, Arg: arg}
+	}
+	for _, p := range []struct {
+		prefix string
+		kind   CommandKind
+	}{
+		{"show project ", CommandShowProject},
+		{"describe project ", CommandShowProject},
+		{"unregister project ", CommandUnregisterProject},
+		{"remove project ", CommandUnregisterProject},
+		{"forget project ", CommandUnregisterProject},
+		{"rename project ", CommandRenameProject},
+		{"alias project ", CommandAliasProject},
+		{"unalias project ", CommandUnaliasProject},
+		{"tag project ", CommandTagProject},
+		{"untag project ", CommandUntagProject},
+		{"group project ", CommandGroupProject},
+		{"relocate project ", CommandRelocateProject},
+		{"move project ", CommandRelocateProject},
+		{"set project ", CommandSetProjectMeta},
+	} {
+		if strings.HasPrefix(lower, p.prefix) {
+			arg := strings.TrimSpace(raw[len(p.prefix):])
+			return Command{Kind: p.kind, Arg: arg}
+		}
+	}
+	if strings.HasPrefix(lower, "ungroup project ") {
+		arg := strings.TrimSpace(raw[len("ungroup project "):])
+		return Command{Kind: CommandGroupProject, Arg: arg + " as "}
+	}
+
+	// Symbol lookups.
+	for _, p := range []struct {
+		prefix string
+		kind   CommandKind
+	}{
+		{"where is ", CommandWhereIs},
+		{"where’s ", CommandWhereIs},
+		{"where's ", CommandWhereIs},
+		{"find symbol ", CommandWhereIs},
+		{"who uses ", CommandWhoUses},
+		{"who calls ", CommandWhoUses},
+		{"usages of ", CommandWhoUses},
+		{"outline ", CommandOutline},
+	} {
+		if strings.HasPrefix(lower, p.prefix) {
+			arg := strings.TrimSuffix(strings.TrimSpace(raw[len(p.prefix):]), "?")
+			if p.kind == CommandWhereIs && strings.HasSuffix(strings.ToLower(arg), " defined") {
+				arg = strings.TrimSpace(arg[:len(arg)-len(" defined")])
+			}
+			return Command{Kind: p.kind, Arg: arg}
+		}
+	}
+
+	// Composer autoload validation.
+	switch lower {
+	case "check autoload", "autoload", "check composer":
+		return Command{Kind: CommandAutoload}
+	}
+	for _, prefix := range []string{"check autoload ", "autoload ", "check composer "} {
+		if strings.HasPrefix(lower, prefix) {
+			arg := strings.TrimSpace(raw[len(prefix):])
+			arg = strings.TrimSpace(strings.TrimPrefix(arg, "for "))
+			return Command{Kind: CommandAutoload, Arg: arg}
+		}
+	}
+
+	// Include graph.
+	if lower == "graph" || lower == "include graph" {
+		return Command{Kind: CommandGraph}
+	}
+	for _, prefix := range []string{"graph ", "include graph "} {
+		if strings.HasPrefix(lower, prefix) {
+			arg := strings.TrimSpace(raw[len(prefix):])
+			arg = strings.TrimSpace(strings.TrimPrefix(arg, "of "))
+			return Command{Kind: CommandGraph, Arg: arg}
+		}
+	}
+
+	// Route table.
+	switch lower {
+	case "routes", "route table", "list routes", "show routes":
+		return Command{Kind: CommandRoutes}
+	}
+	for _, prefix := range []string{"routes ", "route table ", "list routes ", "show routes "} {
+		if strings.HasPrefix(lower, prefix) {
+			arg := strings.TrimSpace(raw[len(prefix):])
+			for _, filler := range []string{"of ", "for ", "in "} {
+				arg = strings.TrimSpace(strings.TrimPrefix(arg, filler))
+			}
+			return Command{Kind: CommandRoutes, Arg: arg}
+		}
+	}
+
+	// MVC layer check.
+	switch lower {
+	case "architecture", "check architecture", "layers", "check layers":
+		return Command{Kind: CommandArchitecture}
+	}
+	for _, prefix := range []string{"architecture ", "check architecture ", "layers ", "check layers "} {
+		if strings.HasPrefix(lower, prefix) {
+			arg := strings.TrimSpace(raw[len(prefix):])
+			for _, filler := range []string{"of ", "for ", "in "} {
+				arg = strings.TrimSpace(strings.TrimPrefix(arg, filler))
+			}
+			return Command{Kind: CommandArchitecture, Arg: arg}
+		}
+	}
+
+	// Changes since the last map.
+	if lower == "changes" || lower == "what changed" || lower == "what changed?" {
+		return Command{Kind: CommandChanges}
+	}
+	for _, prefix := range []string{"changes in ", "changes ", "what changed in "} {
+		if strings.HasPrefix(lower, prefix) {
+			arg := strings.TrimSuffix(strings.TrimSpace(raw[len(prefix):]), "?")
+			return Command{Kind: CommandChanges, Arg: arg}
+		}
+	}
+	if strings.HasPrefix(lower, "diff map ") {
+		arg := strings.TrimSpace(raw[len("diff map "):])
+		return Command{Kind: CommandDiffMap, Arg: arg}
+	}
+	if lower == "diff map" {
+		return Command{Kind: CommandDiffMap}
+	}
+
 	// Map project.
 	if strings.HasPrefix(lower, "map project ") {
 		arg := strings.TrimSpace(raw[len("map project "):])
@@ -113,6 +297,28 @@ func DispatchCommand(message string) Command {
 		return Command{Kind: CommandPatch, Arg: ""}
 	}
 
+	// Unresolved require repairs.
+	switch lower {
+	case "fix paths", "fix those paths", "fix requires", "repair requires":
+		return Command{Kind: CommandRepairRequires}
+	case "generate stubs", "stubs", "stub requires":
+		return Command{Kind: CommandStubRequires}
+	}
+	if strings.HasPrefix(lower, "repair ") {
+		arg := strings.TrimSpace(raw[len("repair "):])
+		return Command{Kind: CommandRepairRequires, Arg: arg}
+	}
+	if strings.HasPrefix(lower, "stub ") {
+		arg := strings.TrimSpace(raw[len("stub "):])
+		return Command{Kind: CommandStubRequires, Arg: arg}
+	}
+
+	// Fix a whole project with one fixer.
+	if strings.HasPrefix(lower, "fix ") {
+		arg := strings.TrimSpace(raw[len("fix "):])
+		return Command{Kind: CommandFix, Arg: arg}
+	}
+
 	// Apply patch.
 	if strings.HasPrefix(lower, "apply ") {
 		arg := strings.TrimSpace(raw[len("apply "):])
@@ -124,3 +330,24 @@ func DispatchCommand(message string) Command {
 
 	return Command{Kind: CommandUnknown}
 }
+
+// isSelection reports whether s is "all" or a list of numbers such as
+// "1, 3 and 4".
+func isSelection(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "all" {
+		return true
+	}
+	s = strings.ReplaceAll(s, " and ", ",")
+	digits := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case r == ',' || r == ' ':
+		default:
+			return false
+		}
+	}
+	return digits
+}
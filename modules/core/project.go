@@ -2,28 +2,56 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	// ErrProjectNotFound is returned when no project matches a name or alias.
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrNameTaken is returned when a name or alias is already in use.
+	ErrNameTaken = errors.New("name already in use")
+	// ErrInvalidName is returned for names that can't double as a file name.
+	ErrInvalidName = errors.New("invalid project name")
+)
+
 // Project represents a single registered project RictusD is allowed to inspect.
 type Project struct {
 	Name         string   `json:"name"`
 	Path         string   `json:"path"`
-	Type         string   `json:"type"`          // e.g., "php", "mixed", "unknown"
-	Languages    []string `json:"languages"`     // optional, can be filled in later
+	Type         string   `json:"type"`      // e.g., "php", "mixed", "unknown"
+	Languages    []string `json:"languages"` // detected on register and on every map
+	Frameworks   []string `json:"frameworks,omitempty"`
+	Tooling      []string `json:"tooling,omitempty"`
 	RegisteredAt string   `json:"registered_at"` // RFC3339
+
+	Aliases       []string `json:"aliases,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	Group         string   `json:"group,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Owner         string   `json:"owner,omitempty"`
+	DefaultBranch string   `json:"default_branch,omitempty"`
+	Standards     string   `json:"standards,omitempty"` // standards profile name
 }
 
+// ProjectMetaKeys are the free-form metadata fields SetMeta accepts.
+var ProjectMetaKeys = []string{"description", "owner", "default_branch", "standards"}
+
 // ProjectRegistry manages the list of registered projects on disk.
 // It is intentionally decoupled from any internal Core type and only needs
-// the daemon's data directory.
+// the daemon's data directory. It is safe for concurrent use.
 type ProjectRegistry struct {
 	dataDir  string
 	filePath string
+
+	mu       sync.RWMutex
 	projects []Project
 }
 
@@ -39,8 +67,9 @@ func NewProjectRegistry(dataDir string) *ProjectRegistry {
 	}
 
 	if err := r.load(); err != nil {
-		// We do not have a logger here on purpose; keep it simple and silent.
-		fmt.Printf("project registry: load failed: %v\n", err)
+		// We do not have a logger here on purpose. Stderr, not stdout: the
+		// lsp and mcp modes speak JSON-RPC on stdout.
+		fmt.Fprintf(os.Stderr, "project registry: load failed: %v\n", err)
 	}
 
 	return r
@@ -93,7 +122,8 @@ func (r *ProjectRegistry) save() error {
 // Register registers a new project at the given path.
 // It verifies the path exists and is a directory, creates a simple project
 // record, and persists it to disk. If a project with the same path already
-// exists, it simply returns the existing record.
+// exists, it simply returns the existing record. When the directory name is
+// already taken, the parent directory is folded into the name ("work-app").
 func (r *ProjectRegistry) Register(path string) (Project, error) {
 	if path == "" {
 		return Project{}, fmt.Errorf("project path is empty")
@@ -113,18 +143,18 @@ func (r *ProjectRegistry) Register(path string) (Project, error) {
 		return Project{}, fmt.Errorf("path is not a directory: %s", abs)
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	// Check if already registered.
 	for _, p := range r.projects {
 		if p.Path == abs {
-			fmt.Printf("project registry: path already registered: %s\n", abs)
+			fmt.Fprintf(os.Stderr, "project registry: path already registered: %s\n", abs)
 			return p, nil
 		}
 	}
 
-	name := filepath.Base(abs)
-	if name == "" || name == "/" {
-		name = abs
-	}
+	name := r.uniqueName(abs)
 
 	proj := Project{
 		Name:         name,
@@ -140,29 +170,408 @@ func (r *ProjectRegistry) Register(path string) (Project, error) {
 		return Project{}, err
 	}
 
-	fmt.Printf("project registry: registered project %q at %s\n", proj.Name, proj.Path)
+	fmt.Fprintf(os.Stderr, "project registry: registered project %q at %s\n", proj.Name, proj.Path)
 	return proj, nil
 }
 
 // List returns a copy of all registered projects.
 func (r *ProjectRegistry) List() []Project {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	out := make([]Project, len(r.projects))
-	copy(out, r.projects)
+	for i, p := range r.projects {
+		out[i] = p.clone()
+	}
 	return out
 }
 
-// FindByName returns the first project whose name matches (case-insensitive).
+// ListByTag returns the projects carrying a tag (case-insensitive).
+func (r *ProjectRegistry) ListByTag(tag string) []Project {
+	out := make([]Project, 0)
+	for _, p := range r.List() {
+		if containsFold(p.Tags, tag) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ListByGroup returns the projects in a group (case-insensitive).
+func (r *ProjectRegistry) ListByGroup(group string) []Project {
+	out := make([]Project, 0)
+	for _, p := range r.List() {
+		if p.Group != "" && strings.EqualFold(p.Group, group) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// FindByName returns the first project whose name or alias matches
+// (case-insensitive).
 func (r *ProjectRegistry) FindByName(name string) (Project, bool) {
-	if name == "" {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	idx := r.indexOf(name)
+	if idx == -1 {
 		return Project{}, false
 	}
+	return r.projects[idx].clone(), true
+}
 
-	lower := strings.ToLower(name)
-	for _, p := range r.projects {
-		if strings.ToLower(p.Name) == lower {
-			return p, true
+// FindByPath returns the project whose root contains the given path.
+// The deepest matching root wins when projects are nested.
+func (r *ProjectRegistry) FindByPath(path string) (Project, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Project{}, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	best := -1
+	for i, p := range r.projects {
+		if abs != p.Path && !strings.HasPrefix(abs, p.Path+string(filepath.Separator)) {
+			continue
+		}
+		if best == -1 || len(p.Path) > len(r.projects[best].Path) {
+			best = i
+		}
+	}
+	if best == -1 {
+		return Project{}, false
+	}
+	return r.projects[best].clone(), true
+}
+
+// Unregister removes a project from the registry. Files on disk are untouched.
+func (r *ProjectRegistry) Unregister(name string) (Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := r.indexOf(name)
+	if idx == -1 {
+		return Project{}, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+
+	removed := r.projects[idx]
+	r.projects = append(r.projects[:idx], r.projects[idx+1:]...)
+
+	if err := r.save(); err != nil {
+		return Project{}, err
+	}
+	return removed, nil
+}
+
+// Rename gives a project a new primary name. The old name is not kept as an
+// alias; add one explicitly if it should keep working.
+func (r *ProjectRegistry) Rename(name, newName string) (Project, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return Project{}, fmt.Errorf("new name is empty")
+	}
+	if err := ValidateName(newName); err != nil {
+		return Project{}, err
+	}
+
+	return r.update(name, func(p *Project) error {
+		if other := r.indexOf(newName); other != -1 && r.projects[other].Path != p.Path {
+			return fmt.Errorf("%w: %s", ErrNameTaken, newName)
 		}
+		p.Name = newName
+		p.Aliases = removeFold(p.Aliases, newName)
+		return nil
+	})
+}
+
+// AddAlias lets a project be found under another name.
+func (r *ProjectRegistry) AddAlias(name, alias string) (Project, error) {
+	alias = strings.TrimSpace(alias)
+	if alias == "" {
+		return Project{}, fmt.Errorf("alias is empty")
+	}
+	if err := ValidateName(alias); err != nil {
+		return Project{}, err
 	}
 
-	return Project{}, false
+	return r.update(name, func(p *Project) error {
+		if other := r.indexOf(alias); other != -1 {
+			if r.projects[other].Path == p.Path {
+				return nil
+			}
+			return fmt.Errorf("%w: %s", ErrNameTaken, alias)
+		}
+		p.Aliases = append(p.Aliases, alias)
+		return nil
+	})
+}
+
+// RemoveAlias drops an alias from a project.
+func (r *ProjectRegistry) RemoveAlias(name, alias string) (Project, error) {
+	return r.update(name, func(p *Project) error {
+		p.Aliases = removeFold(p.Aliases, alias)
+		return nil
+	})
+}
+
+// AddTags adds tags to a project, ignoring ones it already has.
+func (r *ProjectRegistry) AddTags(name string, tags ...string) (Project, error) {
+	return r.update(name, func(p *Project) error {
+		for _, t := range tags {
+			t = strings.TrimSpace(t)
+			if t != "" && !containsFold(p.Tags, t) {
+				p.Tags = append(p.Tags, t)
+			}
+		}
+		sort.Strings(p.Tags)
+		return nil
+	})
+}
+
+// RemoveTags removes tags from a project.
+func (r *ProjectRegistry) RemoveTags(name string, tags ...string) (Project, error) {
+	return r.update(name, func(p *Project) error {
+		for _, t := range tags {
+			p.Tags = removeFold(p.Tags, strings.TrimSpace(t))
+		}
+		return nil
+	})
+}
+
+// SetGroup puts a project in a group; an empty group removes it from one.
+func (r *ProjectRegistry) SetGroup(name, group string) (Project, error) {
+	return r.update(name, func(p *Project) error {
+		p.Group = strings.TrimSpace(group)
+		return nil
+	})
+}
+
+// Relocate points a project at a new directory after it has moved on disk.
+func (r *ProjectRegistry) Relocate(name, newPath string) (Project, error) {
+	abs, err := filepath.Abs(newPath)
+	if err != nil {
+		return Project{}, fmt.Errorf("resolve path: %w", err)
+	}
+
+	st, err := os.Stat(abs)
+	if err != nil {
+		return Project{}, fmt.Errorf("stat path: %w", err)
+	}
+	if !st.IsDir() {
+		return Project{}, fmt.Errorf("path is not a directory: %s", abs)
+	}
+
+	return r.update(name, func(p *Project) error {
+		for _, other := range r.projects {
+			if other.Path == abs && other.Name != p.Name {
+				return fmt.Errorf("%s is already registered as %q", abs, other.Name)
+			}
+		}
+		p.Path = abs
+		return nil
+	})
+}
+
+// SetMeta sets one of the ProjectMetaKeys. An empty value clears it.
+func (r *ProjectRegistry) SetMeta(name, key, value string) (Project, error) {
+	key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), " ", "_")
+	value = strings.TrimSpace(value)
+
+	return r.update(name, func(p *Project) error {
+		switch key {
+		case "description":
+			p.Description = value
+		case "owner":
+			p.Owner = value
+		case "default_branch", "branch":
+			p.DefaultBranch = value
+		case "standards", "standards_profile", "profile":
+			p.Standards = value
+		default:
+			return fmt.Errorf("unknown project field %q (expected one of %s)", key, strings.Join(ProjectMetaKeys, ", "))
+		}
+		return nil
+	})
+}
+
+// Restore puts back a copy of a project taken earlier, e.g. to undo an edit
+// that failed halfway. The project is matched by its name.
+func (r *ProjectRegistry) Restore(snapshot Project) (Project, error) {
+	return r.update(snapshot.Name, func(p *Project) error {
+		*p = snapshot.clone()
+		return nil
+	})
+}
+
+// SetStack records the detected project type, languages, frameworks and
+// tooling. It only writes to disk when something changed.
+func (r *ProjectRegistry) SetStack(name, typ string, languages, frameworks, tooling []string) (Project, error) {
+	if cur, ok := r.FindByName(name); ok &&
+		cur.Type == typ &&
+		equalStrings(cur.Languages, languages) &&
+		equalStrings(cur.Frameworks, frameworks) &&
+		equalStrings(cur.Tooling, tooling) {
+		return cur, nil
+	}
+
+	return r.update(name, func(p *Project) error {
+		p.Type = typ
+		p.Languages = cloneStrings(languages)
+		if p.Languages == nil {
+			p.Languages = []string{}
+		}
+		p.Frameworks = cloneStrings(frameworks)
+		p.Tooling = cloneStrings(tooling)
+		return nil
+	})
+}
+
+// HasLanguage reports whether the project's detected languages include lang.
+// A project that was never detected ("unknown") is treated as possibly
+// containing anything.
+func (p Project) HasLanguage(lang string) bool {
+	if p.Type == "" || p.Type == "unknown" {
+		return true
+	}
+	return containsFold(p.Languages, lang)
+}
+
+// HasFramework reports whether a framework was detected for the project.
+func (p Project) HasFramework(fw string) bool {
+	return containsFold(p.Frameworks, fw)
+}
+
+// update applies fn to the named project under the write lock and persists
+// the result. fn may inspect r.projects but must not modify other entries.
+func (r *ProjectRegistry) update(name string, fn func(p *Project) error) (Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := r.indexOf(name)
+	if idx == -1 {
+		return Project{}, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+
+	p := r.projects[idx].clone()
+	if err := fn(&p); err != nil {
+		return Project{}, err
+	}
+
+	prev := r.projects[idx]
+	r.projects[idx] = p
+	if err := r.save(); err != nil {
+		r.projects[idx] = prev
+		return Project{}, err
+	}
+
+	return p.clone(), nil
+}
+
+// indexOf finds a project by name or alias; callers hold r.mu.
+func (r *ProjectRegistry) indexOf(name string) int {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return -1
+	}
+
+	for i, p := range r.projects {
+		if strings.EqualFold(p.Name, name) {
+			return i
+		}
+	}
+	for i, p := range r.projects {
+		if containsFold(p.Aliases, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ValidateName checks that a project name or alias is a single path
+// element. Names become file names under data/index and data/maps, so a
+// name like "../projects" would write outside them.
+func ValidateName(name string) error {
+	if name == "" || name == "." || strings.Contains(name, "..") || strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// uniqueName derives a project name from its path that does not collide with
+// an existing name or alias; callers hold r.mu.
+func (r *ProjectRegistry) uniqueName(abs string) string {
+	name := filepath.Base(abs)
+	if ValidateName(name) != nil {
+		// The filesystem root, or a directory named in a way no file
+		// name can carry.
+		name = "project"
+	}
+	if r.indexOf(name) == -1 {
+		return name
+	}
+
+	if parent := filepath.Base(filepath.Dir(abs)); parent != "" && parent != "/" && parent != "." {
+		if candidate := parent + "-" + name; ValidateName(candidate) == nil && r.indexOf(candidate) == -1 {
+			return candidate
+		}
+	}
+
+	for i := 2; ; i++ {
+		if candidate := name + "-" + strconv.Itoa(i); r.indexOf(candidate) == -1 {
+			return candidate
+		}
+	}
+}
+
+// clone returns a copy that does not share slices with the registry.
+func (p Project) clone() Project {
+	p.Languages = cloneStrings(p.Languages)
+	p.Aliases = cloneStrings(p.Aliases)
+	p.Tags = cloneStrings(p.Tags)
+	p.Frameworks = cloneStrings(p.Frameworks)
+	p.Tooling = cloneStrings(p.Tooling)
+	return p
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	out := make([]string, len(s))
+	copy(out, s)
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFold(list []string, s string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if !strings.EqualFold(v, s) {
+			out = append(out, v)
+		}
+	}
+	return out
 }
@@ -0,0 +1,204 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProjectConfigFiles are the project-local config locations, in the order
+// they are tried. The first one found wins.
+var ProjectConfigFiles = []string{".rictus.json", filepath.Join(".rictus", "config.json")}
+
+// ProjectConfig is the optional per-project configuration kept in the
+// project itself, e.g.:
+//
+//	{
+//	  "router": "public/index.php",
+//	  "exclude": ["storage/**", "*.min.php"],
+//	  "standards": {"strict_types": true, "docblock": false},
+//	  "php_version": "8.1",
+//	  "include_paths": ["lib", "src"],
+//	  "lawbook": ".rictus/lawbook.md",
+//	  "snapshots": 50,
+//	  "architecture": {"roles": {"src/Domain/**": "model"}, "forbid": {"view": ["db", "header"]}}
+//	}
+type ProjectConfig struct {
+	Router       string             `json:"router"`        // router/entry file, project-relative
+	Exclude      []string           `json:"exclude"`       // glob patterns, project-relative
+	Standards    StandardsConfig    `json:"standards"`     // what the PHP checks require
+	PHPVersion   string             `json:"php_version"`   // e.g. "8.1"
+	IncludePaths []string           `json:"include_paths"` // extra roots for require resolution
+	Lawbook      string             `json:"lawbook"`       // addendum text, or a project-relative .md file
+	Snapshots    int                `json:"snapshots"`     // map snapshots to keep, 0 for the default
+	Architecture ArchitectureConfig `json:"architecture"`  // MVC layer rules
+
+	Source string `json:"-"` // the file this was loaded from, empty for defaults
+}
+
+// StandardsConfig switches individual PHP standards on or off. Unset fields
+// keep the default, which is required.
+type StandardsConfig struct {
+	StrictTypes *bool `json:"strict_types"`
+	Docblock    *bool `json:"docblock"`
+}
+
+// ArchitectureConfig tunes the MVC layer checks. Roles pins files matching
+// a glob to a role (router, controller, view, config, model), overriding the
+// guess. Forbid lists, per role, the checks its files must not trip; a role
+// listed here replaces its default list, and an empty list switches it off.
+type ArchitectureConfig struct {
+	Roles  map[string]string   `json:"roles"`
+	Forbid map[string][]string `json:"forbid"`
+}
+
+// RoleFor returns the role pinned to a project-relative path, if any. When
+// several globs match, the longest wins.
+func (a ArchitectureConfig) RoleFor(rel string) (string, bool) {
+	rel = filepath.ToSlash(rel)
+	best, role := -1, ""
+	for pat, r := range a.Roles {
+		if globMatch(pat, rel) && len(pat) > best {
+			best, role = len(pat), strings.ToLower(strings.TrimSpace(r))
+		}
+	}
+	return role, best >= 0
+}
+
+// LoadProjectConfig reads the project's config file. A project without one
+// gets the zero config, which means "defaults everywhere". A config that
+// exists but cannot be parsed is reported as an error alongside the defaults,
+// so callers can warn and carry on.
+func LoadProjectConfig(root string) (ProjectConfig, error) {
+	for _, name := range ProjectConfigFiles {
+		full := filepath.Join(root, name)
+
+		data, err := os.ReadFile(full)
+		if err != nil {
+			continue
+		}
+
+		var cfg ProjectConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return ProjectConfig{}, fmt.Errorf("parse %s: %w", name, err)
+		}
+		cfg.Source = name
+		return cfg, nil
+	}
+
+	return ProjectConfig{}, nil
+}
+
+// RequireStrict reports whether PHP files must declare strict_types. It is
+// never required for PHP versions that predate strict_types (before 7.0).
+func (c ProjectConfig) RequireStrict() bool {
+	if !c.SupportsStrictTypes() {
+		return false
+	}
+	return c.Standards.StrictTypes == nil || *c.Standards.StrictTypes
+}
+
+// RequireDocblock reports whether PHP files must carry a file docblock.
+func (c ProjectConfig) RequireDocblock() bool {
+	return c.Standards.Docblock == nil || *c.Standards.Docblock
+}
+
+// SupportsStrictTypes reports whether the configured PHP version understands
+// declare(strict_types=1). An unset or unreadable version is assumed modern.
+func (c ProjectConfig) SupportsStrictTypes() bool {
+	major, _, _ := strings.Cut(strings.TrimSpace(c.PHPVersion), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return true
+	}
+	return n >= 7
+}
+
+// Excluded reports whether a project-relative path matches one of the
+// exclude globs. A pattern matches the whole path, any single path segment
+// (so "cache" excludes every cache directory), or with a trailing "/**" the
+// directory and everything below it.
+func (c ProjectConfig) Excluded(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if rel == "." || rel == "" {
+		return false
+	}
+
+	for _, pat := range c.Exclude {
+		if globMatch(pat, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches a slash-separated path against one config glob, the
+// way Excluded describes.
+func globMatch(pat, rel string) bool {
+	pat = strings.Trim(filepath.ToSlash(strings.TrimSpace(pat)), "/")
+	if pat == "" {
+		return false
+	}
+
+	if dir, ok := strings.CutSuffix(pat, "/**"); ok {
+		return rel == dir || strings.HasPrefix(rel, dir+"/")
+	}
+
+	if ok, _ := path.Match(pat, rel); ok {
+		return true
+	}
+	if !strings.Contains(pat, "/") {
+		for _, seg := range strings.Split(rel, "/") {
+			if ok, _ := path.Match(pat, seg); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LawbookAddendum returns the project's addition to the lawbook. A value
+// ending in ".md" is read from the project; anything else is used as is.
+func (c ProjectConfig) LawbookAddendum(root string) (string, error) {
+	text := strings.TrimSpace(c.Lawbook)
+	if !strings.HasSuffix(strings.ToLower(text), ".md") {
+		return text, nil
+	}
+
+	full, err := ResolveProjectPath(root, text)
+	if err != nil {
+		return "", fmt.Errorf("lawbook addendum: %w", err)
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", fmt.Errorf("read lawbook addendum: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ResolveProjectPath turns a project-relative path into the file it names,
+// following symlinks. Absolute paths, paths that climb out with "..", and
+// links that lead outside root are refused.
+func ResolveProjectPath(root, rel string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(rel, "./")))
+	if rel == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q is not a path inside the project", rel)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	full, err := filepath.EvalSymlinks(filepath.Join(root, clean))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(full, realRoot+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s points outside the project", filepath.ToSlash(clean))
+	}
+	return full, nil
+}
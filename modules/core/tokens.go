@@ -0,0 +1,65 @@
+package core
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// APITokensFile holds the bearer tokens the daemon's authenticated
+// endpoints accept, under the conf directory, e.g.:
+//
+//	{
+//	  "tokens": [
+//	    {"name": "editor", "token": "a-long-random-string"}
+//	  ]
+//	}
+const APITokensFile = "tokens.json"
+
+// APIToken is one named bearer token. The name identifies the client in
+// logs and keeps its conversations apart from other clients'.
+type APIToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// LoadAPITokens reads the configured tokens. A missing file means none are
+// configured; tokens with an empty value are skipped.
+func LoadAPITokens(confDir string) ([]APIToken, error) {
+	path := filepath.Join(confDir, APITokensFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var file struct {
+		Tokens []APIToken `json:"tokens"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	out := make([]APIToken, 0, len(file.Tokens))
+	for _, t := range file.Tokens {
+		if t.Token != "" {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// MatchAPIToken returns the token equal to presented, comparing in
+// constant time.
+func MatchAPIToken(tokens []APIToken, presented string) (APIToken, bool) {
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(presented)) == 1 {
+			return t, true
+		}
+	}
+	return APIToken{}, false
+}
@@ -82,3 +82,19 @@ func (l *Law) Headings() ([]string, error) {
 	return headings, nil
 }
 
+// WithAddendum returns the lawbook followed by a project's own additions,
+// as set by the "lawbook" key of its .rictus config. The project section
+// comes last so its rules read as refinements of the general ones.
+func (l *Law) WithAddendum(projectName, addendum string) (string, error) {
+	base, err := l.ReadAll()
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	addendum = strings.TrimSpace(addendum)
+	if addendum == "" {
+		return base, nil
+	}
+
+	return strings.TrimRight(base, "\n") + "\n\n# Project addendum: " + projectName + "\n\n" + addendum + "\n", nil
+}
@@ -0,0 +1,245 @@
+package law
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Router Law checks. Each names a detector the router analyzer runs; a
+// limit, where one applies, is the largest value that still passes.
+const (
+	CheckDB        = "db"        // database calls and raw queries
+	CheckHTML      = "html"      // inline HTML, echoed markup or hand-built JSON
+	CheckHeader    = "header"    // header(), setcookie(), http_response_code()
+	CheckFileIO    = "file-io"   // file writes, deletes and handles
+	CheckLogging   = "logging"   // error_log(), syslog()
+	CheckBootstrap = "bootstrap" // routing that starts before any include
+	CheckBranches  = "branches"  // decision points; limit is the maximum
+	CheckLength    = "length"    // lines of code; limit is the maximum
+)
+
+// KnownChecks lists every check a Router Law rule may name.
+var KnownChecks = []string{CheckDB, CheckHTML, CheckHeader, CheckFileIO, CheckLogging, CheckBootstrap, CheckBranches, CheckLength}
+
+// defaultLimits apply when a rule names a check without a number.
+var defaultLimits = map[string]int{
+	CheckBranches: 15,
+	CheckLength:   120,
+}
+
+// RuleCheck is one detector a rule asks for.
+type RuleCheck struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// RouterRule is one numbered rule of the Router Law.
+type RouterRule struct {
+	Number int         `json:"number"`
+	Title  string      `json:"title"`
+	Text   string      `json:"text"` // the rule and its bullets, as written
+	Checks []RuleCheck `json:"checks"`
+}
+
+// RouterLaw is the Router Law section of a lawbook.
+type RouterLaw struct {
+	Version string       `json:"version"` // e.g. "1.0"; empty if the heading has none
+	Rules   []RouterRule `json:"rules"`
+	Unknown []string     `json:"unknown,omitempty"` // check names no detector implements
+}
+
+// checkKeywords let a rule without a "Checks:" line pick up detectors from
+// its wording, so a lawbook written as plain prose is still enforced.
+var checkKeywords = []struct {
+	check string
+	words []string
+}{
+	{CheckDB, []string{"database", "mysqli", "pdo", "raw quer"}},
+	{CheckHTML, []string{"html", "rendering", "template"}},
+	{CheckHeader, []string{"header()"}},
+	{CheckFileIO, []string{"file write", "file i/o"}},
+	{CheckLogging, []string{"logging"}},
+	{CheckBootstrap, []string{"bootstrap"}},
+	{CheckBranches, []string{"decision tree", "branches"}},
+	{CheckLength, []string{"short", "length"}},
+}
+
+var (
+	routerHeading = regexp.MustCompile(`(?i)^(#+)\s*router law\b(?:\s+v?(\d+(?:\.\d+)*))?`)
+	ruleStart     = regexp.MustCompile(`^(\d+)\.\s+(.*)$`)
+)
+
+// ParseRouterLaw returns the last Router Law section of the lawbook text, so a
+// project addendum's version wins over the general one. ok is false when
+// the text has no such section.
+func ParseRouterLaw(text string) (rl RouterLaw, ok bool) {
+	lines := strings.Split(text, "\n")
+
+	start, level := -1, 0
+	for i, line := range lines {
+		if m := routerHeading.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			start, level = i, len(m[1])
+			rl = RouterLaw{Version: m[2]}
+		}
+	}
+	if start < 0 {
+		return RouterLaw{}, false
+	}
+
+	var cur *RouterRule
+	var body []string
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		cur.Text = strings.TrimSpace(strings.Join(body, "\n"))
+		if cur.Checks == nil {
+			cur.Checks = inferChecks(cur.Text)
+		}
+		rl.Rules = append(rl.Rules, *cur)
+		cur, body = nil, nil
+	}
+
+	for _, line := range lines[start+1:] {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			depth := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			if depth <= level {
+				break
+			}
+		}
+		if m := ruleStart.FindStringSubmatch(line); m != nil {
+			flush()
+			n, _ := strconv.Atoi(m[1])
+			cur = &RouterRule{Number: n, Title: strings.TrimSpace(m[2])}
+			body = []string{line}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(trimmed, "- "))
+		if len(item) > 7 && strings.EqualFold(item[:7], "checks:") {
+			checks, unknown := parseChecks(item[7:])
+			cur.Checks = checks
+			rl.Unknown = append(rl.Unknown, unknown...)
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	return rl, true
+}
+
+// parseChecks reads a "Checks:" list such as "db, length 120, branches 15".
+// "none" leaves the rule without detectors.
+func parseChecks(list string) (checks []RuleCheck, unknown []string) {
+	checks = make([]RuleCheck, 0)
+	for _, item := range strings.Split(list, ",") {
+		fields := strings.Fields(strings.ToLower(strings.TrimSuffix(strings.TrimSpace(item), ".")))
+		if len(fields) == 0 || fields[0] == "none" {
+			continue
+		}
+		name := fields[0]
+		if !isKnownCheck(name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		c := RuleCheck{Name: name, Limit: defaultLimits[name]}
+		if len(fields) > 1 {
+			if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 {
+				c.Limit = n
+			}
+		}
+		checks = append(checks, c)
+	}
+	return checks, unknown
+}
+
+// inferChecks picks detectors from a rule's wording.
+func inferChecks(text string) []RuleCheck {
+	lower := strings.ToLower(text)
+	checks := make([]RuleCheck, 0)
+	for _, k := range checkKeywords {
+		for _, w := range k.words {
+			if strings.Contains(lower, w) {
+				checks = append(checks, RuleCheck{Name: k.check, Limit: defaultLimits[k.check]})
+				break
+			}
+		}
+	}
+	return checks
+}
+
+func isKnownCheck(name string) bool {
+	for _, k := range KnownChecks {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRouterLaw is the Router Law section of the shipped lawbook. Rules
+// parsed from it keep the lawbook's numbering, so findings cite the same rule
+// whether or not the lawbook on disk has the section.
+const defaultRouterLaw = `## Router Law v1.1
+
+Each rule's "Checks:" line names what ` + "`analyze router`" + ` looks for: db, html,
+header, file-io, logging, bootstrap, branches N (decision points) and length N
+(lines of code). A rule without one is matched on its wording.
+
+1. The router’s only job is to route.
+   - It maps an incoming request (URL, path, method) to a handler, controller, or module.
+   - It does not “do the work” itself.
+   - Checks: none
+
+2. No business logic in the router.
+   - No direct database calls (mysqli, PDO, raw queries).
+   - No complex decision trees that belong in controllers or services.
+   - The router only decides *where* execution goes, not *what* the business rules are.
+   - Checks: db
+
+3. No rendering logic in the router.
+   - The router may call a renderer or helper (e.g. render_json(), render_view()).
+   - It does not manually build HTML, JSON strings, or templates inside routing branches.
+   - Checks: html
+
+4. Bootstrap, then route.
+   - The router may require/include bootstrap or core initialization.
+   - After bootstrap, it should resolve the route and delegate to the correct handler.
+   - Checks: bootstrap
+
+5. Keep the router short and readable.
+   - Routing branches (if/else, switch) should be straightforward.
+   - Each route branch should clearly dispatch to a single handler or a small chain of helpers.
+   - Checks: length 120, branches 15
+
+6. No side effects outside routing.
+   - No random header() calls, file writes, or logging blasts scattered through routes.
+   - If side effects are needed, they belong in the handler, controller, or a dedicated helper.
+   - Checks: header, file-io, logging
+`
+
+// DefaultRouterLaw is used when the lawbook has no Router Law section.
+func DefaultRouterLaw() RouterLaw {
+	rl, _ := ParseRouterLaw(defaultRouterLaw)
+	return rl
+}
+
+// RouterLaw returns the Router Law in effect for a project: the last Router
+// Law section of the lawbook and its addendum, or the default rules if
+// neither has one. The lawbook is read on every call, so a new version takes
+// effect without a restart.
+func (l *Law) RouterLaw(projectName, addendum string) (rl RouterLaw, fromLawbook bool, err error) {
+	text, err := l.WithAddendum(projectName, addendum)
+	if err != nil {
+		return DefaultRouterLaw(), false, err
+	}
+	if rl, ok := ParseRouterLaw(text); ok && len(rl.Rules) > 0 {
+		return rl, true, nil
+	}
+	return DefaultRouterLaw(), false, nil
+}
@@ -0,0 +1,78 @@
+package law
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseRouterLaw(t *testing.T) {
+	text := `# Lawbook
+
+## Other
+
+1. Not a router rule.
+
+## Router Law v2.0
+
+1. Route only.
+   - Checks: none
+
+2. No queries.
+   - Checks: db, branches 10, teleport
+
+3. Keep it short.
+   - The router stays under its length limit.
+
+## After
+
+1. Not a router rule either.
+`
+	rl, ok := ParseRouterLaw(text)
+	if !ok {
+		t.Fatal("ParseRouterLaw found no Router Law section")
+	}
+	if rl.Version != "2.0" {
+		t.Errorf("Version = %q; want 2.0", rl.Version)
+	}
+
+	want := []struct {
+		number int
+		title  string
+		checks []RuleCheck
+	}{
+		{1, "Route only.", []RuleCheck{}},
+		{2, "No queries.", []RuleCheck{{Name: CheckDB}, {Name: CheckBranches, Limit: 10}}},
+		{3, "Keep it short.", []RuleCheck{{Name: CheckLength, Limit: defaultLimits[CheckLength]}}},
+	}
+	if len(rl.Rules) != len(want) {
+		t.Fatalf("got %d rules; want %d: %+v", len(rl.Rules), len(want), rl.Rules)
+	}
+	for i, w := range want {
+		r := rl.Rules[i]
+		if r.Number != w.number || r.Title != w.title || !reflect.DeepEqual(r.Checks, w.checks) {
+			t.Errorf("rule %d = {%d %q %+v}; want {%d %q %+v}", i, r.Number, r.Title, r.Checks, w.number, w.title, w.checks)
+		}
+	}
+	if !reflect.DeepEqual(rl.Unknown, []string{"teleport"}) {
+		t.Errorf("Unknown = %v; want [teleport]", rl.Unknown)
+	}
+
+	if _, ok := ParseRouterLaw("# Lawbook\n\n1. Nothing about routers.\n"); ok {
+		t.Error("ParseRouterLaw found a section in a lawbook without one")
+	}
+}
+
+func TestDefaultRouterLawMatchesLawbook(t *testing.T) {
+	data, err := os.ReadFile("../../conf/lawbook.md")
+	if err != nil {
+		t.Skip(err)
+	}
+	book, ok := ParseRouterLaw(string(data))
+	if !ok {
+		t.Fatal("conf/lawbook.md has no Router Law section")
+	}
+	if def := DefaultRouterLaw(); !reflect.DeepEqual(def, book) {
+		t.Errorf("DefaultRouterLaw() = %+v\nlawbook = %+v", def, book)
+	}
+}
@@ -0,0 +1,292 @@
+package lsp
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+	"rictusd/modules/patch"
+)
+
+// fixerRules links fixers to the rules whose diagnostics they resolve, so
+// editors can offer them on the squiggle itself.
+var fixerRules = map[string][]string{
+	"header":       {"php/strict-types", "php/docblock"},
+	"strict-types": {"php/strict-types"},
+}
+
+// document resolves a URI to its project and project-relative path. Only
+// PHP files inside a registered project are analyzed.
+func (s *Server) document(uri string) (core.Project, string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return core.Project{}, "", false
+	}
+	path := filepath.FromSlash(u.Path)
+	if !strings.EqualFold(filepath.Ext(path), ".php") {
+		return core.Project{}, "", false
+	}
+	proj, ok := s.mind.Projects().FindByPath(path)
+	if !ok {
+		return core.Project{}, "", false
+	}
+	rel, err := filepath.Rel(proj.Path, path)
+	if err != nil {
+		return core.Project{}, "", false
+	}
+	return proj, rel, true
+}
+
+// fileURI is the URI of a file under a project.
+func fileURI(proj core.Project, rel string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(proj.Path, rel))}
+	return u.String()
+}
+
+// --- Diagnostics ------------------------------------------------------------
+
+// publishDiagnostics checks an open document and sends what it finds. Files
+// outside registered projects get an empty list, which clears stale ones.
+func (s *Server) publishDiagnostics(uri string) {
+	text := s.docs[uri]
+	diags := make([]diagnostic, 0)
+	if proj, rel, ok := s.document(uri); ok {
+		for _, f := range s.mind.FileFindings(proj, rel, []byte(text)) {
+			diags = append(diags, toDiagnostic(text, f))
+		}
+	}
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+// toDiagnostic places a finding on its line. Whole-file findings go on the
+// first line. The suggested fix and the law it enforces follow the message.
+func toDiagnostic(text string, f brain.Finding) diagnostic {
+	line := f.Line - 1
+	if line < 0 {
+		line = 0
+	}
+	end := line
+	if f.EndLine > f.Line {
+		end = f.EndLine - 1
+	}
+
+	msg := f.Message
+	if f.Fix != "" {
+		msg += "\n" + f.Fix
+	}
+	if f.Law != "" {
+		msg += "\n" + f.Law
+	}
+
+	sev := severityInformation
+	switch f.Severity {
+	case brain.SeverityError:
+		sev = severityError
+	case brain.SeverityWarning:
+		sev = severityWarning
+	}
+
+	return diagnostic{
+		Range: lspRange{
+			Start: position{Line: line},
+			End:   position{Line: end, Character: utf16Len(lineAt(text, end))},
+		},
+		Severity: sev,
+		Code:     f.RuleID,
+		Source:   "rictusd",
+		Message:  msg,
+	}
+}
+
+// --- Code actions -----------------------------------------------------------
+
+// codeActions offers every applicable fixer that would change the document
+// as it stands in the editor. Fixers that aren't safe on their own are
+// offered too, but never as the preferred fix.
+func (s *Server) codeActions(p codeActionParams) []codeAction {
+	out := make([]codeAction, 0)
+	if !wantsKind(p.Context.Only, codeActionQuickFix) {
+		return out
+	}
+	text, open := s.docs[p.TextDocument.URI]
+	proj, rel, ok := s.document(p.TextDocument.URI)
+	if !open || !ok {
+		return out
+	}
+
+	for _, f := range patch.Fixers() {
+		if !patch.Applies(f, proj) {
+			continue
+		}
+		prop, changed, err := s.patchEng.ProposeContent(proj, rel, text, f)
+		if err != nil {
+			s.core.Log.Warnf("lsp: %v", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		title := "RictusD: fix " + f.Issue()
+		if !f.Standalone() {
+			title += " (review the behavior change)"
+		}
+		action := codeAction{
+			Title: title,
+			Kind:  codeActionQuickFix,
+			Edit:  workspaceEdit{Changes: map[string][]textEdit{p.TextDocument.URI: {minimalEdit(prop.Original, prop.Patched)}}},
+		}
+		for _, d := range p.Context.Diagnostics {
+			if d.Source == "rictusd" && containsString(fixerRules[f.Name()], d.Code) {
+				action.Diagnostics = append(action.Diagnostics, d)
+			}
+		}
+		action.IsPreferred = f.Standalone() && len(action.Diagnostics) > 0
+		out = append(out, action)
+	}
+	return out
+}
+
+// wantsKind reports whether a client's "only" filter admits a kind. Kinds
+// are hierarchical: "quickfix" admits "quickfix.rictusd".
+func wantsKind(only []string, kind string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, k := range only {
+		if k == kind || strings.HasPrefix(kind, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// minimalEdit turns a whole-file rewrite into one edit covering only the
+// lines that differ, so the editor keeps the cursor and folds elsewhere.
+func minimalEdit(original, patched string) textEdit {
+	a, b := splitKeepEnds(original), splitKeepEnds(patched)
+
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	end := position{Line: len(a) - suf}
+	if suf == 0 && len(a) > 0 && !strings.HasSuffix(original, "\n") {
+		// The last line has no newline to stand after.
+		end = position{Line: len(a) - 1, Character: utf16Len(a[len(a)-1])}
+	}
+	return textEdit{
+		Range:   lspRange{Start: position{Line: pre}, End: end},
+		NewText: strings.Join(b[pre:len(b)-suf], ""),
+	}
+}
+
+// splitKeepEnds splits text into lines that keep their line endings.
+func splitKeepEnds(s string) []string {
+	out := make([]string, 0, strings.Count(s, "\n")+1)
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			out = append(out, s)
+			break
+		}
+		out = append(out, s[:i+1])
+		s = s[i+1:]
+	}
+	return out
+}
+
+// --- Definitions ------------------------------------------------------------
+
+// definition looks up the declarations of the symbol under the cursor in
+// the project's symbol index.
+func (s *Server) definition(ctx context.Context, p positionParams) []location {
+	out := make([]location, 0)
+	text, open := s.docs[p.TextDocument.URI]
+	proj, rel, ok := s.document(p.TextDocument.URI)
+	if !open || !ok {
+		return out
+	}
+
+	col := byteOffset(lineAt(text, p.Position.Line), p.Position.Character)
+	q := brain.SymbolAt([]byte(text), filepath.ToSlash(rel), p.Position.Line+1, col)
+	if q == "" {
+		return out
+	}
+
+	idx, _, err := brain.NewIndexer(s.core).RefreshContext(ctx, proj, nil)
+	if err != nil {
+		s.core.Log.Warnf("lsp: index %s: %v", proj.Name, err)
+		return out
+	}
+	for _, hit := range idx.FindSymbol(q) {
+		pos := position{Line: hit.Line - 1}
+		out = append(out, location{URI: fileURI(proj, filepath.FromSlash(hit.File)), Range: lspRange{Start: pos, End: pos}})
+	}
+	return out
+}
+
+// --- Positions --------------------------------------------------------------
+
+// lineAt returns a 0-based line of text without its line ending.
+func lineAt(text string, n int) string {
+	for ; n > 0; n-- {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			return ""
+		}
+		text = text[i+1:]
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSuffix(text, "\r")
+}
+
+// utf16Len is the length of s in UTF-16 code units, the unit LSP columns
+// are counted in.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Units(r)
+	}
+	return n
+}
+
+// byteOffset converts a UTF-16 column on a line into a byte offset.
+func byteOffset(line string, col int) int {
+	units := 0
+	for i, r := range line {
+		if units >= col {
+			return i
+		}
+		units += utf16Units(r)
+	}
+	return len(line)
+}
+
+// utf16Units is 2 for runes outside the Basic Multilingual Plane, which
+// UTF-16 encodes as surrogate pairs, and 1 otherwise.
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
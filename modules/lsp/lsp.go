@@ -0,0 +1,225 @@
+// Package lsp serves RictusD's findings to editors over the Language Server
+// Protocol: diagnostics for open PHP files, code actions backed by the patch
+// fixers, and go-to-definition from the symbol index.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/core"
+	"rictusd/modules/mind"
+	"rictusd/modules/patch"
+)
+
+// Server is a language server bound to one client connection. Messages are
+// handled one at a time in the order they arrive.
+type Server struct {
+	core     *core.Core
+	mind     *mind.Mind
+	patchEng *patch.Engine
+
+	out  *bufio.Writer
+	docs map[string]string // URI -> current text of open documents
+
+	initialized bool
+	shutdown    bool
+}
+
+// New constructs a language server. Nothing is read or written until Serve.
+func New(c *core.Core) *Server {
+	return &Server{
+		core:     c,
+		mind:     mind.New(c),
+		patchEng: patch.NewEngine(c),
+		docs:     make(map[string]string),
+	}
+}
+
+// Serve reads LSP messages from in and answers on out until the client
+// sends exit, in is closed, or ctx is cancelled. Exiting without a
+// shutdown request first is reported as an error, as the protocol asks.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = bufio.NewWriter(out)
+
+	msgs := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			body, err := readMessage(r)
+			if err != nil {
+				errc <- err
+				return
+			}
+			msgs <- body
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case body := <-msgs:
+			var req request
+			if err := json.Unmarshal(body, &req); err != nil {
+				s.replyError(nil, codeParseError, err.Error())
+				continue
+			}
+			if req.Method == "exit" {
+				if !s.shutdown {
+					return errors.New("lsp: exit without shutdown")
+				}
+				return nil
+			}
+			s.handle(ctx, req)
+		}
+	}
+}
+
+// handle dispatches one request or notification.
+func (s *Server) handle(ctx context.Context, req request) {
+	if !s.initialized && req.Method != "initialize" {
+		if req.ID != nil {
+			s.replyError(req.ID, codeNotInitialized, "the server is not initialized")
+		}
+		return
+	}
+
+	switch req.Method {
+	case "initialize":
+		s.initialized = true
+		s.reply(req.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    textDocumentSyncFull,
+					"save":      map[string]any{"includeText": true},
+				},
+				"codeActionProvider": map[string]any{"codeActionKinds": []string{codeActionQuickFix}},
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "rictusd"},
+		})
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+	case "shutdown":
+		s.shutdown = true
+		s.reply(req.ID, nil)
+
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if s.decode(req, &p) {
+			s.docs[p.TextDocument.URI] = p.TextDocument.Text
+			s.publishDiagnostics(p.TextDocument.URI)
+		}
+	case "textDocument/didChange":
+		var p didChangeParams
+		if s.decode(req, &p) && len(p.ContentChanges) > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+			s.publishDiagnostics(p.TextDocument.URI)
+		}
+	case "textDocument/didSave":
+		var p didSaveParams
+		if s.decode(req, &p) {
+			if p.Text != nil {
+				s.docs[p.TextDocument.URI] = *p.Text
+			}
+			s.publishDiagnostics(p.TextDocument.URI)
+		}
+	case "textDocument/didClose":
+		var p didCloseParams
+		if s.decode(req, &p) {
+			delete(s.docs, p.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []diagnostic{}})
+		}
+
+	case "textDocument/codeAction":
+		var p codeActionParams
+		if s.decode(req, &p) {
+			s.reply(req.ID, s.codeActions(p))
+		}
+	case "textDocument/definition":
+		var p positionParams
+		if s.decode(req, &p) {
+			s.reply(req.ID, s.definition(ctx, p))
+		}
+
+	default:
+		if req.ID != nil {
+			s.replyError(req.ID, codeMethodNotFound, "method not supported: "+req.Method)
+		}
+	}
+}
+
+// decode unmarshals a message's params, answering requests with an error
+// when they don't fit.
+func (s *Server) decode(req request, v any) bool {
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		s.core.Log.Warnf("lsp: %s: bad params: %v", req.Method, err)
+		if req.ID != nil {
+			s.replyError(req.ID, codeInvalidParams, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+func (s *Server) reply(id *json.RawMessage, result any) {
+	s.write(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, msg string) {
+	s.write(errorResponse{JSONRPC: "2.0", ID: id, Error: rpcError{Code: code, Message: msg}})
+}
+
+func (s *Server) notify(method string, params any) {
+	s.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// write frames a message with its Content-Length header.
+func (s *Server) write(v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		s.core.Log.Errorf("lsp: encode message: %v", err)
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(body))
+	s.out.Write(body)
+	if err := s.out.Flush(); err != nil {
+		s.core.Log.Errorf("lsp: write message: %v", err)
+	}
+}
+
+// readMessage reads one framed message: headers, a blank line, and a body
+// of Content-Length bytes.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("lsp: read header: %w", err)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("lsp: bad Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("lsp: read body: %w", err)
+	}
+	return body, nil
+}
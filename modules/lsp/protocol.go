@@ -0,0 +1,132 @@
+package lsp
+
+import "encoding/json"
+
+// The JSON-RPC envelope and the slice of the Language Server Protocol
+// RictusD speaks: document sync, diagnostics, code actions and definitions.
+type (
+	request struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id,omitempty"` // nil for notifications
+		Method  string           `json:"method"`
+		Params  json.RawMessage  `json:"params,omitempty"`
+	}
+	response struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Result  any              `json:"result"`
+	}
+	errorResponse struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Error   rpcError         `json:"error"`
+	}
+	notification struct {
+		JSONRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  any    `json:"params"`
+	}
+	rpcError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	position struct {
+		Line      int `json:"line"`      // 0-based
+		Character int `json:"character"` // UTF-16 code units
+	}
+	lspRange struct {
+		Start position `json:"start"`
+		End   position `json:"end"`
+	}
+	location struct {
+		URI   string   `json:"uri"`
+		Range lspRange `json:"range"`
+	}
+
+	textDocumentItem struct {
+		URI        string `json:"uri"`
+		LanguageID string `json:"languageId"`
+		Version    int    `json:"version"`
+		Text       string `json:"text"`
+	}
+	textDocumentID struct {
+		URI string `json:"uri"`
+	}
+	didOpenParams struct {
+		TextDocument textDocumentItem `json:"textDocument"`
+	}
+	didChangeParams struct {
+		TextDocument   textDocumentID `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	didSaveParams struct {
+		TextDocument textDocumentID `json:"textDocument"`
+		Text         *string        `json:"text,omitempty"`
+	}
+	didCloseParams struct {
+		TextDocument textDocumentID `json:"textDocument"`
+	}
+	positionParams struct {
+		TextDocument textDocumentID `json:"textDocument"`
+		Position     position       `json:"position"`
+	}
+	codeActionParams struct {
+		TextDocument textDocumentID `json:"textDocument"`
+		Range        lspRange       `json:"range"`
+		Context      struct {
+			Diagnostics []diagnostic `json:"diagnostics"`
+			Only        []string     `json:"only,omitempty"`
+		} `json:"context"`
+	}
+
+	diagnostic struct {
+		Range    lspRange `json:"range"`
+		Severity int      `json:"severity"`
+		Code     string   `json:"code,omitempty"`
+		Source   string   `json:"source"`
+		Message  string   `json:"message"`
+	}
+	publishDiagnosticsParams struct {
+		URI         string       `json:"uri"`
+		Diagnostics []diagnostic `json:"diagnostics"`
+	}
+
+	textEdit struct {
+		Range   lspRange `json:"range"`
+		NewText string   `json:"newText"`
+	}
+	workspaceEdit struct {
+		Changes map[string][]textEdit `json:"changes"`
+	}
+	codeAction struct {
+		Title       string        `json:"title"`
+		Kind        string        `json:"kind"`
+		Diagnostics []diagnostic  `json:"diagnostics,omitempty"`
+		IsPreferred bool          `json:"isPreferred,omitempty"`
+		Edit        workspaceEdit `json:"edit"`
+	}
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeNotInitialized = -32002
+)
+
+// Diagnostic severities.
+const (
+	severityError       = 1
+	severityWarning     = 2
+	severityInformation = 3
+)
+
+// textDocumentSyncFull asks the client to send whole documents on change.
+const textDocumentSyncFull = 1
+
+// codeActionQuickFix is the kind of every code action RictusD offers.
+const codeActionQuickFix = "quickfix"
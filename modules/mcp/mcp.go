@@ -0,0 +1,184 @@
+// Package mcp exposes RictusD's project knowledge to other agents over the
+// Model Context Protocol. Tools follow the lawbook's agent classes: Observer
+// tools only read, Scribe tools only prepare proposals, and nothing an MCP
+// client does is written to a project without Madam's approval in chat.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+	"rictusd/modules/mind"
+	"rictusd/modules/patch"
+)
+
+// ProtocolVersion is the MCP revision RictusD implements. Clients asking for
+// another revision are answered with this one, as the protocol prescribes.
+const ProtocolVersion = "2025-06-18"
+
+// maxLine caps one stdio message.
+const maxLine = 16 << 20
+
+// The JSON-RPC 2.0 messages MCP is carried in.
+type (
+	request struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id,omitempty"` // nil for notifications
+		Method  string           `json:"method"`
+		Params  json.RawMessage  `json:"params,omitempty"`
+	}
+	response struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Result  any              `json:"result"`
+	}
+	errorResponse struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Error   rpcError         `json:"error"`
+	}
+	rpcError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Server answers MCP requests. It holds no per-client state, so one Server
+// can back the stdio transport and every HTTP request alike.
+type Server struct {
+	core     *core.Core
+	mind     *mind.Mind
+	brain    *brain.Brain
+	patchEng *patch.Engine
+	queue    *patch.Queue
+}
+
+// New constructs an MCP server answering from m's projects.
+func New(c *core.Core, m *mind.Mind) *Server {
+	return &Server{
+		core:     c,
+		mind:     m,
+		brain:    brain.NewBrain(c),
+		patchEng: patch.NewEngine(c),
+		queue:    m.Queue(),
+	}
+}
+
+// ServeStdio reads newline-delimited JSON-RPC messages from in and writes
+// responses to out, one per line, until in is closed or ctx is cancelled.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(in)
+		sc.Buffer(make([]byte, 64<<10), maxLine)
+		for sc.Scan() {
+			lines <- append([]byte(nil), sc.Bytes()...)
+		}
+		errc <- sc.Err()
+	}()
+
+	w := bufio.NewWriter(out)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return err
+		case line := <-lines:
+			if len(line) == 0 {
+				continue
+			}
+			resp, ok := s.Handle(ctx, line)
+			if !ok {
+				continue
+			}
+			w.Write(resp)
+			w.WriteByte('\n')
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Handle answers one JSON-RPC message. It returns false for notifications,
+// which get no response.
+func (s *Server) Handle(ctx context.Context, body []byte) ([]byte, bool) {
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return s.encode(errorResponse{JSONRPC: "2.0", Error: rpcError{codeParseError, err.Error()}}), true
+	}
+	if req.Method == "" {
+		if req.ID == nil {
+			// A client's response to a request we never send.
+			return nil, false
+		}
+		return s.encode(errorResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcError{codeInvalidRequest, "missing method"}}), true
+	}
+
+	result, err := s.dispatch(ctx, req)
+	if req.ID == nil {
+		return nil, false
+	}
+	var rpcErr *rpcError
+	if errors.As(err, &rpcErr) {
+		return s.encode(errorResponse{JSONRPC: "2.0", ID: req.ID, Error: *rpcErr}), true
+	}
+	return s.encode(response{JSONRPC: "2.0", ID: req.ID, Result: result}), true
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// dispatch runs one method.
+func (s *Server) dispatch(ctx context.Context, req request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": "rictusd", "version": "2"},
+			"instructions": "RictusD knows the projects registered with it. Observer tools read and analyze; " +
+				"Scribe tools queue patch proposals, which are written only after approval in RictusD's chat.",
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "tools/list":
+		return map[string]any{"tools": toolList()}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+		return s.call(ctx, p.Name, p.Arguments)
+	}
+	return nil, &rpcError{codeMethodNotFound, "method not supported: " + req.Method}
+}
+
+func (s *Server) encode(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.core.Log.Errorf("mcp: encode response: %v", err)
+		data, _ = json.Marshal(errorResponse{JSONRPC: "2.0", Error: rpcError{codeInternalError, "internal error"}})
+	}
+	return data
+}
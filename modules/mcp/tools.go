@@ -0,0 +1,532 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+	"rictusd/modules/patch"
+)
+
+// Agent classes from the lawbook (§7.1).
+const (
+	classObserver = "observer" // read-only
+	classScribe   = "scribe"   // drafts and proposals, applied only with consent
+)
+
+// Limits that keep tool results small enough for a model's context.
+const (
+	readFileMax      = 512 << 10 // bytes read_file returns
+	searchFileMax    = 1 << 20   // files search_code skips above this size
+	searchResultsMax = 200
+	searchLineMax    = 240 // characters of a matching line returned
+)
+
+// tool is one MCP tool. run returns the result to encode as JSON, or an
+// error reported to the client as a failed call.
+type tool struct {
+	name        string
+	class       string
+	description string
+	params      map[string]any // JSON Schema properties
+	required    []string
+	run         func(s *Server, ctx context.Context, args json.RawMessage) (any, error)
+}
+
+var projectParam = map[string]any{"type": "string", "description": "registered project name or alias"}
+
+// tools is the fixed tool set, in the order tools/list shows it.
+var tools = []tool{
+	{
+		name:        "list_projects",
+		class:       classObserver,
+		description: "List the projects registered with RictusD, with their detected languages and frameworks.",
+		params:      map[string]any{},
+		run:         (*Server).listProjects,
+	},
+	{
+		name:        "map_project",
+		class:       classObserver,
+		description: "Map a project: files, line counts per language, sizes, largest files and the per-directory breakdown.",
+		params:      map[string]any{"project": projectParam},
+		required:    []string{"project"},
+		run:         (*Server).mapProject,
+	},
+	{
+		name:        "analyze_project",
+		class:       classObserver,
+		description: "Run every analyzer that applies to a project and return the findings, each with a rule ID, severity, location and suggested fix.",
+		params:      map[string]any{"project": projectParam},
+		required:    []string{"project"},
+		run:         (*Server).analyzeProject,
+	},
+	{
+		name:        "analyze_router",
+		class:       classObserver,
+		description: "Find a PHP project's router and check it against Router Law, citing the rule each finding breaks.",
+		params:      map[string]any{"project": projectParam},
+		required:    []string{"project"},
+		run:         (*Server).analyzeRouter,
+	},
+	{
+		name:        "read_file",
+		class:       classObserver,
+		description: "Read a project file. Only files RictusD indexes are readable: ignored paths such as vendor/ and secrets such as .env are not.",
+		params: map[string]any{
+			"project":    projectParam,
+			"path":       map[string]any{"type": "string", "description": "project-relative path"},
+			"start_line": map[string]any{"type": "integer", "minimum": 1, "description": "first line to return (default 1)"},
+			"end_line":   map[string]any{"type": "integer", "minimum": 1, "description": "last line to return (default: the end)"},
+		},
+		required: []string{"project", "path"},
+		run:      (*Server).readFile,
+	},
+	{
+		name:        "search_code",
+		class:       classObserver,
+		description: "Search a project's indexed text files for a string or a regular expression and return the matching lines.",
+		params: map[string]any{
+			"project":     projectParam,
+			"query":       map[string]any{"type": "string"},
+			"regex":       map[string]any{"type": "boolean", "description": "treat query as a Go regular expression"},
+			"max_results": map[string]any{"type": "integer", "minimum": 1, "maximum": searchResultsMax, "description": "default 50"},
+		},
+		required: []string{"project", "query"},
+		run:      (*Server).searchCode,
+	},
+	{
+		name:        "list_fixers",
+		class:       classScribe,
+		description: "List the patch fixers RictusD can propose, with the issue each one fixes.",
+		params:      map[string]any{},
+		run:         (*Server).listFixers,
+	},
+	{
+		name:  "propose_patch",
+		class: classScribe,
+		description: "Run a fixer over one project file and queue the result as a proposal. Nothing is written: " +
+			"the proposal waits in RictusD's approval queue until Madam applies it in chat.",
+		params: map[string]any{
+			"project": projectParam,
+			"path":    map[string]any{"type": "string", "description": "project-relative path of a PHP file"},
+			"fixer":   map[string]any{"type": "string", "enum": fixerNames(), "description": "default header"},
+		},
+		required: []string{"project", "path"},
+		run:      (*Server).proposePatch,
+	},
+	{
+		name:        "list_proposals",
+		class:       classScribe,
+		description: "List a project's proposals waiting for approval.",
+		params:      map[string]any{"project": projectParam},
+		required:    []string{"project"},
+		run:         (*Server).listProposals,
+	},
+}
+
+func fixerNames() []string {
+	out := make([]string, 0)
+	for _, f := range patch.Fixers() {
+		out = append(out, f.Name())
+	}
+	return out
+}
+
+// toolList renders the tools for tools/list. The class leads each
+// description so clients show it, and the annotations say the same.
+func toolList() []map[string]any {
+	out := make([]map[string]any, 0, len(tools))
+	for _, t := range tools {
+		label := "Observer"
+		if t.class == classScribe {
+			label = "Scribe"
+		}
+		schema := map[string]any{"type": "object", "properties": t.params}
+		if len(t.required) > 0 {
+			schema["required"] = t.required
+		}
+		out = append(out, map[string]any{
+			"name":        t.name,
+			"description": label + " action. " + t.description,
+			"inputSchema": schema,
+			"annotations": map[string]any{
+				"readOnlyHint":    t.class == classObserver,
+				"destructiveHint": false,
+				"openWorldHint":   false,
+			},
+		})
+	}
+	return out
+}
+
+// call runs a tool and logs the call to events.jsonl, whatever its outcome.
+func (s *Server) call(ctx context.Context, name string, args json.RawMessage) (any, error) {
+	var t *tool
+	for i := range tools {
+		if tools[i].name == name {
+			t = &tools[i]
+		}
+	}
+	if t == nil {
+		s.brain.Record("mcp", "unknown", name+" refused: no such tool")
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + name}
+	}
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+
+	result, err := t.run(s, ctx, args)
+
+	entry := t.name + " " + compactJSON(args)
+	if err != nil {
+		s.brain.Record("mcp", t.class, entry+" failed: "+err.Error())
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}, nil
+	}
+	s.brain.Record("mcp", t.class, entry+" ok")
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, &rpcError{codeInternalError, err.Error()}
+	}
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": string(data)}},
+		"isError": false,
+	}, nil
+}
+
+func compactJSON(raw json.RawMessage) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return string(raw)
+	}
+	return b.String()
+}
+
+// --- Arguments --------------------------------------------------------------
+
+type projectArgs struct {
+	Project string `json:"project"`
+}
+
+// project decodes the arguments into v and looks up the project they name.
+func (s *Server) project(args json.RawMessage, v any) (core.Project, error) {
+	if err := json.Unmarshal(args, v); err != nil {
+		return core.Project{}, fmt.Errorf("bad arguments: %w", err)
+	}
+	var pa projectArgs
+	json.Unmarshal(args, &pa)
+	if strings.TrimSpace(pa.Project) == "" {
+		return core.Project{}, fmt.Errorf("project is required")
+	}
+	p, ok := s.mind.Projects().FindByName(pa.Project)
+	if !ok {
+		return core.Project{}, fmt.Errorf("no registered project named %q", pa.Project)
+	}
+	return p, nil
+}
+
+// projectFile resolves a project-relative path to a file the index holds.
+// Paths that leave the project, directly or through a symlink, are refused.
+func (s *Server) projectFile(ctx context.Context, p core.Project, path string) (string, string, error) {
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(path, "./")))
+	if path == "" || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("%q is not a path inside the project", path)
+	}
+
+	idx, _, err := brain.NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if !idx.Has(rel) {
+		return "", "", fmt.Errorf("%s is not a file RictusD indexes in %q", filepath.ToSlash(rel), p.Name)
+	}
+	if secretFile(rel) {
+		return "", "", fmt.Errorf("%s looks like it holds secrets, so I don’t hand it out", filepath.ToSlash(rel))
+	}
+
+	full, err := core.ResolveProjectPath(p.Path, rel)
+	if err != nil {
+		return "", "", err
+	}
+	return rel, full, nil
+}
+
+// secretFile reports whether a path looks like credentials: dotenv files,
+// private keys and keystores.
+func secretFile(rel string) bool {
+	base := strings.ToLower(filepath.Base(rel))
+	switch {
+	case base == ".env" || strings.HasPrefix(base, ".env."):
+		return true
+	case strings.HasPrefix(base, "id_rsa") || strings.HasPrefix(base, "id_ed25519") || strings.HasPrefix(base, "id_ecdsa"):
+		return true
+	}
+	switch filepath.Ext(base) {
+	case ".pem", ".key", ".p12", ".pfx", ".jks", ".keystore":
+		return true
+	}
+	return false
+}
+
+// --- Observer tools ---------------------------------------------------------
+
+func (s *Server) listProjects(ctx context.Context, args json.RawMessage) (any, error) {
+	return s.mind.Projects().List(), nil
+}
+
+func (s *Server) mapProject(ctx context.Context, args json.RawMessage) (any, error) {
+	p, err := s.project(args, &projectArgs{})
+	if err != nil {
+		return nil, err
+	}
+	pm, _, err := brain.NewMapper(s.core).BuildMapContext(ctx, p)
+	return pm, err
+}
+
+func (s *Server) analyzeProject(ctx context.Context, args json.RawMessage) (any, error) {
+	p, err := s.project(args, &projectArgs{})
+	if err != nil {
+		return nil, err
+	}
+	findings, err := s.mind.Findings(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return brain.FindingsReport{Project: p.Name, Root: p.Path, Findings: findings}, nil
+}
+
+func (s *Server) analyzeRouter(ctx context.Context, args json.RawMessage) (any, error) {
+	p, err := s.project(args, &projectArgs{})
+	if err != nil {
+		return nil, err
+	}
+	if !p.HasLanguage("php") {
+		return nil, fmt.Errorf("%q has no PHP, so it has no router to check", p.Name)
+	}
+	rel, findings := s.mind.RouterLawFindings(p)
+	if rel == "" {
+		return nil, fmt.Errorf("I couldn’t find a router in %q; set router in its .rictus config", p.Name)
+	}
+	if findings == nil {
+		findings = []brain.Finding{}
+	}
+	return map[string]any{"project": p.Name, "router": filepath.ToSlash(rel), "findings": findings}, nil
+}
+
+func (s *Server) readFile(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+	p, err := s.project(args, &a)
+	if err != nil {
+		return nil, err
+	}
+	rel, full, err := s.projectFile(ctx, p, a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, fmt.Errorf("%s is a binary file", filepath.ToSlash(rel))
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	start, end := max(a.StartLine, 1), len(lines)
+	if a.EndLine > 0 && a.EndLine < end {
+		end = a.EndLine
+	}
+	content := ""
+	if start <= end {
+		content = strings.Join(lines[start-1:end], "")
+	}
+	truncated := len(content) > readFileMax
+	if truncated {
+		content = content[:readFileMax]
+	}
+
+	return map[string]any{
+		"path":       filepath.ToSlash(rel),
+		"lines":      len(lines),
+		"start_line": start,
+		"end_line":   end,
+		"content":    content,
+		"truncated":  truncated,
+	}, nil
+}
+
+// searchMatch is one line search_code found.
+type searchMatch struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+func (s *Server) searchCode(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		Query      string `json:"query"`
+		Regex      bool   `json:"regex"`
+		MaxResults int    `json:"max_results"`
+	}
+	p, err := s.project(args, &a)
+	if err != nil {
+		return nil, err
+	}
+	if a.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	limit := a.MaxResults
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, searchResultsMax)
+
+	match := func(line string) bool { return strings.Contains(line, a.Query) }
+	if a.Regex {
+		re, err := regexp.Compile(a.Query)
+		if err != nil {
+			return nil, fmt.Errorf("bad regular expression: %w", err)
+		}
+		match = re.MatchString
+	}
+
+	idx, _, err := brain.NewIndexer(s.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]searchMatch, 0)
+	truncated := false
+	for _, e := range idx.Sorted() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if e.Size > searchFileMax || secretFile(e.Path) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(p.Path, filepath.FromSlash(e.Path)))
+		if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			continue
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if !match(line) {
+				continue
+			}
+			if len(matches) == limit {
+				truncated = true
+				break
+			}
+			text := strings.TrimSpace(line)
+			if len(text) > searchLineMax {
+				text = text[:searchLineMax] + "…"
+			}
+			matches = append(matches, searchMatch{File: e.Path, Line: i + 1, Text: text})
+		}
+		if truncated {
+			break
+		}
+	}
+
+	return map[string]any{"matches": matches, "truncated": truncated}, nil
+}
+
+// --- Scribe tools -----------------------------------------------------------
+
+func (s *Server) listFixers(ctx context.Context, args json.RawMessage) (any, error) {
+	out := make([]map[string]any, 0)
+	for _, f := range patch.Fixers() {
+		out = append(out, map[string]any{
+			"name":       f.Name(),
+			"issue":      f.Issue(),
+			"scope":      f.Scope(),
+			"standalone": f.Standalone(),
+		})
+	}
+	return out, nil
+}
+
+func (s *Server) proposePatch(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		Path  string `json:"path"`
+		Fixer string `json:"fixer"`
+	}
+	p, err := s.project(args, &a)
+	if err != nil {
+		return nil, err
+	}
+	if a.Fixer == "" {
+		a.Fixer = "header"
+	}
+	fixer, ok := patch.FixerByName(a.Fixer)
+	if !ok {
+		return nil, fmt.Errorf("no fixer called %q; list_fixers shows the ones there are", a.Fixer)
+	}
+	if !patch.Applies(fixer, p) {
+		return nil, fmt.Errorf("the %s fixer doesn’t apply to %q", fixer.Name(), p.Name)
+	}
+	rel, _, err := s.projectFile(ctx, p, a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	prop, changed, err := s.patchEng.Propose(p, rel, fixer)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return map[string]any{"queued": false, "message": filepath.ToSlash(rel) + " doesn’t need the " + fixer.Name() + " fixer."}, nil
+	}
+	prop, err = s.queue.Add(prop)
+	if err != nil {
+		return nil, err
+	}
+	s.brain.Record("proposal", fixer.Name(), p.Name+":"+prop.RelPath)
+
+	return map[string]any{
+		"queued":      true,
+		"proposal_id": prop.ID,
+		"project":     p.Name,
+		"path":        filepath.ToSlash(prop.RelPath),
+		"fixer":       fixer.Name(),
+		"standalone":  fixer.Standalone(),
+		"patched":     prop.Patched,
+		"approval": "Queued, not written. It is applied only when Madam approves it in RictusD's chat " +
+			"(\"show project " + p.Name + "\", then \"apply " + filepath.ToSlash(prop.RelPath) + "\").",
+	}, nil
+}
+
+func (s *Server) listProposals(ctx context.Context, args json.RawMessage) (any, error) {
+	p, err := s.project(args, &projectArgs{})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]map[string]any, 0)
+	for _, prop := range s.queue.ForProject(p.Name) {
+		out = append(out, map[string]any{
+			"proposal_id": prop.ID,
+			"path":        filepath.ToSlash(prop.RelPath),
+			"fixer":       prop.Fixer,
+			"create":      prop.Create,
+			"created_at":  prop.CreatedAt,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package mind
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/brain"
+)
+
+// archFindingsShown caps how many findings per layer a reply lists.
+const archFindingsShown = 8
+
+// archLayers is the order layers are reported in, with their plural names.
+var archLayers = []struct {
+	role brain.Role
+	name string
+}{
+	{brain.RoleRouter, "Routers"},
+	{brain.RoleController, "Controllers"},
+	{brain.RoleModel, "Models"},
+	{brain.RoleView, "Views"},
+	{brain.RoleConfig, "Config"},
+	{brain.RoleUnknown, "Unclassified"},
+}
+
+// --- Architecture -----------------------------------------------------------
+
+func (m *Mind) handleArchitecture(ctx context.Context, arg string) string {
+	proj, fail := m.projectArg(arg, "architecture")
+	if fail != "" {
+		return fail
+	}
+
+	report, err := m.phpScan.CheckArchitecture(ctx, proj)
+	if err != nil {
+		m.core.Log.Errorf("architecture %s failed: %v", proj.Name, err)
+		return m.address + ", I couldn’t check the architecture of \"" + proj.Name + "\": " + err.Error()
+	}
+
+	total := 0
+	census := make([]string, 0, len(archLayers))
+	for _, l := range archLayers {
+		if n := report.Roles[l.role]; n > 0 {
+			total += n
+			census = append(census, strconv.Itoa(n)+" "+strings.ToLower(l.name))
+		}
+	}
+	if total == 0 {
+		return m.address + ", \"" + proj.Name + "\" has no PHP files, so there are no layers to check."
+	}
+
+	m.brain.Record("architecture", proj.Name, strconv.Itoa(len(report.Violations))+" findings")
+
+	var b strings.Builder
+	b.WriteString(m.address + ", I sorted the " + plural(total, "PHP file", "PHP files") + " of \"" + proj.Name + "\" into layers: " +
+		joinList(census) + ".\n")
+	if len(report.Unknown) > 0 {
+		b.WriteString("I ignored parts of the architecture config I don’t understand: " + joinList(report.Unknown) + ".\n")
+	}
+
+	if len(report.Violations) == 0 {
+		b.WriteString("Every layer keeps to its rules.")
+		return b.String()
+	}
+	b.WriteString("I found " + plural(len(report.Violations), "layer violation", "layer violations") + ":\n")
+
+	for _, l := range archLayers {
+		var hits []brain.ArchFinding
+		for _, f := range report.Violations {
+			if f.Role == l.role {
+				hits = append(hits, f)
+			}
+		}
+		if len(hits) == 0 {
+			continue
+		}
+		b.WriteString("\n" + l.name + " (must not: " + strings.Join(report.Rules[l.role], ", ") + ")\n")
+		for i, f := range hits {
+			if i == archFindingsShown {
+				b.WriteString("- …and " + strconv.Itoa(len(hits)-archFindingsShown) + " more\n")
+				break
+			}
+			b.WriteString("- " + f.File + ":" + strconv.Itoa(f.Line) + ": " + f.Message + ": " + f.Snippet + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
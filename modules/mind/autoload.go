@@ -0,0 +1,62 @@
+package mind
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// autoloadShown caps how many autoload issues a reply lists.
+const autoloadShown = 15
+
+// --- Composer autoload ------------------------------------------------------
+
+func (m *Mind) handleAutoload(ctx context.Context, arg string) string {
+	proj, fail := m.projectArg(arg, "check autoload")
+	if fail != "" {
+		return fail
+	}
+
+	report, err := m.phpScan.CheckAutoload(ctx, proj)
+	if err != nil {
+		m.core.Log.Errorf("autoload check for %s failed: %v", proj.Name, err)
+		return m.address + ", I couldn’t check the autoload setup of \"" + proj.Name + "\": " + err.Error()
+	}
+	if !report.HasComposer {
+		return m.address + ", \"" + proj.Name + "\" has no composer.json, so there’s no autoload setup to check."
+	}
+
+	if report.Mappings == 0 && len(report.Issues) == 0 && report.ClassesChecked == 0 {
+		return m.address + ", the composer.json of \"" + proj.Name + "\" doesn’t declare any autoloading, so there’s nothing to check."
+	}
+
+	m.brain.Record("autoload", proj.Name, strconv.Itoa(len(report.Issues))+" issues")
+
+	if len(report.Issues) == 0 {
+		return m.address + ", the autoload setup of \"" + proj.Name + "\" checks out: " +
+			plural(report.ClassesChecked, "class", "classes") + " against " +
+			plural(report.Mappings, "mapping", "mappings") + ", and every imported class resolves."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", I checked " + plural(report.ClassesChecked, "class", "classes") + " in \"" + proj.Name +
+		"\" against " + plural(report.Mappings, "autoload mapping", "autoload mappings") + " and found " +
+		plural(len(report.Issues), "problem", "problems") + ":\n")
+
+	for i, is := range report.Issues {
+		if i == autoloadShown {
+			b.WriteString("- …and " + strconv.Itoa(len(report.Issues)-autoloadShown) + " more\n")
+			break
+		}
+		line := "- "
+		if is.File != "" && is.Line > 0 {
+			line += is.File + ":" + strconv.Itoa(is.Line) + ": "
+		}
+		line += is.Message + "."
+		if is.Suggestion != "" {
+			line += " Suggestion: " + is.Suggestion + "."
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,142 @@
+package mind
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+)
+
+// changesShown caps how many paths or issues are listed per category.
+const changesShown = 10
+
+// --- Map snapshots ----------------------------------------------------------
+
+// projectArg resolves the project a command names, falling back to the
+// last project when the argument is empty.
+func (m *Mind) projectArg(arg, verb string) (core.Project, string) {
+	name := strings.TrimSpace(arg)
+	if name == "" {
+		name = m.lastProject
+	}
+	if name == "" {
+		return core.Project{}, m.address + ", tell me which project. For example: " + verb + " chaos-mvc."
+	}
+
+	proj, ok := m.Projects().FindByName(name)
+	if !ok {
+		return core.Project{}, m.address + ", I don’t see a registered project named \"" + name + "\"."
+	}
+	m.lastProject = proj.Name
+	return proj, ""
+}
+
+func (m *Mind) handleChanges(ctx context.Context, arg string) string {
+	proj, fail := m.projectArg(arg, "changes")
+	if fail != "" {
+		return fail
+	}
+	if m.mapper == nil {
+		m.mapper = brain.NewMapper(m.core)
+	}
+
+	diff, ok, err := m.mapper.Changes(ctx, proj)
+	if err != nil {
+		m.core.Log.Errorf("changes %s failed: %v", proj.Name, err)
+		return m.address + ", I couldn’t compare \"" + proj.Name + "\": " + err.Error()
+	}
+	if !ok {
+		return m.address + ", this is the first map I have of \"" + proj.Name + "\". Ask again later and I’ll tell you what changed since now."
+	}
+
+	m.brain.Record("changes", proj.Name, diffSummary(diff))
+	return m.describeDiff(proj.Name, diff, "since the last map")
+}
+
+func (m *Mind) handleDiffMap(arg string) string {
+	proj, fail := m.projectArg(arg, "diff map")
+	if fail != "" {
+		return fail
+	}
+	if m.mapper == nil {
+		m.mapper = brain.NewMapper(m.core)
+	}
+
+	diff, ok, err := m.mapper.DiffLatest(proj.Name)
+	if err != nil {
+		m.core.Log.Errorf("diff map %s failed: %v", proj.Name, err)
+		return m.address + ", I couldn’t read the snapshots of \"" + proj.Name + "\": " + err.Error()
+	}
+	if !ok {
+		return m.address + ", I need at least two different maps of \"" + proj.Name + "\" to compare. Map it again after it changes."
+	}
+
+	return m.describeDiff(proj.Name, diff, "between the last two snapshots")
+}
+
+// describeDiff renders a MapDiff for chat.
+func (m *Mind) describeDiff(name string, d brain.MapDiff, span string) string {
+	if d.Empty() {
+		return m.address + ", nothing changed in \"" + name + "\" " + span + " (" + snapshotTime(d.From) + ")."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", here’s what changed in \"" + name + "\" " + span)
+	b.WriteString(" (" + snapshotTime(d.From) + " → " + snapshotTime(d.To) + ").\n\n")
+
+	b.WriteString("Files: " + strconv.Itoa(d.FilesBefore) + " → " + strconv.Itoa(d.FilesAfter) + ".\n")
+	writePaths(&b, "Added", d.Added)
+	writePaths(&b, "Removed", d.Removed)
+	writePaths(&b, "Modified", d.Modified)
+
+	if len(d.LanguagesAdded) > 0 {
+		b.WriteString("New languages: " + joinList(d.LanguagesAdded) + ".\n")
+	}
+	if len(d.LanguagesRemoved) > 0 {
+		b.WriteString("Languages gone: " + joinList(d.LanguagesRemoved) + ".\n")
+	}
+	if d.DepthBefore != d.DepthAfter {
+		b.WriteString("Max depth went from " + strconv.Itoa(d.DepthBefore) + " to " + strconv.Itoa(d.DepthAfter) + ".\n")
+	}
+
+	writePaths(&b, "New PHP issues", d.NewIssues)
+	writePaths(&b, "Resolved PHP issues", d.ResolvedIssues)
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writePaths lists up to changesShown items under a label.
+func writePaths(b *strings.Builder, label string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	b.WriteString(label + " (" + strconv.Itoa(len(items)) + "):\n")
+	for i, it := range items {
+		if i == changesShown {
+			b.WriteString("- …and " + strconv.Itoa(len(items)-changesShown) + " more\n")
+			break
+		}
+		b.WriteString("- " + it + "\n")
+	}
+}
+
+// diffSummary is the one-line form recorded in the brain.
+func diffSummary(d brain.MapDiff) string {
+	return "added=" + strconv.Itoa(len(d.Added)) +
+		" removed=" + strconv.Itoa(len(d.Removed)) +
+		" modified=" + strconv.Itoa(len(d.Modified)) +
+		" new_issues=" + strconv.Itoa(len(d.NewIssues)) +
+		" resolved_issues=" + strconv.Itoa(len(d.ResolvedIssues))
+}
+
+// snapshotTime renders a snapshot's RFC3339 stamp in local time.
+func snapshotTime(stamp string) string {
+	t, err := time.Parse(time.RFC3339, stamp)
+	if err != nil {
+		return stamp
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,216 @@
+package mind
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"rictusd/modules/brain"
+)
+
+// watchInterval is how often a watched workspace root is re-scanned.
+const watchInterval = time.Minute
+
+// --- Workspace discovery ----------------------------------------------------
+
+// parseDiscoverArg splits "<dir> [depth N]" into the directory and depth.
+func parseDiscoverArg(arg string) (string, int) {
+	dir, rest, ok := splitArg(arg, " depth ")
+	if !ok {
+		return dir, brain.DefaultDiscoverDepth
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(rest))
+	if err != nil || n <= 0 {
+		return strings.TrimSpace(arg), brain.DefaultDiscoverDepth
+	}
+	return dir, n
+}
+
+func (m *Mind) handleDiscover(arg string) string {
+	dir, depth := parseDiscoverArg(arg)
+	if dir == "" {
+		return m.address + ", tell me which directory to search. For example: discover ~/src."
+	}
+
+	found, err := brain.Discover(dir, depth)
+	if err != nil {
+		m.core.Log.Errorf("discover %s failed: %v", dir, err)
+		return m.address + ", I couldn’t search \"" + dir + "\": " + err.Error()
+	}
+
+	m.discovered = found
+	m.brain.Record("discover", dir, strconv.Itoa(len(found))+" candidates")
+
+	if len(found) == 0 {
+		return m.address + ", I didn’t find any repositories under \"" + dir + "\" within " + strconv.Itoa(depth) + " levels."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", I found " + strconv.Itoa(len(found)) + " repositor")
+	if len(found) == 1 {
+		b.WriteString("y")
+	} else {
+		b.WriteString("ies")
+	}
+	b.WriteString(" under \"" + dir + "\":\n")
+
+	fresh := 0
+	for i, d := range found {
+		b.WriteString(strconv.Itoa(i+1) + ". " + d.Path + " (" + stackSummary(d.Stack) + ", " + d.Marker + ")")
+		if p, ok := m.Projects().FindByPath(d.Path); ok && p.Path == d.Path {
+			b.WriteString(" already registered as \"" + p.Name + "\"")
+		} else {
+			fresh++
+		}
+		b.WriteString("\n")
+	}
+
+	if fresh == 0 {
+		b.WriteString("\nAll of them are already registered.")
+	} else {
+		b.WriteString("\nSay \"register all\" or pick some, for example \"register 1, 3\".")
+	}
+	return b.String()
+}
+
+func (m *Mind) handleRegisterFound(arg string) string {
+	if len(m.discovered) == 0 {
+		return m.address + ", I don’t have any discovered repositories to register. Run \"discover <dir>\" first."
+	}
+
+	var picks []int
+	if strings.EqualFold(strings.TrimSpace(arg), "all") {
+		for i := range m.discovered {
+			picks = append(picks, i)
+		}
+	} else {
+		for _, item := range splitList(arg) {
+			n, err := strconv.Atoi(item)
+			if err != nil || n < 1 || n > len(m.discovered) {
+				return m.address + ", \"" + item + "\" isn’t one of the numbers I listed (1–" + strconv.Itoa(len(m.discovered)) + ")."
+			}
+			picks = append(picks, n-1)
+		}
+	}
+
+	var b strings.Builder
+	registered := 0
+	for _, i := range picks {
+		d := m.discovered[i]
+		if p, ok := m.Projects().FindByPath(d.Path); ok && p.Path == d.Path {
+			b.WriteString("- " + d.Path + ": already registered as \"" + p.Name + "\"\n")
+			continue
+		}
+
+		p, err := m.RegisterProject(d.Path)
+		if err != nil {
+			m.core.Log.Errorf("register %s failed: %v", d.Path, err)
+			b.WriteString("- " + d.Path + ": failed, " + err.Error() + "\n")
+			continue
+		}
+		m.lastProject = p.Name
+		m.brain.Record("project", "register", p.Name+" "+p.Path)
+		registered++
+		b.WriteString("- \"" + p.Name + "\" → " + p.Path + " (" + stackLabel(p) + ")\n")
+	}
+
+	head := m.address + ", I registered " + strconv.Itoa(registered) + " project"
+	if registered != 1 {
+		head += "s"
+	}
+	return head + ":\n" + b.String()
+}
+
+func (m *Mind) handleWatch(arg string) string {
+	dir, depth := parseDiscoverArg(arg)
+	if dir == "" {
+		return m.address + ", tell me which directory to watch. For example: watch ~/src."
+	}
+
+	// Validate the root and normalize it before keying the watch on it.
+	if _, err := brain.Discover(dir, depth); err != nil {
+		return m.address + ", I can’t watch \"" + dir + "\": " + err.Error()
+	}
+	root, _ := filepath.Abs(brain.ExpandHome(dir))
+
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+
+	if _, ok := m.watches[root]; ok {
+		return m.address + ", I’m already watching " + root + "."
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.watches[root] = cancel
+
+	reg := m.Projects()
+	go brain.Watch(ctx, root, depth, watchInterval, func(d brain.Discovered) {
+		if p, ok := reg.FindByPath(d.Path); ok && p.Path == d.Path {
+			return
+		}
+		p, err := reg.Register(d.Path)
+		if err != nil {
+			m.core.Log.Errorf("watch %s: register %s failed: %v", root, d.Path, err)
+			return
+		}
+		if _, err := reg.SetStack(p.Name, d.Stack.Type, d.Stack.Languages, d.Stack.Frameworks, d.Stack.Tooling); err != nil {
+			m.core.Log.Warnf("watch %s: detect stack for %s: %v", root, p.Name, err)
+		}
+		m.core.Log.Infof("watch %s: registered new project %q at %s", root, p.Name, p.Path)
+		m.brain.Record("project", "watch", p.Name+" "+p.Path)
+	})
+
+	m.brain.Record("discover", "watch", root)
+	return m.address + ", I’ll check " + root + " every " + watchInterval.String() + " and register new repositories as they appear. Say \"unwatch " + dir + "\" to stop."
+}
+
+// StopWatching stops every workspace watcher, e.g. on shutdown.
+func (m *Mind) StopWatching() {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+
+	for root, cancel := range m.watches {
+		cancel()
+		delete(m.watches, root)
+	}
+}
+
+func (m *Mind) handleUnwatch(arg string) string {
+	if strings.TrimSpace(arg) == "" {
+		m.watchMu.Lock()
+		n := len(m.watches)
+		m.watchMu.Unlock()
+
+		if n == 0 {
+			return m.address + ", I’m not watching any directories."
+		}
+		m.StopWatching()
+		return m.address + ", I’ve stopped watching all directories."
+	}
+
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+
+	if len(m.watches) == 0 {
+		return m.address + ", I’m not watching any directories."
+	}
+
+	root, _ := filepath.Abs(brain.ExpandHome(strings.TrimSpace(arg)))
+	cancel, ok := m.watches[root]
+	if !ok {
+		return m.address + ", I’m not watching " + root + "."
+	}
+	cancel()
+	delete(m.watches, root)
+	return m.address + ", I’ve stopped watching " + root + "."
+}
+
+// stackSummary names a detected stack for candidate lists.
+func stackSummary(st brain.Stack) string {
+	if len(st.Frameworks) > 0 {
+		return st.Type + "/" + strings.Join(st.Frameworks, "+")
+	}
+	return st.Type
+}
@@ -0,0 +1,168 @@
+package mind
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+)
+
+// --- Findings ---------------------------------------------------------------
+
+// Findings runs every analyzer that applies to a project and returns what
+// they report as one list, ordered by file and line. It writes nothing but
+// the include graph, and leaves the conversation state alone, so the API
+// can call it too.
+func (m *Mind) Findings(ctx context.Context, proj core.Project) ([]brain.Finding, error) {
+	mapper := brain.NewMapper(m.core)
+	phpScan := brain.NewPHPScanner(m.core)
+
+	pm, _, err := mapper.BuildMapContext(ctx, proj)
+	if err != nil {
+		return nil, err
+	}
+	out := brain.NewSuggestEngine(m.core).FindingsForProject(pm)
+
+	if proj.HasLanguage("php") {
+		report, err := phpScan.AnalyzeProjectContext(ctx, proj)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, report.Findings()...)
+
+		if al, err := phpScan.CheckAutoload(ctx, proj); err != nil {
+			m.core.Log.Warnf("findings: autoload check failed: %v", err)
+		} else {
+			out = append(out, al.Findings()...)
+		}
+
+		if g, err := mapper.IncludeGraphContext(ctx, proj); g != nil {
+			out = append(out, g.Findings()...)
+		} else if err != nil {
+			m.core.Log.Warnf("findings: include graph failed: %v", err)
+		}
+
+		if arch, err := phpScan.CheckArchitecture(ctx, proj); err != nil {
+			m.core.Log.Warnf("findings: architecture check failed: %v", err)
+		} else {
+			out = append(out, arch.Findings()...)
+		}
+
+		cfg := m.projectConfig(proj)
+		if table, err := phpScan.Routes(ctx, proj, routerCandidates(proj, cfg)); err != nil {
+			m.core.Log.Warnf("findings: routes failed: %v", err)
+		} else {
+			out = append(out, table.Findings()...)
+		}
+		_, law := m.routerLawFindings(proj, cfg)
+		out = append(out, law...)
+	}
+
+	if proj.HasLanguage("go") {
+		report, err := brain.NewGoScanner(m.core).AnalyzeProjectContext(ctx, proj)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, report.Findings()...)
+	}
+
+	js, err := brain.NewJSScanner(m.core).AnalyzeProjectContext(ctx, proj)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, js.Findings()...)
+
+	brain.SortFindings(out)
+	return out, nil
+}
+
+// RouterLawFindings finds the project's router and checks it against Router
+// Law. The router path is "" when the project has none.
+func (m *Mind) RouterLawFindings(proj core.Project) (string, []brain.Finding) {
+	return m.routerLawFindings(proj, m.projectConfig(proj))
+}
+
+func (m *Mind) routerLawFindings(proj core.Project, cfg core.ProjectConfig) (string, []brain.Finding) {
+	rel := findRouter(proj, cfg)
+	if rel == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(filepath.Join(proj.Path, rel))
+	if err != nil {
+		m.core.Log.Warnf("findings: read router: %v", err)
+		return rel, nil
+	}
+	return rel, m.checkRouterLaw(proj, cfg, rel, data)
+}
+
+// checkRouterLaw checks router source against the project's Router Law.
+func (m *Mind) checkRouterLaw(proj core.Project, cfg core.ProjectConfig, rel string, data []byte) []brain.Finding {
+	addendum, err := cfg.LawbookAddendum(proj.Path)
+	if err != nil {
+		m.core.Log.Warnf("findings: %v", err)
+	}
+	rl, _, err := m.law.RouterLaw(proj.Name, addendum)
+	if err != nil {
+		m.core.Log.Warnf("findings: %v", err)
+	}
+	return brain.RouterFindings(filepath.ToSlash(rel), rl, brain.CheckRouterLaw(data, rl))
+}
+
+// FileFindings checks one PHP file of a project as it stands in content,
+// which may differ from the file on disk: strict_types, docblock and
+// require checks, and Router Law when the file is the project's router.
+// Project-wide analyses are left to Findings.
+func (m *Mind) FileFindings(proj core.Project, relPath string, content []byte) []brain.Finding {
+	out := m.phpScan.CheckSource(proj, relPath, content)
+
+	cfg := m.projectConfig(proj)
+	if rel := findRouter(proj, cfg); rel != "" && filepath.Clean(rel) == filepath.Clean(relPath) {
+		out = append(out, m.checkRouterLaw(proj, cfg, rel, content)...)
+	}
+
+	brain.SortFindings(out)
+	return out
+}
+
+// findRouter returns the first router candidate that exists, or "".
+func findRouter(proj core.Project, cfg core.ProjectConfig) string {
+	for _, c := range routerCandidates(proj, cfg) {
+		if info, err := os.Stat(filepath.Join(proj.Path, c)); err == nil && !info.IsDir() {
+			return c
+		}
+	}
+	return ""
+}
+
+// exportFindings collects the project's findings and saves them in the
+// given format, returning the sentence that tells the user where.
+func (m *Mind) exportFindings(ctx context.Context, proj core.Project, format string) string {
+	findings, err := m.Findings(ctx, proj)
+	if err != nil {
+		m.core.Log.Errorf("analyze: findings for %s failed: %v", proj.Name, err)
+		return "I couldn’t collect the findings for the export: " + err.Error()
+	}
+	path, err := m.mapper.WriteFindings(proj, format, findings)
+	if err != nil {
+		m.core.Log.Errorf("analyze: export findings for %s failed: %v", proj.Name, err)
+		return "I couldn’t save the findings: " + err.Error()
+	}
+
+	counts := make(map[brain.Severity]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	m.brain.Record("findings", proj.Name, strconv.Itoa(len(findings))+" findings exported as "+format)
+
+	name := "JSON"
+	if format == "sarif" {
+		name = "SARIF 2.1"
+	}
+	return "I exported " + plural(len(findings), "finding", "findings") + " (" +
+		plural(counts[brain.SeverityError], "error", "errors") + ", " +
+		plural(counts[brain.SeverityWarning], "warning", "warnings") + ", " +
+		plural(counts[brain.SeverityNote], "note", "notes") + ") as " + name + " to " + path + "."
+}
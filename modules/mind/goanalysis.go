@@ -0,0 +1,76 @@
+package mind
+
+import (
+	"strconv"
+	"strings"
+
+	"rictusd/modules/brain"
+)
+
+// goFindingsShown caps how many findings of each kind the Go part of an
+// analysis lists.
+const goFindingsShown = 5
+
+// --- Go analysis ------------------------------------------------------------
+
+// writeGoAnalysis appends the Go part of an analysis.
+func writeGoAnalysis(b *strings.Builder, r brain.GoReport) {
+	b.WriteString("\nOn the Go side, I see " + plural(r.TotalFiles, "file", "files") + " in " +
+		plural(len(r.Packages), "package", "packages"))
+	if r.Module != "" {
+		b.WriteString(" of module " + r.Module)
+	}
+	b.WriteString(".\n")
+
+	if len(r.ParseErrors) > 0 {
+		b.WriteString(plural(len(r.ParseErrors), "file doesn’t", "files don’t") + " parse: " + goIssueFiles(r.ParseErrors) + ".\n")
+	}
+
+	if len(r.ImportCycles) > 0 {
+		b.WriteString("The packages import each other in " + plural(len(r.ImportCycles), "cycle", "cycles") + ":\n")
+		for _, c := range r.ImportCycles {
+			b.WriteString("- " + strings.Join(c, " → ") + "\n")
+		}
+	} else if len(r.Packages) > 1 {
+		b.WriteString("The package imports don’t form any cycles.\n")
+	}
+
+	if r.MissingDocHint > 0 {
+		b.WriteString(plural(r.MissingDocHint, "exported declaration has", "exported declarations have") + " no doc comment.\n")
+		if len(r.SampleNoDoc) > 0 {
+			b.WriteString("Examples include: " + strings.Join(r.SampleNoDoc, ", ") + ".\n")
+		}
+	} else {
+		b.WriteString("Every exported declaration has a doc comment.\n")
+	}
+
+	if len(r.UncheckedErrors) > 0 {
+		b.WriteString(plural(len(r.UncheckedErrors), "call drops its error", "calls drop their errors") + ":\n")
+		writeGoIssues(b, r.UncheckedErrors)
+	} else {
+		b.WriteString("I don’t see common calls with their errors ignored.\n")
+	}
+
+	if len(r.LongFuncs) > 0 {
+		b.WriteString(plural(len(r.LongFuncs), "function runs", "functions run") + " past " + strconv.Itoa(brain.GoLongFunc) + " lines:\n")
+		writeGoIssues(b, r.LongFuncs)
+	}
+}
+
+func writeGoIssues(b *strings.Builder, issues []brain.GoIssue) {
+	for i, is := range issues {
+		if i == goFindingsShown {
+			b.WriteString("- …and " + strconv.Itoa(len(issues)-goFindingsShown) + " more\n")
+			break
+		}
+		b.WriteString("- " + is.File + ":" + strconv.Itoa(is.Line) + ": " + is.Message + "\n")
+	}
+}
+
+func goIssueFiles(issues []brain.GoIssue) string {
+	files := make([]string, 0, len(issues))
+	for _, is := range issues {
+		files = append(files, is.File)
+	}
+	return joinList(files)
+}
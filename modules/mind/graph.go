@@ -0,0 +1,101 @@
+package mind
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/brain"
+)
+
+// --- Include graph ----------------------------------------------------------
+
+func (m *Mind) handleGraph(ctx context.Context, arg string) string {
+	name, format := graphFormat(arg)
+	proj, fail := m.projectArg(name, "graph")
+	if fail != "" {
+		return fail
+	}
+	if m.mapper == nil {
+		m.mapper = brain.NewMapper(m.core)
+	}
+
+	g, err := m.mapper.IncludeGraphContext(ctx, proj)
+	if err != nil {
+		m.core.Log.Errorf("graph %s failed: %v", proj.Name, err)
+		if g == nil {
+			return m.address + ", I couldn’t build the include graph of \"" + proj.Name + "\": " + err.Error()
+		}
+	}
+	if len(g.Nodes) == 0 {
+		return m.address + ", \"" + proj.Name + "\" has no PHP files, so there’s no include graph to draw."
+	}
+
+	m.brain.Record("graph", proj.Name, "nodes="+strconv.Itoa(len(g.Nodes))+
+		" edges="+strconv.Itoa(len(g.Edges))+
+		" cycles="+strconv.Itoa(len(g.Cycles))+
+		" orphans="+strconv.Itoa(len(g.Orphans)))
+
+	var b strings.Builder
+	b.WriteString(m.address + ", the include graph of \"" + proj.Name + "\" has " +
+		plural(len(g.Nodes), "PHP file", "PHP files") + " and " + plural(len(g.Edges), "include", "includes"))
+	if g.Unresolved > 0 {
+		b.WriteString(" (" + strconv.Itoa(g.Unresolved) + " more point nowhere)")
+	}
+	b.WriteString(".\n")
+
+	if len(g.Cycles) == 0 {
+		b.WriteString("No include cycles.\n")
+	} else {
+		cycles := make([]string, 0, len(g.Cycles))
+		for _, c := range g.Cycles {
+			cycles = append(cycles, strings.Join(c, " → "))
+		}
+		writePaths(&b, "Include cycles", cycles)
+	}
+	writePaths(&b, "Orphans (not an entry point, nothing includes them)", g.Orphans)
+
+	if len(g.Central) > 0 {
+		b.WriteString("Most central:\n")
+		for _, n := range g.Central {
+			b.WriteString("- " + n.File + " (included by " + strconv.Itoa(n.FanIn) + ", includes " + strconv.Itoa(n.FanOut) + ")\n")
+		}
+	}
+
+	switch format {
+	case "dot":
+		b.WriteString("```dot\n" + g.DOT() + "```\n")
+	case "mermaid":
+		b.WriteString("```mermaid\n" + g.Mermaid() + "```\n")
+	default:
+		if err == nil {
+			files := m.mapper.GraphFiles(proj.Name)
+			b.WriteString("Saved as " + joinList(files) + ". Say \"graph " + proj.Name + " as dot\" or \"as mermaid\" to see it here.\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// graphFormat splits a trailing "as dot", "as mermaid", "dot" or "mermaid"
+// off a graph argument.
+func graphFormat(arg string) (name, format string) {
+	return splitFormat(arg, "dot", "mermaid")
+}
+
+// splitFormat splits a trailing "as <format>", "in <format>" or "<format>"
+// off a command argument, for any of the given formats.
+func splitFormat(arg string, formats ...string) (name, format string) {
+	name = strings.TrimSpace(arg)
+	lower := strings.ToLower(name)
+	for _, f := range formats {
+		for _, suffix := range []string{" as " + f, " in " + f, " " + f} {
+			if strings.HasSuffix(lower, suffix) {
+				return strings.TrimSpace(name[:len(name)-len(suffix)]), f
+			}
+		}
+		if lower == f || lower == "as "+f {
+			return "", f
+		}
+	}
+	return name, ""
+}
@@ -0,0 +1,72 @@
+package mind
+
+import (
+	"strconv"
+	"strings"
+
+	"rictusd/modules/brain"
+)
+
+// jsFindingsShown caps how many findings of each kind the JavaScript part
+// of an analysis lists.
+const jsFindingsShown = 5
+
+// --- JavaScript analysis ----------------------------------------------------
+
+// writeJSAnalysis appends the JavaScript and TypeScript part of an analysis.
+func writeJSAnalysis(b *strings.Builder, r brain.JSReport) {
+	b.WriteString("\nOn the JavaScript side, I see " + plural(r.TotalFiles, "file", "files") +
+		" with " + plural(len(r.Imports), "import", "imports") + ".\n")
+
+	if len(r.UnresolvedImports) > 0 {
+		b.WriteString(plural(len(r.UnresolvedImports), "relative import doesn’t", "relative imports don’t") + " match a file:\n")
+		for i, imp := range r.UnresolvedImports {
+			if i == jsFindingsShown {
+				b.WriteString("- …and " + strconv.Itoa(len(r.UnresolvedImports)-jsFindingsShown) + " more\n")
+				break
+			}
+			b.WriteString("- " + imp.File + ":" + strconv.Itoa(imp.Line) + " → " + imp.Spec + "\n")
+		}
+	} else if len(r.Imports) > 0 {
+		b.WriteString("Every relative import points at a file.\n")
+	}
+
+	if len(r.Leftovers) > 0 {
+		b.WriteString(plural(len(r.Leftovers), "debugging statement is", "debugging statements are") + " still in the code:\n")
+		writeJSIssues(b, r.Leftovers)
+	} else {
+		b.WriteString("I don’t see leftover console.log or debugger statements.\n")
+	}
+
+	if r.MissingDocHint > 0 {
+		b.WriteString(plural(r.MissingDocHint, "file doesn’t", "files don’t") + " open with a comment.\n")
+		if len(r.SampleNoDoc) > 0 {
+			b.WriteString("Examples include: " + strings.Join(r.SampleNoDoc, ", ") + ".\n")
+		}
+	}
+
+	if !r.HasManifest {
+		return
+	}
+	if len(r.UndeclaredDeps) > 0 {
+		b.WriteString(plural(len(r.UndeclaredDeps), "package is", "packages are") + " imported without being declared in package.json:\n")
+		writeJSIssues(b, r.UndeclaredDeps)
+	}
+	if len(r.UnusedDeps) > 0 {
+		b.WriteString("package.json declares " + plural(len(r.UnusedDeps), "dependency", "dependencies") +
+			" nothing imports: " + joinList(r.UnusedDeps) + ".\n")
+	}
+	if len(r.UndeclaredDeps) == 0 && len(r.UnusedDeps) == 0 {
+		b.WriteString("package.json’s dependencies match what the code imports.\n")
+	}
+}
+
+func writeJSIssues(b *strings.Builder, issues []brain.JSIssue) {
+	for i, is := range issues {
+		if i == jsFindingsShown {
+			b.WriteString("- …and " + strconv.Itoa(len(issues)-jsFindingsShown) + " more\n")
+			break
+		}
+		b.WriteString("- " + is.File + ":" + strconv.Itoa(is.Line) + ": " + is.Message + "\n")
+	}
+}
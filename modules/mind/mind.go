@@ -1,11 +1,14 @@
 package mind
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"rictusd/modules/brain"
 	"rictusd/modules/convo"
@@ -15,6 +18,15 @@ import (
 	"rictusd/modules/tasks"
 )
 
+// How much of a project map the chat reply lists; the API has all of it.
+const (
+	mapDirsShown    = 8
+	mapLargestShown = 5
+)
+
+// routerFindingsShown caps how many findings per Router Law rule are listed.
+const routerFindingsShown = 5
+
 // languageConfig is optional config for how RictusD addresses you.
 type languageConfig struct {
 	Address string `json:"Address"` // e.g., "Madam"
@@ -32,7 +44,10 @@ type Mind struct {
 	suggest  *brain.SuggestEngine
 	init     *brain.Initializer
 	phpScan  *brain.PHPScanner
+	goScan   *brain.GoScanner
+	jsScan   *brain.JSScanner
 	patchEng *patch.Engine
+	queue    *patch.Queue
 	tasks    *tasks.Store
 
 	address        string
@@ -40,10 +55,19 @@ type Mind struct {
 	lastProject    string
 	lastPHPExample string
 
-	lastPatch    map[string]string // key: projectName:relPath
-	lastPatchKey string            // last key we patched
+	lastPatchKey string // projectName:relPath of the last proposal we made
 
 	routerCache map[string]string // projectName -> router relative path
+
+	discovered []brain.Discovered // candidates from the last "discover"
+
+	chatMu   sync.Mutex          // one message at a time; guards the conversation state
+	sessions map[string]*session // saved conversation state, by session key
+	parked   *session            // the main conversation's state while a session runs
+	client   string              // who the running session belongs to; "" in the main conversation
+
+	watchMu sync.Mutex
+	watches map[string]context.CancelFunc // root -> stop
 }
 
 // New initializes the Mind.
@@ -58,12 +82,16 @@ func New(c *core.Core) *Mind {
 		suggest:  brain.NewSuggestEngine(c),
 		init:     brain.NewInitializer(c),
 		phpScan:  brain.NewPHPScanner(c),
+		goScan:   brain.NewGoScanner(c),
+		jsScan:   brain.NewJSScanner(c),
 		patchEng: patch.NewEngine(c),
+		queue:    patch.NewQueue(c),
 		tasks:    tasks.NewStore(c),
 	}
 
-	m.lastPatch = make(map[string]string)
 	m.routerCache = make(map[string]string)
+	m.sessions = make(map[string]*session)
+	m.watches = make(map[string]context.CancelFunc)
 
 	cfg := m.loadLanguageConfig()
 	if strings.TrimSpace(cfg.Address) == "" {
@@ -82,13 +110,44 @@ func New(c *core.Core) *Mind {
 	return m
 }
 
+// Queue exposes the pending proposal queue, so other front ends in the
+// process queue proposals where the chat's "apply" finds them.
+func (m *Mind) Queue() *patch.Queue {
+	return m.queue
+}
+
 // Chat handles a single incoming message and returns the reply text.
 func (m *Mind) Chat(message string) string {
+	return m.ChatContext(context.Background(), message)
+}
+
+// ChatContext is Chat with cancellation: project scans started by the
+// message stop when ctx is done, e.g. when the HTTP client goes away or the
+// daemon shuts down.
+func (m *Mind) ChatContext(ctx context.Context, message string) string {
+	m.chatMu.Lock()
+	defer m.chatMu.Unlock()
+	return m.chat(ctx, message)
+}
+
+// logChat records one turn of the conversation. A session's turns aren't
+// part of the daemon's own conversation, so they stay out of its log and are
+// tagged with their client in the event log.
+func (m *Mind) logChat(role, text string) {
+	if m.client != "" {
+		m.brain.Record("chat", m.client+":"+role, text)
+		return
+	}
+	m.convo.Append(role, text)
+	m.brain.Record("chat", role, text)
+}
+
+// chat answers one message; callers hold chatMu.
+func (m *Mind) chat(ctx context.Context, message string) string {
 	msg := strings.TrimSpace(message)
 
 	// Record inbound message.
-	m.convo.Append("user", msg)
-	m.brain.Record("chat", "user", msg)
+	m.logChat("user", msg)
 	m.core.Log.Infof("chat message: %q", msg)
 
 	lower := strings.ToLower(msg)
@@ -98,26 +157,22 @@ func (m *Mind) Chat(message string) string {
 		switch {
 		case strings.HasPrefix(lower, "task "):
 			reply := m.handleTaskAdd(strings.TrimSpace(msg[5:]))
-			m.convo.Append("daemon", reply)
-			m.brain.Record("chat", "daemon", reply)
+			m.logChat("daemon", reply)
 			return reply
 
 		case strings.HasPrefix(lower, "todo "):
 			reply := m.handleTaskAdd(strings.TrimSpace(msg[5:]))
-			m.convo.Append("daemon", reply)
-			m.brain.Record("chat", "daemon", reply)
+			m.logChat("daemon", reply)
 			return reply
 
 		case lower == "tasks" || lower == "show tasks":
 			reply := m.handleTaskList()
-			m.convo.Append("daemon", reply)
-			m.brain.Record("chat", "daemon", reply)
+			m.logChat("daemon", reply)
 			return reply
 
 		case strings.HasPrefix(lower, "done "):
 			reply := m.handleTaskDone(strings.TrimSpace(msg[5:]))
-			m.convo.Append("daemon", reply)
-			m.brain.Record("chat", "daemon", reply)
+			m.logChat("daemon", reply)
 			return reply
 		}
 	}
@@ -156,12 +211,45 @@ func (m *Mind) Chat(message string) string {
 			reply = m.handleRegisterProject(fake, "register project ")
 		}
 
+	case core.CommandListProjects:
+		reply = m.handleListProjects(cmd.Arg)
+
+	case core.CommandShowProject:
+		reply = m.handleShowProject(cmd.Arg)
+
+	case core.CommandUnregisterProject:
+		reply = m.handleUnregisterProject(cmd.Arg)
+
+	case core.CommandRenameProject:
+		reply = m.handleRenameProject(cmd.Arg)
+
+	case core.CommandAliasProject:
+		reply = m.handleAliasProject(cmd.Arg, false)
+
+	case core.CommandUnaliasProject:
+		reply = m.handleAliasProject(cmd.Arg, true)
+
+	case core.CommandTagProject:
+		reply = m.handleTagProject(cmd.Arg, false)
+
+	case core.CommandUntagProject:
+		reply = m.handleTagProject(cmd.Arg, true)
+
+	case core.CommandGroupProject:
+		reply = m.handleGroupProject(cmd.Arg)
+
+	case core.CommandRelocateProject:
+		reply = m.handleRelocateProject(cmd.Arg)
+
+	case core.CommandSetProjectMeta:
+		reply = m.handleSetProjectMeta(cmd.Arg)
+
 	case core.CommandMapProject:
 		if cmd.Arg == "" {
 			reply = m.address + ", you asked me to map a project but didn’t give me a name."
 		} else {
 			fake := "map project " + cmd.Arg
-			reply = m.handleMapProject(fake, "map project ")
+			reply = m.handleMapProject(ctx, fake, "map project ")
 		}
 
 	case core.CommandSuggestProject:
@@ -169,15 +257,15 @@ func (m *Mind) Chat(message string) string {
 			reply = m.address + ", you asked for suggestions but didn’t give me a project name."
 		} else {
 			fake := "suggest project " + cmd.Arg
-			reply = m.handleSuggestProject(fake, "suggest project ")
+			reply = m.handleSuggestProject(ctx, fake, "suggest project ")
 		}
 
 	case core.CommandAnalyze:
 		if cmd.Arg == "" {
-			reply = m.handleAnalyzeDefault()
+			reply = m.handleAnalyzeDefault(ctx)
 		} else {
 			fake := "analyze project " + cmd.Arg
-			reply = m.handleAnalyzeProject(fake, "analyze project ")
+			reply = m.handleAnalyzeProject(ctx, fake, "analyze project ")
 		}
 
 	case core.CommandPatch:
@@ -188,6 +276,57 @@ func (m *Mind) Chat(message string) string {
 			reply = m.handlePatchFile(fake, "patch ")
 		}
 
+	case core.CommandFix:
+		reply = m.handleFix(ctx, cmd.Arg)
+
+	case core.CommandFixers:
+		reply = m.handleFixers()
+
+	case core.CommandDiscover:
+		reply = m.handleDiscover(cmd.Arg)
+
+	case core.CommandRegisterFound:
+		reply = m.handleRegisterFound(cmd.Arg)
+
+	case core.CommandWatch:
+		reply = m.handleWatch(cmd.Arg)
+
+	case core.CommandUnwatch:
+		reply = m.handleUnwatch(cmd.Arg)
+
+	case core.CommandChanges:
+		reply = m.handleChanges(ctx, cmd.Arg)
+
+	case core.CommandDiffMap:
+		reply = m.handleDiffMap(cmd.Arg)
+
+	case core.CommandWhereIs:
+		reply = m.handleWhereIs(ctx, cmd.Arg)
+
+	case core.CommandWhoUses:
+		reply = m.handleWhoUses(ctx, cmd.Arg)
+
+	case core.CommandOutline:
+		reply = m.handleOutline(ctx, cmd.Arg)
+
+	case core.CommandAutoload:
+		reply = m.handleAutoload(ctx, cmd.Arg)
+
+	case core.CommandGraph:
+		reply = m.handleGraph(ctx, cmd.Arg)
+
+	case core.CommandRoutes:
+		reply = m.handleRoutes(ctx, cmd.Arg)
+
+	case core.CommandArchitecture:
+		reply = m.handleArchitecture(ctx, cmd.Arg)
+
+	case core.CommandRepairRequires:
+		reply = m.handleRepairRequires(cmd.Arg)
+
+	case core.CommandStubRequires:
+		reply = m.handleStubRequires(cmd.Arg)
+
 	case core.CommandApply:
 		if cmd.Arg == "" {
 			reply = m.handleApplyDefault()
@@ -201,8 +340,7 @@ func (m *Mind) Chat(message string) string {
 	}
 
 	// Record outbound message.
-	m.convo.Append("daemon", reply)
-	m.brain.Record("chat", "daemon", reply)
+	m.logChat("daemon", reply)
 
 	return reply
 }
@@ -233,7 +371,21 @@ func (m *Mind) lawStatusReply() string {
 		return m.address + ", I don’t see a lawbook yet. I expected it under conf/lawbook.md."
 	}
 
-	return m.address + ", your lawbook is present and loaded. I’m following the rules defined in conf/lawbook.md."
+	reply := m.address + ", your lawbook is present and loaded. I’m following the rules defined in conf/lawbook.md."
+
+	// A project's .rictus config may add its own rules on top.
+	if proj, ok := m.Projects().FindByName(m.lastProject); ok && m.lastProject != "" {
+		cfg := m.projectConfig(proj)
+		addendum, err := cfg.LawbookAddendum(proj.Path)
+		if err != nil {
+			m.core.Log.Warnf("law status: %v", err)
+		}
+		if addendum != "" {
+			reply += " For \"" + proj.Name + "\", " + cfg.Source + " adds a project addendum on top of it."
+		}
+	}
+
+	return reply
 }
 
 // --- Router -----------------------------------------------------------------
@@ -252,34 +404,27 @@ func (m *Mind) handleRouter() string {
 		return m.address + ", I don’t see a registered project named \"" + m.lastProject + "\"."
 	}
 
+	cfg := m.projectConfig(proj)
+
 	// If cached, verify it still exists and return.
-	if rel, ok := m.routerCache[proj.Name]; ok {
+	if rel, ok := m.cachedRouter(proj, cfg); ok {
 		full := filepath.Join(proj.Path, rel)
-		if info, err := os.Stat(full); err == nil && !info.IsDir() {
-			bootstrap := routerHasBootstrap(full)
-
-			var b strings.Builder
-			b.WriteString(m.address + ", for \"" + proj.Name + "\" I’m treating \"" + rel + "\" as the router.\n")
-			if bootstrap {
-				b.WriteString("It references bootstrap.php, so the bootstrap pattern is in place.\n")
-			} else {
-				b.WriteString("I don’t clearly see a bootstrap.php reference in that file yet.\n")
-			}
-			b.WriteString("This path is cached; I’ll reuse it unless the file moves or disappears.")
+		bootstrap := routerHasBootstrap(full)
 
-			return b.String()
+		var b strings.Builder
+		b.WriteString(m.address + ", for \"" + proj.Name + "\" I’m treating \"" + rel + "\" as the router.\n")
+		if bootstrap {
+			b.WriteString("It references bootstrap.php, so the bootstrap pattern is in place.\n")
+		} else {
+			b.WriteString("I don’t clearly see a bootstrap.php reference in that file yet.\n")
 		}
-		// If cached path is invalid now, drop it and re-discover.
-		delete(m.routerCache, proj.Name)
+		b.WriteString("This path is cached; I’ll reuse it unless the file moves or disappears.")
+
+		return b.String()
 	}
 
 	// Discover a router candidate.
-	candidates := []string{
-		"public/index.php",
-		"index.php",
-		"public/router.php",
-		"app/router.php",
-	}
+	candidates := routerCandidates(proj, cfg)
 
 	var found string
 	for _, rel := range candidates {
@@ -291,7 +436,7 @@ func (m *Mind) handleRouter() string {
 	}
 
 	if found == "" {
-		return m.address + ", I don’t see an obvious router file for \"" + proj.Name + "\" yet. I checked public/index.php, index.php, public/router.php, and app/router.php."
+		return m.address + ", I don’t see an obvious router file for \"" + proj.Name + "\" yet. I checked " + joinList(candidates) + "."
 	}
 
 	m.routerCache[proj.Name] = found
@@ -299,7 +444,11 @@ func (m *Mind) handleRouter() string {
 	bootstrap := routerHasBootstrap(full)
 
 	var b strings.Builder
-	b.WriteString(m.address + ", for \"" + proj.Name + "\" I’ve identified \"" + found + "\" as the router.\n")
+	if cfg.Router != "" && found == candidates[0] {
+		b.WriteString(m.address + ", for \"" + proj.Name + "\" I’m using \"" + found + "\" as the router, as " + cfg.Source + " says.\n")
+	} else {
+		b.WriteString(m.address + ", for \"" + proj.Name + "\" I’ve identified \"" + found + "\" as the router.\n")
+	}
 	if bootstrap {
 		b.WriteString("It appears to use bootstrap.php, which matches your preferred pattern.\n")
 	} else {
@@ -324,36 +473,18 @@ func (m *Mind) handleAnalyzeRouter() string {
 		return m.address + ", I don’t see a registered project named \"" + m.lastProject + "\"."
 	}
 
-	// Resolve router file, preferring cache.
-	var rel string
+	cfg := m.projectConfig(proj)
 
-	if cached, ok := m.routerCache[proj.Name]; ok {
-		full := filepath.Join(proj.Path, cached)
-		if info, err := os.Stat(full); err == nil && !info.IsDir() {
-			rel = cached
-		} else {
-			delete(m.routerCache, proj.Name)
-		}
-	}
+	// Resolve router file, preferring cache.
+	rel, _ := m.cachedRouter(proj, cfg)
 
+	candidates := routerCandidates(proj, cfg)
 	if rel == "" {
-		candidates := []string{
-			"public/index.php",
-			"index.php",
-			"public/router.php",
-			"app/router.php",
-		}
-		for _, c := range candidates {
-			full := filepath.Join(proj.Path, c)
-			if info, err := os.Stat(full); err == nil && !info.IsDir() {
-				rel = c
-				break
-			}
-		}
+		rel = findRouter(proj, cfg)
 	}
 
 	if rel == "" {
-		return m.address + ", I tried to analyze the router, but I couldn’t find a likely router file. I checked public/index.php, index.php, public/router.php, and app/router.php."
+		return m.address + ", I tried to analyze the router, but I couldn’t find a likely router file. I checked " + joinList(candidates) + "."
 	}
 
 	m.routerCache[proj.Name] = rel
@@ -365,19 +496,17 @@ func (m *Mind) handleAnalyzeRouter() string {
 		return m.address + ", I found a router candidate but couldn’t read it: " + err.Error()
 	}
 
-	content := string(data)
 	bootstrap := routerHasBootstrap(full)
 
-	hasDB := strings.Contains(content, "mysqli_") ||
-		strings.Contains(content, "PDO") ||
-		strings.Contains(content, "SELECT ")
-	hasHTML := strings.Contains(content, "<html") ||
-		strings.Contains(strings.ToLower(content), "<!doctype html") ||
-		strings.Contains(content, "echo \"<") ||
-		strings.Contains(content, "echo '<")
-	hasSideEffects := strings.Contains(content, "file_put_contents(") ||
-		strings.Contains(content, "fopen(") ||
-		strings.Contains(content, "unlink(")
+	addendum, err := cfg.LawbookAddendum(proj.Path)
+	if err != nil {
+		m.core.Log.Warnf("analyze router: %v", err)
+	}
+	rl, fromLawbook, err := m.law.RouterLaw(proj.Name, addendum)
+	if err != nil {
+		m.core.Log.Warnf("analyze router: %v", err)
+	}
+	findings := brain.CheckRouterLaw(data, rl)
 
 	var b strings.Builder
 
@@ -389,31 +518,174 @@ func (m *Mind) handleAnalyzeRouter() string {
 		b.WriteString("I don’t clearly see a bootstrap.php reference in this file.\n")
 	}
 
-	b.WriteString("\nFrom a Router Law perspective:\n")
-
-	if hasDB {
-		b.WriteString("- I see signs of DB or query logic inside the router, which is something you normally want out of that layer.\n")
+	lawName := "Router Law"
+	if rl.Version != "" {
+		lawName += " v" + rl.Version
+	}
+	if fromLawbook {
+		b.WriteString("\nChecking it against " + lawName + " from your lawbook (" + plural(len(rl.Rules), "rule", "rules") + "):\n")
 	} else {
-		b.WriteString("- I don’t see obvious DB or query logic in the router.\n")
+		b.WriteString("\nYour lawbook has no Router Law section, so I’m checking it against my default router rules:\n")
+	}
+	if len(rl.Unknown) > 0 {
+		b.WriteString("(I don’t have a check called " + joinList(rl.Unknown) + ", so I skipped it.)\n")
 	}
 
-	if hasHTML {
-		b.WriteString("- I see signs of inline HTML or view rendering in the router.\n")
-	} else {
-		b.WriteString("- I don’t see obvious inline HTML or view rendering in the router.\n")
+	if len(findings) == 0 {
+		b.WriteString("\nOverall, this router looks clean under " + lawName + ".")
+		m.brain.Record("router-law", proj.Name, rel+": clean")
+		return b.String()
 	}
 
-	if hasSideEffects {
-		b.WriteString("- I see file or side-effect style operations inside the router.\n")
-	} else {
-		b.WriteString("- I don’t see obvious file I/O side-effects in the router.\n")
+	m.brain.Record("router-law", proj.Name, rel+": "+plural(len(findings), "finding", "findings"))
+
+	perRule := make(map[int]int)
+	for _, f := range findings {
+		perRule[f.Rule]++
+	}
+	shown := make(map[int]int)
+	for i, f := range findings {
+		if i == 0 || findings[i-1].Rule != f.Rule {
+			b.WriteString("\nRule " + strconv.Itoa(f.Rule) + " – " + f.RuleTitle + "\n")
+		}
+		shown[f.Rule]++
+		if shown[f.Rule] > routerFindingsShown {
+			if shown[f.Rule] == routerFindingsShown+1 {
+				b.WriteString("- …and " + strconv.Itoa(perRule[f.Rule]-routerFindingsShown) + " more\n")
+			}
+			continue
+		}
+		b.WriteString("- line " + strconv.Itoa(f.Line) + ": " + f.Message + ": " + f.Snippet + "\n")
 	}
 
-	if !hasDB && !hasHTML && !hasSideEffects {
-		b.WriteString("\nOverall, this router looks clean under your Router Law from this quick scan.")
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// routerCandidates lists likely router files, most likely first: the router
+// named in the project's .rictus config, then guesses based on the frameworks
+// detected for the project.
+func routerCandidates(p core.Project, cfg core.ProjectConfig) []string {
+	var guesses []string
+	switch {
+	case p.HasFramework("laravel"):
+		guesses = []string{"routes/web.php", "routes/api.php", "public/index.php"}
+	case p.HasFramework("slim"):
+		guesses = []string{"app/routes.php", "src/routes.php", "public/index.php", "index.php"}
+	case p.HasFramework("symfony"):
+		guesses = []string{"public/index.php", "config/routes.php"}
+	default:
+		guesses = []string{"public/index.php", "index.php", "public/router.php", "app/router.php"}
 	}
 
-	return b.String()
+	if cfg.Router == "" {
+		return guesses
+	}
+	configured := filepath.Clean(cfg.Router)
+	out := []string{configured}
+	for _, g := range guesses {
+		if g != configured {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// cachedRouter returns the cached router path for a project if the file still
+// exists and the project's config doesn't name a different one.
+func (m *Mind) cachedRouter(p core.Project, cfg core.ProjectConfig) (string, bool) {
+	rel, ok := m.routerCache[p.Name]
+	if !ok {
+		return "", false
+	}
+	if cfg.Router != "" && filepath.Clean(cfg.Router) != rel {
+		delete(m.routerCache, p.Name)
+		return "", false
+	}
+	if info, err := os.Stat(filepath.Join(p.Path, rel)); err != nil || info.IsDir() {
+		delete(m.routerCache, p.Name)
+		return "", false
+	}
+	return rel, true
+}
+
+// projectConfig loads a project's .rictus config, warning and falling back to
+// the defaults when it cannot be parsed.
+func (m *Mind) projectConfig(p core.Project) core.ProjectConfig {
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		m.core.Log.Warnf("project config for %s: %v; using defaults", p.Name, err)
+	}
+	return cfg
+}
+
+// skippedLine reports what ignore rules left out of a walk.
+func skippedLine(st brain.SkipStats) string {
+	var parts []string
+	if st.Dirs > 0 {
+		parts = append(parts, plural(st.Dirs, "directory", "directories"))
+	}
+	if st.Files > 0 {
+		parts = append(parts, plural(st.Files, "file", "files"))
+	}
+	line := "Ignore rules skipped " + joinList(parts)
+	if len(st.Sample) > 0 {
+		line += " (" + strings.Join(st.Sample, ", ") + ")"
+	}
+	return line + ".\n"
+}
+
+// plural renders "1 file" or "3 files".
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return strconv.Itoa(n) + " " + many
+}
+
+// stackLabel names a project's detected stack for short replies.
+func stackLabel(p core.Project) string {
+	if len(p.Frameworks) > 0 {
+		return p.Type + "/" + strings.Join(p.Frameworks, "+")
+	}
+	if p.Type == "" {
+		return "unknown"
+	}
+	return p.Type
+}
+
+// humanBytes renders a byte count as "512 B", "3.4 KB" or "1.2 MB".
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(n)/float64(div), 'f', 1, 64) + " " + string("KMGT"[exp]) + "B"
+}
+
+// languagesByCode orders a map's languages by lines of code, most first.
+func languagesByCode(pm brain.ProjectMap) []string {
+	out := append([]string(nil), pm.Languages...)
+	sort.SliceStable(out, func(i, j int) bool { return pm.Lines[out[i]].Code > pm.Lines[out[j]].Code })
+	return out
+}
+
+// joinList renders "a, b, and c".
+func joinList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " and " + items[1]
+	default:
+		return strings.Join(items[:len(items)-1], ", ") + ", and " + items[len(items)-1]
+	}
 }
 
 func routerHasBootstrap(fullPath string) bool {
@@ -441,7 +713,7 @@ func (m *Mind) handleRegisterProject(msg string, prefix string) string {
 		return m.address + ", you asked me to register a project, but the path was empty."
 	}
 
-	proj, err := m.projects.Register(rawPath)
+	proj, err := m.RegisterProject(rawPath)
 	if err != nil {
 		m.core.Log.Errorf("project register failed: %v", err)
 		return m.address + ", registering that project failed: " + err.Error()
@@ -449,10 +721,14 @@ func (m *Mind) handleRegisterProject(msg string, prefix string) string {
 
 	m.lastProject = proj.Name
 
-	return m.address + ", I’ve registered that as project \"" + proj.Name + "\" at \"" + proj.Path + "\"."
+	reply := m.address + ", I’ve registered that as project \"" + proj.Name + "\" at \"" + proj.Path + "\"."
+	if desc := describeStack(proj); desc != "" {
+		reply += " " + desc
+	}
+	return reply
 }
 
-func (m *Mind) handleMapProject(msg string, prefix string) string {
+func (m *Mind) handleMapProject(ctx context.Context, msg string, prefix string) string {
 	if m.projects == nil {
 		m.projects = core.NewProjectRegistry(m.core.Data)
 	}
@@ -477,9 +753,10 @@ func (m *Mind) handleMapProject(msg string, prefix string) string {
 		return m.address + ", I don’t see a registered project named \"" + raw + "\"."
 	}
 
+	proj = m.detectStack(proj)
 	m.lastProject = proj.Name
 
-	pm, err := m.mapper.MapProject(proj)
+	pm, err := m.mapper.MapProjectContext(ctx, proj)
 	if err != nil {
 		m.core.Log.Errorf("map project failed: %v", err)
 		return m.address + ", mapping that project failed: " + err.Error()
@@ -492,9 +769,51 @@ func (m *Mind) handleMapProject(msg string, prefix string) string {
 	b.WriteString(m.address + ", here’s the high-level map for \"" + proj.Name + "\".\n\n")
 	b.WriteString("Path: " + pm.Path + ".\n")
 	b.WriteString("Files: " + strconv.Itoa(pm.TotalFiles) + ", directories: " + strconv.Itoa(pm.TotalDirs) + ", max depth: " + strconv.Itoa(pm.MaxDepth) + ".\n")
+	if pm.Skipped.Total() > 0 {
+		b.WriteString(skippedLine(pm.Skipped))
+	}
+
+	b.WriteString("Size: " + humanBytes(pm.TotalBytes) + ", " + plural(pm.TotalLines.Total(), "line", "lines") +
+		" (" + strconv.Itoa(pm.TotalLines.Code) + " code, " + plural(pm.TotalLines.Comment, "comment", "comments") + ", " +
+		strconv.Itoa(pm.TotalLines.Blank) + " blank).\n")
+	if pm.BinaryFiles > 0 || pm.GeneratedFiles > 0 {
+		b.WriteString("Left out of line counts: " + plural(pm.BinaryFiles, "binary file", "binary files") +
+			" and " + plural(pm.GeneratedFiles, "generated file", "generated files") + ".\n")
+	}
 
 	if len(pm.Languages) > 0 {
-		b.WriteString("Languages I see: " + strings.Join(pm.Languages, ", ") + ".\n")
+		b.WriteString("\nLanguages I see:\n")
+		for _, lang := range languagesByCode(pm) {
+			lc := pm.Lines[lang]
+			b.WriteString("- " + lang + ": " + strconv.Itoa(lc.Code) + " code, " +
+				plural(lc.Comment, "comment", "comments") + ", " + strconv.Itoa(lc.Blank) + " blank\n")
+		}
+	}
+
+	if len(pm.Directories) > 0 {
+		b.WriteString("\nBy directory:\n")
+		for i, d := range pm.Directories {
+			if i == mapDirsShown {
+				b.WriteString("- …and " + strconv.Itoa(len(pm.Directories)-mapDirsShown) + " more\n")
+				break
+			}
+			line := "- " + d.Path + ": " + plural(d.Files, "file", "files") + ", " + humanBytes(d.Bytes) +
+				", " + plural(d.Lines.Code, "line of code", "lines of code")
+			if len(d.Languages) > 0 {
+				line += " (" + strings.Join(d.Languages, ", ") + ")"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	if len(pm.Largest) > 0 {
+		b.WriteString("\nLargest files:\n")
+		for i, f := range pm.Largest {
+			if i == mapLargestShown {
+				break
+			}
+			b.WriteString("- " + f.Path + " (" + humanBytes(f.Size) + ")\n")
+		}
 	}
 
 	if len(suggestions) > 0 {
@@ -507,7 +826,7 @@ func (m *Mind) handleMapProject(msg string, prefix string) string {
 	return b.String()
 }
 
-func (m *Mind) handleSuggestProject(msg string, prefix string) string {
+func (m *Mind) handleSuggestProject(ctx context.Context, msg string, prefix string) string {
 	if m.projects == nil {
 		m.projects = core.NewProjectRegistry(m.core.Data)
 	}
@@ -532,9 +851,10 @@ func (m *Mind) handleSuggestProject(msg string, prefix string) string {
 		return m.address + ", I don’t see a registered project named \"" + raw + "\"."
 	}
 
+	proj = m.detectStack(proj)
 	m.lastProject = proj.Name
 
-	pm, err := m.mapper.MapProject(proj)
+	pm, err := m.mapper.MapProjectContext(ctx, proj)
 	if err != nil {
 		m.core.Log.Errorf("map for suggestions failed: %v", err)
 		return m.address + ", analyzing that project failed: " + err.Error()
@@ -556,16 +876,16 @@ func (m *Mind) handleSuggestProject(msg string, prefix string) string {
 
 // --- Analyze ---------------------------------------------------------------
 
-func (m *Mind) handleAnalyzeDefault() string {
+func (m *Mind) handleAnalyzeDefault(ctx context.Context) string {
 	if m.lastProject == "" {
 		return m.address + ", you said \"analyze\" but didn’t tell me which project. For example: analyze chaos-mvc."
 	}
 
 	fake := "analyze project " + m.lastProject
-	return m.handleAnalyzeProject(fake, "analyze project ")
+	return m.handleAnalyzeProject(ctx, fake, "analyze project ")
 }
 
-func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
+func (m *Mind) handleAnalyzeProject(ctx context.Context, msg string, prefix string) string {
 	if m.projects == nil {
 		m.projects = core.NewProjectRegistry(m.core.Data)
 	}
@@ -578,12 +898,21 @@ func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
 	if m.phpScan == nil {
 		m.phpScan = brain.NewPHPScanner(m.core)
 	}
+	if m.goScan == nil {
+		m.goScan = brain.NewGoScanner(m.core)
+	}
+	if m.jsScan == nil {
+		m.jsScan = brain.NewJSScanner(m.core)
+	}
 
 	if len(msg) <= len(prefix) {
 		return m.address + ", you asked me to analyze a project but didn’t give me a name."
 	}
 
-	raw := strings.TrimSpace(msg[len(prefix):])
+	raw, format := splitFormat(msg[len(prefix):], "sarif", "json")
+	if raw == "" && format != "" {
+		raw = m.lastProject
+	}
 	if raw == "" {
 		return m.address + ", you asked me to analyze a project but the name was empty."
 	}
@@ -593,6 +922,7 @@ func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
 		return m.address + ", I don’t see a registered project named \"" + raw + "\"."
 	}
 
+	proj = m.detectStack(proj)
 	m.lastProject = proj.Name
 
 	created, readmePath, err := m.init.EnsureReadme(proj)
@@ -601,16 +931,36 @@ func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
 		return m.address + ", I tried to ensure a README exists, but that failed: " + err.Error()
 	}
 
-	pm, err := m.mapper.MapProject(proj)
+	pm, err := m.mapper.MapProjectContext(ctx, proj)
 	if err != nil {
 		m.core.Log.Errorf("analyze: map failed: %v", err)
 		return m.address + ", mapping that project failed: " + err.Error()
 	}
 
-	phpReport, err := m.phpScan.AnalyzeProject(proj)
+	var phpReport brain.PHPReport
+	if proj.HasLanguage("php") {
+		phpReport, err = m.phpScan.AnalyzeProjectContext(ctx, proj)
+		if err != nil {
+			m.core.Log.Errorf("analyze: PHP scan failed: %v", err)
+			return m.address + ", the PHP scan failed: " + err.Error()
+		}
+	}
+
+	var goReport brain.GoReport
+	if proj.HasLanguage("go") {
+		goReport, err = m.goScan.AnalyzeProjectContext(ctx, proj)
+		if err != nil {
+			m.core.Log.Errorf("analyze: Go scan failed: %v", err)
+			return m.address + ", the Go scan failed: " + err.Error()
+		}
+	}
+
+	// Front-end scripts turn up in projects of any stack, so the JavaScript
+	// scan always runs and only reports when it finds files.
+	jsReport, err := m.jsScan.AnalyzeProjectContext(ctx, proj)
 	if err != nil {
-		m.core.Log.Errorf("analyze: PHP scan failed: %v", err)
-		return m.address + ", the PHP scan failed: " + err.Error()
+		m.core.Log.Errorf("analyze: JavaScript scan failed: %v", err)
+		return m.address + ", the JavaScript scan failed: " + err.Error()
 	}
 
 	if len(phpReport.SampleNoDoc) > 0 {
@@ -636,11 +986,35 @@ func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
 	if len(pm.Languages) > 0 {
 		b.WriteString("Language-wise I’m seeing: " + strings.Join(pm.Languages, ", ") + ".\n")
 	}
+	if desc := describeStack(proj); desc != "" {
+		b.WriteString(desc + "\n")
+	}
 
-	// PHP
+	if proj.HasLanguage("php") {
+		m.writePHPAnalysis(ctx, &b, proj, phpReport)
+	}
+	if proj.HasLanguage("go") && goReport.TotalFiles > 0 {
+		writeGoAnalysis(&b, goReport)
+	}
+	if jsReport.TotalFiles > 0 {
+		writeJSAnalysis(&b, jsReport)
+	}
+	if !proj.HasLanguage("php") && goReport.TotalFiles == 0 && jsReport.TotalFiles == 0 {
+		b.WriteString("\nI don’t see PHP, Go or JavaScript here, so I skipped the language checks.\n")
+	}
+
+	if format != "" {
+		b.WriteString("\n" + m.exportFindings(ctx, proj, format) + "\n")
+	}
+
+	return b.String()
+}
+
+// writePHPAnalysis appends the PHP part of an analysis.
+func (m *Mind) writePHPAnalysis(ctx context.Context, b *strings.Builder, proj core.Project, phpReport brain.PHPReport) {
 	if phpReport.TotalFiles == 0 {
-		b.WriteString("\nI don’t see any PHP files yet, so there’s nothing PHP-specific to critique.")
-		return b.String()
+		b.WriteString("\nI don’t see any PHP files yet, so there’s nothing PHP-specific to critique.\n")
+		return
 	}
 
 	b.WriteString("\nOn the PHP side, I see " + strconv.Itoa(phpReport.TotalFiles) + " file")
@@ -648,8 +1022,15 @@ func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
 		b.WriteString("s")
 	}
 	b.WriteString(".\n")
+	if phpReport.Skipped.Total() > 0 {
+		b.WriteString(skippedLine(phpReport.Skipped))
+	}
 
-	if phpReport.MissingStrict > 0 {
+	cfg := m.projectConfig(proj)
+
+	if !cfg.RequireStrict() {
+		b.WriteString("declare(strict_types=1) isn’t required by this project’s config, so I didn’t check for it.\n")
+	} else if phpReport.MissingStrict > 0 {
 		b.WriteString(strconv.Itoa(phpReport.MissingStrict) + " file")
 		if phpReport.MissingStrict != 1 {
 			b.WriteString("s")
@@ -659,7 +1040,9 @@ func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
 		b.WriteString("All inspected PHP files appear to be using declare(strict_types=1).\n")
 	}
 
-	if phpReport.MissingDocHint > 0 {
+	if !cfg.RequireDocblock() {
+		b.WriteString("File docblocks aren’t required by this project’s config, so I didn’t check for them.\n")
+	} else if phpReport.MissingDocHint > 0 {
 		b.WriteString(strconv.Itoa(phpReport.MissingDocHint) + " file")
 		if phpReport.MissingDocHint != 1 {
 			b.WriteString("s")
@@ -694,7 +1077,13 @@ func (m *Mind) handleAnalyzeProject(msg string, prefix string) string {
 		b.WriteString("I don’t see unresolved require/include targets from this scan.\n")
 	}
 
-	return b.String()
+	if al, err := m.phpScan.CheckAutoload(ctx, proj); err != nil {
+		m.core.Log.Warnf("analyze: autoload check failed: %v", err)
+	} else if al.HasComposer && len(al.Issues) > 0 {
+		b.WriteString("Composer’s autoload setup has " + plural(len(al.Issues), "problem", "problems") + "; say \"check autoload\" for the details.\n")
+	} else if al.HasComposer {
+		b.WriteString("Composer’s autoload mappings match the classes I see.\n")
+	}
 }
 
 // --- Patch ------------------------------------------------------------------
@@ -718,6 +1107,9 @@ func (m *Mind) handlePatchFile(msg string, prefix string) string {
 	if m.patchEng == nil {
 		m.patchEng = patch.NewEngine(m.core)
 	}
+	if m.queue == nil {
+		m.queue = patch.NewQueue(m.core)
+	}
 	if m.phpScan == nil {
 		m.phpScan = brain.NewPHPScanner(m.core)
 	}
@@ -735,6 +1127,19 @@ func (m *Mind) handlePatchFile(msg string, prefix string) string {
 		return m.handlePatchDefault()
 	}
 
+	// "patch <file> with <fixer>" picks a specific fixer; the header fixer
+	// is the default.
+	fixerName := "header"
+	if idx := strings.Index(strings.ToLower(file), " with "); idx != -1 {
+		fixerName = strings.TrimSpace(file[idx+len(" with "):])
+		file = strings.TrimSpace(file[:idx])
+	}
+
+	fixer, ok := patch.FixerByName(fixerName)
+	if !ok {
+		return m.address + ", I don’t have a fixer called \"" + fixerName + "\". Say \"fixers\" to see the ones I know."
+	}
+
 	proj, ok := m.projects.FindByName(m.lastProject)
 	if !ok {
 		return m.address + ", I’ve lost track of the last project. Tell me which one to use again."
@@ -743,37 +1148,40 @@ func (m *Mind) handlePatchFile(msg string, prefix string) string {
 	rel := strings.TrimPrefix(file, "./")
 	rel = strings.TrimPrefix(rel, "/")
 
-	phpReport, err := m.phpScan.AnalyzeProject(proj)
+	unresolved, err := m.phpScan.MissingRequiresIn(proj, rel)
 	if err != nil {
 		m.core.Log.Errorf("patch: PHP scan failed: %v", err)
-		return m.address + ", I tried to scan the PHP for that project, but the scan failed: " + err.Error()
-	}
-
-	var unresolved []brain.MissingRequire
-	if phpReport.MissingRequireCount > 0 {
-		for _, mr := range phpReport.MissingRequires {
-			if mr.File == rel {
-				unresolved = append(unresolved, mr)
-			}
-		}
+		return m.address + ", I tried to scan \"" + rel + "\", but the scan failed: " + err.Error()
 	}
 
-	patched, err := m.patchEng.PatchPHPFile(proj, rel)
+	prop, changed, err := m.patchEng.Propose(proj, rel, fixer)
 	if err != nil {
-		m.core.Log.Errorf("patch: PatchPHPFile failed: %v", err)
+		m.core.Log.Errorf("patch: Propose failed: %v", err)
 		return m.address + ", I couldn’t prepare a patch for " + rel + ": " + err.Error()
 	}
 
-	// Remember last patch.
-	key := proj.Name + ":" + rel
-	m.lastPatch[key] = patched
-	m.lastPatchKey = key
 	m.lastPHPExample = rel
 
+	if !changed {
+		return m.address + ", \"" + rel + "\" doesn’t need the " + fixer.Name() + " fixer; there’s nothing to change."
+	}
+
+	prop, err = m.queue.Add(prop)
+	if err != nil {
+		m.core.Log.Errorf("patch: queue proposal failed: %v", err)
+		return m.address + ", I prepared a patch for " + rel + " but couldn’t queue it: " + err.Error()
+	}
+	m.lastPatchKey = proj.Name + ":" + rel
+	m.brain.Record("proposal", fixer.Name(), proj.Name+":"+rel)
+
 	var b strings.Builder
 
-	b.WriteString(m.address + ", here’s the cleaned version of \"" + rel + "\" for \"" + proj.Name + "\".\n\n")
-	b.WriteString("I’ve prepared this as a proposal only; I have not written it to disk. You’re still in control.\n\n")
+	b.WriteString(m.address + ", here’s the " + fixer.Name() + " proposal for \"" + rel + "\" in \"" + proj.Name + "\".\n\n")
+	b.WriteString("I’ve prepared this as a proposal only; I have not written it to disk. You’re still in control.\n")
+	if !fixer.Standalone() {
+		b.WriteString("This fixer can change runtime behavior, so it’s worth a look at the callers before you apply it.\n")
+	}
+	b.WriteString("\n")
 
 	if len(unresolved) > 0 {
 		b.WriteString("I also noticed unresolved require/include targets in this file:\n")
@@ -785,13 +1193,258 @@ func (m *Mind) handlePatchFile(msg string, prefix string) string {
 			mr := unresolved[i]
 			b.WriteString("- " + mr.File + " → " + mr.Target + "\n")
 		}
-		b.WriteString("You can tell me whether to fix those paths, generate stubs, or leave them alone.\n\n")
+		b.WriteString("Say \"fix paths\" and I’ll propose corrected require lines, \"generate stubs\" for placeholder files, or leave them alone.\n\n")
+	}
+
+	b.WriteString("Proposed file (preview only):\n\n```php\n")
+	b.WriteString(prop.Patched)
+	b.WriteString("\n```\n")
+
+	return b.String()
+}
+
+// --- Require repair ---------------------------------------------------------
+
+// unresolvedRequires returns the current project and the unresolved
+// require/include targets in one of its files. An empty file means the file
+// we last patched.
+func (m *Mind) unresolvedRequires(file string) (core.Project, string, []brain.MissingRequire, string) {
+	if m.projects == nil {
+		m.projects = core.NewProjectRegistry(m.core.Data)
+	}
+	if m.phpScan == nil {
+		m.phpScan = brain.NewPHPScanner(m.core)
+	}
+
+	if m.lastProject == "" {
+		return core.Project{}, "", nil, m.address + ", I don’t know which project you mean yet. For example: analyze chaos-mvc first."
+	}
+
+	proj, ok := m.projects.FindByName(m.lastProject)
+	if !ok {
+		return core.Project{}, "", nil, m.address + ", I’ve lost track of the last project. Tell me which one to use again."
+	}
+
+	rel := strings.TrimPrefix(strings.TrimSpace(file), "./")
+	rel = strings.TrimPrefix(rel, "/")
+	if rel == "" {
+		rel = m.lastPHPExample
+	}
+	if rel == "" {
+		return proj, "", nil, m.address + ", tell me which file to look at. For example: repair index.php."
+	}
+
+	missing, err := m.phpScan.MissingRequiresIn(proj, rel)
+	if err != nil {
+		m.core.Log.Errorf("require repair: PHP scan failed: %v", err)
+		return proj, rel, nil, m.address + ", I tried to scan \"" + rel + "\", but the scan failed: " + err.Error()
+	}
+
+	if len(missing) == 0 {
+		return proj, rel, nil, m.address + ", I don’t see unresolved require/include targets in \"" + rel + "\"."
+	}
+
+	return proj, rel, missing, ""
+}
+
+func (m *Mind) handleRepairRequires(file string) string {
+	if m.patchEng == nil {
+		m.patchEng = patch.NewEngine(m.core)
+	}
+	if m.queue == nil {
+		m.queue = patch.NewQueue(m.core)
+	}
+
+	proj, rel, missing, problem := m.unresolvedRequires(file)
+	if problem != "" {
+		return problem
+	}
+
+	prop, repairs, changed, err := m.patchEng.ProposeRequireRepair(proj, rel, missing)
+	if err != nil {
+		m.core.Log.Errorf("require repair failed: %v", err)
+		return m.address + ", I couldn’t work out repairs for " + rel + ": " + err.Error()
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", here’s what I found for the unresolved requires in \"" + rel + "\":\n")
+	for _, r := range repairs {
+		b.WriteString("- line " + strconv.Itoa(r.Missing.Line) + ": " + r.Missing.Target)
+		if len(r.Candidates) == 0 {
+			b.WriteString(" → no likely candidates; a stub may be the better option\n")
+			continue
+		}
+		names := make([]string, 0, len(r.Candidates))
+		for _, c := range r.Candidates {
+			names = append(names, c.RelPath+" ("+strconv.Itoa(int(c.Score*100))+"%)")
+		}
+		b.WriteString(" → " + strings.Join(names, ", ") + "\n")
 	}
 
+	if !changed {
+		b.WriteString("\nNone of them had a candidate I’d trust, so there’s no rewrite to propose. Say \"generate stubs\" if you want placeholders instead.")
+		return b.String()
+	}
+
+	if _, err := m.queue.Add(prop); err != nil {
+		m.core.Log.Errorf("require repair: queue proposal failed: %v", err)
+		return m.address + ", I prepared a repair for " + rel + " but couldn’t queue it: " + err.Error()
+	}
+	m.lastPatchKey = proj.Name + ":" + rel
+	m.brain.Record("proposal", prop.Fixer, proj.Name+":"+rel)
+
+	b.WriteString("\nI’ve rewritten each repairable line to its top candidate. Nothing is written yet; say \"apply " + rel + "\" to accept it.\n\n")
 	b.WriteString("Proposed file (preview only):\n\n```php\n")
-	b.WriteString(patched)
-	b.WriteString("\n```\\n")
+	b.WriteString(prop.Patched)
+	b.WriteString("\n```\n")
+
+	return b.String()
+}
+
+func (m *Mind) handleStubRequires(file string) string {
+	if m.patchEng == nil {
+		m.patchEng = patch.NewEngine(m.core)
+	}
+	if m.queue == nil {
+		m.queue = patch.NewQueue(m.core)
+	}
+
+	proj, rel, missing, problem := m.unresolvedRequires(file)
+	if problem != "" {
+		return problem
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", here are the stub proposals for the unresolved requires in \"" + rel + "\":\n")
+
+	queued := 0
+	for _, mr := range missing {
+		prop, err := m.patchEng.ProposeStub(proj, mr)
+		if err != nil {
+			b.WriteString("- " + mr.Target + ": skipped (" + err.Error() + ")\n")
+			continue
+		}
+		if _, err := m.queue.Add(prop); err != nil {
+			m.core.Log.Errorf("stub: queue proposal failed: %v", err)
+			b.WriteString("- " + mr.Target + ": couldn’t queue (" + err.Error() + ")\n")
+			continue
+		}
+		m.lastPatchKey = proj.Name + ":" + prop.RelPath
+		m.brain.Record("proposal", prop.Fixer, proj.Name+":"+prop.RelPath)
+		b.WriteString("- new file " + prop.RelPath + "\n")
+		queued++
+	}
+
+	if queued == 0 {
+		b.WriteString("\nThere was nothing I could safely stub.")
+		return b.String()
+	}
+
+	b.WriteString("\nNothing has been created yet. Say \"apply <file>\" for one stub or \"apply all\" for every pending proposal.")
+	return b.String()
+}
+
+// --- Fix --------------------------------------------------------------------
+
+func (m *Mind) handleFix(ctx context.Context, arg string) string {
+	if m.projects == nil {
+		m.projects = core.NewProjectRegistry(m.core.Data)
+	}
+	if m.patchEng == nil {
+		m.patchEng = patch.NewEngine(m.core)
+	}
+	if m.queue == nil {
+		m.queue = patch.NewQueue(m.core)
+	}
+
+	// "fix <project> <fixer>", or "fix <fixer>" for the current project.
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return m.address + ", tell me which project and fixer to use. For example: fix chaos-mvc strict-types."
+	}
+
+	projName := m.lastProject
+	fixerName := fields[len(fields)-1]
+	if len(fields) > 1 {
+		projName = strings.Join(fields[:len(fields)-1], " ")
+	}
 
+	fixer, ok := patch.FixerByName(fixerName)
+	if !ok {
+		return m.address + ", I don’t have a fixer called \"" + fixerName + "\". Say \"fixers\" to see the ones I know."
+	}
+
+	if projName == "" {
+		return m.address + ", I don’t know which project to fix yet. For example: fix chaos-mvc " + fixer.Name() + "."
+	}
+
+	proj, ok := m.projects.FindByName(projName)
+	if !ok {
+		return m.address + ", I don’t see a registered project named \"" + projName + "\"."
+	}
+
+	m.lastProject = proj.Name
+
+	if !patch.Applies(fixer, proj) {
+		return m.address + ", the " + fixer.Name() + " fixer doesn’t apply to \"" + proj.Name + "\" (" + stackLabel(proj) + "), so I left it alone."
+	}
+
+	props, err := m.patchEng.ProposeProject(ctx, proj, fixer)
+	if err != nil {
+		m.core.Log.Errorf("fix: ProposeProject failed: %v", err)
+		return m.address + ", running the " + fixer.Name() + " fixer failed: " + err.Error()
+	}
+
+	if len(props) == 0 {
+		return m.address + ", the " + fixer.Name() + " fixer found nothing to change in \"" + proj.Name + "\"."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", the " + fixer.Name() + " fixer has " + strconv.Itoa(len(props)) + " proposal")
+	if len(props) != 1 {
+		b.WriteString("s")
+	}
+	b.WriteString(" for \"" + proj.Name + "\" (" + fixer.Issue() + "):\n")
+
+	for _, prop := range props {
+		if _, err := m.queue.Add(prop); err != nil {
+			m.core.Log.Errorf("fix: queue proposal failed: %v", err)
+			return m.address + ", I couldn’t queue the proposal for " + prop.RelPath + ": " + err.Error()
+		}
+		m.lastPatchKey = proj.Name + ":" + prop.RelPath
+		m.brain.Record("proposal", fixer.Name(), proj.Name+":"+prop.RelPath)
+		b.WriteString("- " + prop.RelPath + "\n")
+	}
+
+	b.WriteString("\nNothing has been written yet. Say \"apply <file>\" for one, or \"apply all\" to write every pending proposal for this project.")
+	if !fixer.Standalone() {
+		b.WriteString("\nThis fixer can change runtime behavior, so please review before applying.")
+	}
+
+	return b.String()
+}
+
+func (m *Mind) handleFixers() string {
+	// With a current project, mark the fixers its stack rules out.
+	var proj core.Project
+	haveProj := false
+	if m.lastProject != "" {
+		proj, haveProj = m.Projects().FindByName(m.lastProject)
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", these are the fixers I can run:\n")
+	for _, f := range patch.Fixers() {
+		b.WriteString("- " + f.Name() + " (" + string(f.Scope()) + "): " + f.Issue())
+		if !f.Standalone() {
+			b.WriteString("; review before applying")
+		}
+		if haveProj && !patch.Applies(f, proj) {
+			b.WriteString("; not used for " + proj.Name)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nUse \"patch <file> with <fixer>\" for one file or \"fix <project> <fixer>\" for a whole project.")
 	return b.String()
 }
 
@@ -824,6 +1477,10 @@ func (m *Mind) handleApplyFile(msg string, prefix string) string {
 		return m.handleApplyDefault()
 	}
 
+	if strings.EqualFold(file, "all") {
+		return m.applyAllPatches()
+	}
+
 	return m.applyPatchForFile(file)
 }
 
@@ -834,6 +1491,9 @@ func (m *Mind) applyPatchForFile(file string) string {
 	if m.patchEng == nil {
 		m.patchEng = patch.NewEngine(m.core)
 	}
+	if m.queue == nil {
+		m.queue = patch.NewQueue(m.core)
+	}
 
 	if m.lastProject == "" {
 		return m.address + ", I don’t know which project you want this patch applied to. For example: analyze chaos-mvc."
@@ -847,20 +1507,89 @@ func (m *Mind) applyPatchForFile(file string) string {
 	rel := strings.TrimPrefix(file, "./")
 	rel = strings.TrimPrefix(rel, "/")
 
-	key := proj.Name + ":" + rel
-	content, ok := m.lastPatch[key]
+	prop, ok := m.queue.Get(proj.Name, rel)
 	if !ok {
 		return m.address + ", I don’t have a prepared patch stored for \"" + rel + "\" yet. Ask me to patch that file first."
 	}
 
-	if err := m.patchEng.ApplyFile(proj, rel, content); err != nil {
-		m.core.Log.Errorf("apply: ApplyFile failed: %v", err)
+	if err := m.applyProposal(proj, prop); err != nil {
+		m.core.Log.Errorf("apply: ApplyProposal failed: %v", err)
 		return m.address + ", applying that patch failed: " + err.Error()
 	}
 
 	return m.address + ", done. I’ve applied the patch to \"" + rel + "\" in \"" + proj.Name + "\" and saved a .bak backup of the previous version."
 }
 
+func (m *Mind) applyAllPatches() string {
+	if m.projects == nil {
+		m.projects = core.NewProjectRegistry(m.core.Data)
+	}
+	if m.patchEng == nil {
+		m.patchEng = patch.NewEngine(m.core)
+	}
+	if m.queue == nil {
+		m.queue = patch.NewQueue(m.core)
+	}
+
+	if m.lastProject == "" {
+		return m.address + ", I don’t know which project you want these patches applied to. For example: analyze chaos-mvc."
+	}
+
+	proj, ok := m.projects.FindByName(m.lastProject)
+	if !ok {
+		return m.address + ", I’ve lost track of the last project. Tell me which one to use again."
+	}
+
+	pending := m.queue.ForProject(proj.Name)
+	if len(pending) == 0 {
+		return m.address + ", there are no pending proposals for \"" + proj.Name + "\"."
+	}
+
+	applied := 0
+	var failed []string
+	for _, prop := range pending {
+		if err := m.applyProposal(proj, prop); err != nil {
+			m.core.Log.Errorf("apply all: %s: %v", prop.RelPath, err)
+			failed = append(failed, prop.RelPath+" ("+err.Error()+")")
+			continue
+		}
+		applied++
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", I’ve applied " + strconv.Itoa(applied) + " of " + strconv.Itoa(len(pending)) + " pending proposal")
+	if len(pending) != 1 {
+		b.WriteString("s")
+	}
+	b.WriteString(" in \"" + proj.Name + "\", keeping a .bak backup of each file.")
+	if len(failed) > 0 {
+		b.WriteString("\nThese were not applied:\n")
+		for _, f := range failed {
+			b.WriteString("- " + f + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// applyProposal writes one approved proposal, drops it from the queue and
+// logs the result.
+func (m *Mind) applyProposal(proj core.Project, prop patch.Proposal) error {
+	if err := m.patchEng.ApplyProposal(proj, prop); err != nil {
+		return err
+	}
+
+	if err := m.queue.Remove(proj.Name, prop.RelPath); err != nil {
+		m.core.Log.Warnf("apply: dequeue %s: %v", prop.RelPath, err)
+	}
+	if m.lastPatchKey == proj.Name+":"+prop.RelPath {
+		m.lastPatchKey = ""
+	}
+	m.brain.Record("apply", prop.Fixer, proj.Name+":"+prop.RelPath)
+
+	return nil
+}
+
 // --- Tasks ------------------------------------------------------------------
 
 func (m *Mind) handleTaskAdd(text string) string {
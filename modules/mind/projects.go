@@ -0,0 +1,402 @@
+package mind
+
+import (
+	"fmt"
+	"strings"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+)
+
+// --- Project registry lifecycle -------------------------------------------
+
+// Projects exposes the shared project registry so the HTTP API and the chat
+// window see the same state.
+func (m *Mind) Projects() *core.ProjectRegistry {
+	if m.projects == nil {
+		m.projects = core.NewProjectRegistry(m.core.Data)
+	}
+	return m.projects
+}
+
+// RegisterProject registers the directory at path and detects its stack.
+func (m *Mind) RegisterProject(path string) (core.Project, error) {
+	p, err := m.Projects().Register(path)
+	if err != nil {
+		return core.Project{}, err
+	}
+	return m.detectStack(p), nil
+}
+
+// detectStack re-reads the project's manifests and records what it finds.
+// Detection never fails; a registry write error is logged and the project is
+// returned as it was.
+func (m *Mind) detectStack(p core.Project) core.Project {
+	st := brain.DetectStack(p.Path)
+
+	updated, err := m.Projects().SetStack(p.Name, st.Type, st.Languages, st.Frameworks, st.Tooling)
+	if err != nil {
+		m.core.Log.Warnf("detect stack for %s: %v", p.Name, err)
+		return p
+	}
+	return updated
+}
+
+// describeStack summarizes the detected stack in one sentence, or returns ""
+// when nothing was recognized.
+func describeStack(p core.Project) string {
+	if p.Type == "" || p.Type == "unknown" {
+		return ""
+	}
+
+	s := "It looks like a " + p.Type + " project"
+	if len(p.Frameworks) > 0 {
+		s += " using " + joinList(p.Frameworks)
+	}
+	if len(p.Tooling) > 0 {
+		s += ", with " + joinList(p.Tooling)
+	}
+	return s + "."
+}
+
+// splitArg splits "left <sep> right" on the last case-insensitive occurrence
+// of sep, so names may contain the separator word themselves.
+func splitArg(arg, sep string) (string, string, bool) {
+	idx := strings.LastIndex(strings.ToLower(arg), strings.ToLower(sep))
+	if idx == -1 {
+		return strings.TrimSpace(arg), "", false
+	}
+	return strings.TrimSpace(arg[:idx]), strings.TrimSpace(arg[idx+len(sep):]), true
+}
+
+// splitList splits "a, b and c" into its items.
+func splitList(s string) []string {
+	s = strings.ReplaceAll(s, " and ", ",")
+	out := make([]string, 0)
+	for _, part := range strings.Split(s, ",") {
+		for _, f := range strings.Fields(part) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (m *Mind) handleListProjects(arg string) string {
+	reg := m.Projects()
+
+	var list []core.Project
+	var scope string
+
+	lower := strings.ToLower(arg)
+	switch {
+	case strings.HasPrefix(lower, "tagged "):
+		tag := strings.TrimSpace(arg[len("tagged "):])
+		list = reg.ListByTag(tag)
+		scope = " tagged \"" + tag + "\""
+	case strings.HasPrefix(lower, "in group "):
+		group := strings.TrimSpace(arg[len("in group "):])
+		list = reg.ListByGroup(group)
+		scope = " in group \"" + group + "\""
+	case strings.HasPrefix(lower, "in "):
+		group := strings.TrimSpace(arg[len("in "):])
+		list = reg.ListByGroup(group)
+		scope = " in group \"" + group + "\""
+	default:
+		list = reg.List()
+	}
+
+	if len(list) == 0 {
+		return m.address + ", I don’t have any registered projects" + scope + "."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", these are the projects I know" + scope + ":\n")
+	for _, p := range list {
+		b.WriteString("- " + p.Name + " → " + p.Path)
+		if p.Group != "" {
+			b.WriteString(" [" + p.Group + "]")
+		}
+		if len(p.Tags) > 0 {
+			b.WriteString(" #" + strings.Join(p.Tags, " #"))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (m *Mind) handleShowProject(name string) string {
+	if name == "" {
+		name = m.lastProject
+	}
+	if name == "" {
+		return m.address + ", tell me which project to describe. For example: show project chaos-mvc."
+	}
+
+	p, ok := m.Projects().FindByName(name)
+	if !ok {
+		return m.address + ", I don’t see a registered project named \"" + name + "\"."
+	}
+
+	m.lastProject = p.Name
+
+	var b strings.Builder
+	b.WriteString(m.address + ", here’s what I have on record for \"" + p.Name + "\".\n\n")
+	b.WriteString("Path: " + p.Path + "\n")
+	b.WriteString("Type: " + p.Type + "\n")
+	if len(p.Languages) > 0 {
+		b.WriteString("Languages: " + strings.Join(p.Languages, ", ") + "\n")
+	}
+	if len(p.Frameworks) > 0 {
+		b.WriteString("Frameworks: " + strings.Join(p.Frameworks, ", ") + "\n")
+	}
+	if len(p.Tooling) > 0 {
+		b.WriteString("Tooling: " + strings.Join(p.Tooling, ", ") + "\n")
+	}
+	if len(p.Aliases) > 0 {
+		b.WriteString("Aliases: " + strings.Join(p.Aliases, ", ") + "\n")
+	}
+	if len(p.Tags) > 0 {
+		b.WriteString("Tags: " + strings.Join(p.Tags, ", ") + "\n")
+	}
+	if p.Group != "" {
+		b.WriteString("Group: " + p.Group + "\n")
+	}
+	if p.Description != "" {
+		b.WriteString("Description: " + p.Description + "\n")
+	}
+	if p.Owner != "" {
+		b.WriteString("Owner: " + p.Owner + "\n")
+	}
+	if p.DefaultBranch != "" {
+		b.WriteString("Default branch: " + p.DefaultBranch + "\n")
+	}
+	if p.Standards != "" {
+		b.WriteString("Standards profile: " + p.Standards + "\n")
+	}
+	b.WriteString("Registered: " + p.RegisteredAt + "\n")
+
+	return b.String()
+}
+
+func (m *Mind) handleUnregisterProject(name string) string {
+	if name == "" {
+		return m.address + ", tell me which project to unregister."
+	}
+
+	p, err := m.unregisterProject(name)
+	if err != nil {
+		return m.projectError("unregister", err)
+	}
+
+	return m.address + ", I’ve unregistered \"" + p.Name + "\". Nothing at " + p.Path + " was touched, and any pending proposals for it were discarded."
+}
+
+func (m *Mind) handleRenameProject(arg string) string {
+	name, newName, ok := splitArg(arg, " to ")
+	if !ok || name == "" || newName == "" {
+		return m.address + ", tell me the project and its new name. For example: rename project app to billing-app."
+	}
+
+	prev, found := m.Projects().FindByName(name)
+	if !found {
+		return m.address + ", I don’t see a registered project named \"" + name + "\"."
+	}
+
+	p, err := m.renameProject(prev.Name, newName)
+	if err != nil {
+		return m.projectError("rename", err)
+	}
+
+	return m.address + ", \"" + prev.Name + "\" is now registered as \"" + p.Name + "\"."
+}
+
+// UnregisterProject removes a project from the registry and drops what
+// RictusD keeps under its name: pending proposals, the index, map snapshots
+// and the cached router. Files in the project are untouched.
+func (m *Mind) UnregisterProject(name string) (core.Project, error) {
+	m.chatMu.Lock()
+	defer m.chatMu.Unlock()
+	return m.unregisterProject(name)
+}
+
+// unregisterProject is UnregisterProject; callers hold chatMu.
+func (m *Mind) unregisterProject(name string) (core.Project, error) {
+	p, err := m.Projects().Unregister(name)
+	if err != nil {
+		return core.Project{}, err
+	}
+
+	if m.queue != nil {
+		if err := m.queue.DropProject(p.Name); err != nil {
+			m.core.Log.Warnf("unregister: drop proposals for %s: %v", p.Name, err)
+		}
+	}
+	if err := brain.NewIndexer(m.core).Forget(p.Name); err != nil {
+		m.core.Log.Warnf("unregister: %v", err)
+	}
+	if err := brain.NewMapper(m.core).ForgetSnapshots(p.Name); err != nil {
+		m.core.Log.Warnf("unregister: %v", err)
+	}
+	delete(m.routerCache, p.Name)
+	m.eachSession(func(s *session) { s.forget(p.Name) })
+	m.brain.Record("project", "unregister", p.Name+" "+p.Path)
+
+	return p, nil
+}
+
+// RenameProject gives a project a new name and carries everything RictusD
+// keeps under the old one over to it: pending proposals, the index, map
+// snapshots, the cached router and the conversations' current project.
+func (m *Mind) RenameProject(name, newName string) (core.Project, error) {
+	m.chatMu.Lock()
+	defer m.chatMu.Unlock()
+	return m.renameProject(name, newName)
+}
+
+// renameProject is RenameProject; callers hold chatMu.
+func (m *Mind) renameProject(name, newName string) (core.Project, error) {
+	prev, ok := m.Projects().FindByName(name)
+	if !ok {
+		return core.Project{}, fmt.Errorf("%w: %s", core.ErrProjectNotFound, name)
+	}
+	oldName := prev.Name
+
+	p, err := m.Projects().Rename(oldName, newName)
+	if err != nil {
+		return core.Project{}, err
+	}
+	if p.Name == oldName {
+		return p, nil
+	}
+
+	if rel, ok := m.routerCache[oldName]; ok {
+		delete(m.routerCache, oldName)
+		m.routerCache[p.Name] = rel
+	}
+	if err := brain.NewIndexer(m.core).Rename(oldName, p.Name); err != nil {
+		m.core.Log.Warnf("rename: %v", err)
+	}
+	if err := brain.NewMapper(m.core).RenameSnapshots(oldName, p.Name); err != nil {
+		m.core.Log.Warnf("rename: %v", err)
+	}
+	if m.queue != nil {
+		if err := m.queue.RenameProject(oldName, p.Name); err != nil {
+			m.core.Log.Warnf("rename: move proposals for %s: %v", oldName, err)
+		}
+	}
+	m.eachSession(func(s *session) { s.rename(oldName, p.Name) })
+	m.brain.Record("project", "rename", oldName+" -> "+p.Name)
+
+	return p, nil
+}
+
+func (m *Mind) handleAliasProject(arg string, remove bool) string {
+	name, alias, ok := splitArg(arg, " as ")
+	if !ok || name == "" || alias == "" {
+		return m.address + ", tell me the project and the alias. For example: alias project chaos-mvc as chaos."
+	}
+
+	var p core.Project
+	var err error
+	if remove {
+		p, err = m.Projects().RemoveAlias(name, alias)
+	} else {
+		p, err = m.Projects().AddAlias(name, alias)
+	}
+	if err != nil {
+		return m.projectError("alias", err)
+	}
+
+	if remove {
+		return m.address + ", \"" + alias + "\" no longer refers to \"" + p.Name + "\"."
+	}
+	return m.address + ", \"" + p.Name + "\" can now also be called \"" + alias + "\"."
+}
+
+func (m *Mind) handleTagProject(arg string, remove bool) string {
+	name, rest, ok := splitArg(arg, " with ")
+	tags := splitList(rest)
+	if !ok || name == "" || len(tags) == 0 {
+		return m.address + ", tell me the project and the tags. For example: tag project chaos-mvc with php, client."
+	}
+
+	var p core.Project
+	var err error
+	if remove {
+		p, err = m.Projects().RemoveTags(name, tags...)
+	} else {
+		p, err = m.Projects().AddTags(name, tags...)
+	}
+	if err != nil {
+		return m.projectError("tag", err)
+	}
+
+	if len(p.Tags) == 0 {
+		return m.address + ", \"" + p.Name + "\" has no tags now."
+	}
+	return m.address + ", \"" + p.Name + "\" is now tagged " + strings.Join(p.Tags, ", ") + "."
+}
+
+func (m *Mind) handleGroupProject(arg string) string {
+	name, group, ok := splitArg(arg, " as ")
+	if !ok || name == "" {
+		return m.address + ", tell me the project and the group. For example: group project chaos-mvc as clients."
+	}
+
+	p, err := m.Projects().SetGroup(name, group)
+	if err != nil {
+		return m.projectError("group", err)
+	}
+
+	if p.Group == "" {
+		return m.address + ", \"" + p.Name + "\" is no longer in a group."
+	}
+	return m.address + ", \"" + p.Name + "\" is now in the \"" + p.Group + "\" group."
+}
+
+func (m *Mind) handleRelocateProject(arg string) string {
+	name, path, ok := splitArg(arg, " to ")
+	if !ok || name == "" || path == "" {
+		return m.address + ", tell me the project and its new path. For example: relocate project chaos-mvc to /srv/chaos-mvc."
+	}
+
+	p, err := m.Projects().Relocate(name, path)
+	if err != nil {
+		return m.projectError("relocate", err)
+	}
+
+	delete(m.routerCache, p.Name)
+	m.brain.Record("project", "relocate", p.Name+" -> "+p.Path)
+
+	return m.address + ", I’ll look for \"" + p.Name + "\" at " + p.Path + " from now on."
+}
+
+func (m *Mind) handleSetProjectMeta(arg string) string {
+	head, value, ok := splitArg(arg, " to ")
+	fields := strings.Fields(head)
+	if !ok || len(fields) < 2 {
+		return m.address + ", tell me the project, the field and the value. For example: set project chaos-mvc owner to Mei. Fields: " + strings.Join(core.ProjectMetaKeys, ", ") + "."
+	}
+
+	name := strings.Join(fields[:len(fields)-1], " ")
+	key := fields[len(fields)-1]
+
+	// Allow two-word field names such as "default branch".
+	if len(fields) > 2 && strings.EqualFold(fields[len(fields)-2], "default") {
+		name = strings.Join(fields[:len(fields)-2], " ")
+		key = "default_branch"
+	}
+
+	p, err := m.Projects().SetMeta(name, key, value)
+	if err != nil {
+		return m.projectError("set", err)
+	}
+
+	return m.address + ", updated " + key + " for \"" + p.Name + "\"."
+}
+
+// projectError turns a registry error into a reply and logs it.
+func (m *Mind) projectError(action string, err error) string {
+	m.core.Log.Errorf("project %s failed: %v", action, err)
+	return m.address + ", I couldn’t " + action + " that project: " + err.Error()
+}
@@ -0,0 +1,82 @@
+package mind
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/brain"
+)
+
+// routesShown caps how many routes a reply lists.
+const routesShown = 30
+
+// --- Route table ------------------------------------------------------------
+
+func (m *Mind) handleRoutes(ctx context.Context, arg string) string {
+	proj, fail := m.projectArg(arg, "routes")
+	if fail != "" {
+		return fail
+	}
+
+	cfg := m.projectConfig(proj)
+	files := routerCandidates(proj, cfg)
+	if rel, ok := m.cachedRouter(proj, cfg); ok {
+		files = append([]string{rel}, files...)
+	}
+
+	table, err := m.phpScan.Routes(ctx, proj, files)
+	if err != nil {
+		m.core.Log.Errorf("routes %s failed: %v", proj.Name, err)
+		return m.address + ", I couldn’t read the routes of \"" + proj.Name + "\": " + err.Error()
+	}
+	if len(table.Routes) == 0 {
+		return m.address + ", I didn’t find any routes in \"" + proj.Name + "\". I looked in " + joinList(files) + "."
+	}
+
+	m.brain.Record("routes", proj.Name, strconv.Itoa(len(table.Routes))+" routes, "+strconv.Itoa(table.Problems)+" problems")
+
+	var b strings.Builder
+	b.WriteString(m.address + ", \"" + proj.Name + "\" defines " + plural(len(table.Routes), "route", "routes") +
+		" in " + joinList(table.Files))
+	if table.Problems > 0 {
+		b.WriteString(", and " + plural(table.Problems, "handler doesn’t", "handlers don’t") + " resolve")
+	}
+	b.WriteString(":\n")
+
+	for i, r := range table.Routes {
+		if i == routesShown {
+			b.WriteString("- …and " + strconv.Itoa(len(table.Routes)-routesShown) + " more\n")
+			break
+		}
+		b.WriteString("- " + routeLine(r, len(table.Files) > 1) + "\n")
+	}
+
+	if table.Problems > 0 && len(table.Routes) > routesShown {
+		b.WriteString("\nUnresolved handlers:\n")
+		for _, r := range table.Routes {
+			if r.Problem != "" {
+				b.WriteString("- " + r.File + ":" + strconv.Itoa(r.Line) + ": " + r.Problem + "\n")
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// routeLine renders one route, naming its router file only when the table
+// spans several.
+func routeLine(r brain.Route, withFile bool) string {
+	handler := r.Handler
+	if handler == "" {
+		handler = "no handler I can name"
+	}
+	loc := "line " + strconv.Itoa(r.Line)
+	if withFile {
+		loc = r.File + ":" + strconv.Itoa(r.Line)
+	}
+	line := r.Method + " " + r.Path + " → " + handler + " (" + loc + ")"
+	if r.Problem != "" {
+		line += " — " + r.Problem
+	}
+	return line
+}
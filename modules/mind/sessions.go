@@ -0,0 +1,121 @@
+package mind
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"rictusd/modules/brain"
+)
+
+// maxSessions caps the saved conversations; the least recently used go
+// first.
+const maxSessions = 256
+
+// --- Sessions ---------------------------------------------------------------
+
+// session is the conversation state a chat builds up: the project "apply"
+// and "patch" refer to, the last proposal, and the last discover results.
+type session struct {
+	lastProject    string
+	lastPHPExample string
+	lastPatchKey   string
+	discovered     []brain.Discovered
+	used           time.Time
+}
+
+// ChatSession answers a message in a conversation of its own rather than
+// the daemon's main one. It continues from the state saved under from, or
+// from a fresh state when from is empty or unknown, and saves the state it
+// ends in under to. from is left as it was, so a client can retry a turn.
+// The turn is logged under client, not in the main conversation log.
+func (m *Mind) ChatSession(ctx context.Context, client, from, to, message string) string {
+	m.chatMu.Lock()
+	defer m.chatMu.Unlock()
+
+	m.parked = m.saveSession()
+	m.client = client
+	defer func() {
+		m.loadSession(m.parked)
+		m.parked = nil
+		m.client = ""
+	}()
+
+	start := &session{}
+	if s, ok := m.sessions[from]; ok && from != "" {
+		start = s
+	}
+	m.loadSession(start)
+
+	reply := m.chat(ctx, message)
+
+	m.sessions[to] = m.saveSession()
+	m.evictSessions()
+	return reply
+}
+
+func (m *Mind) saveSession() *session {
+	return &session{
+		lastProject:    m.lastProject,
+		lastPHPExample: m.lastPHPExample,
+		lastPatchKey:   m.lastPatchKey,
+		discovered:     m.discovered,
+		used:           time.Now(),
+	}
+}
+
+func (m *Mind) loadSession(s *session) {
+	m.lastProject = s.lastProject
+	m.lastPHPExample = s.lastPHPExample
+	m.lastPatchKey = s.lastPatchKey
+	m.discovered = s.discovered
+}
+
+// eachSession applies fn to every conversation state: the current one, the
+// main one while a session has it parked, and the saved ones. Callers hold
+// chatMu.
+func (m *Mind) eachSession(fn func(s *session)) {
+	cur := m.saveSession()
+	fn(cur)
+	m.loadSession(cur)
+
+	if m.parked != nil {
+		fn(m.parked)
+	}
+	for _, s := range m.sessions {
+		fn(s)
+	}
+}
+
+// rename points the state at a project's new name.
+func (s *session) rename(oldName, newName string) {
+	if strings.EqualFold(s.lastProject, oldName) {
+		s.lastProject = newName
+	}
+	if proj, rel, ok := strings.Cut(s.lastPatchKey, ":"); ok && strings.EqualFold(proj, oldName) {
+		s.lastPatchKey = newName + ":" + rel
+	}
+}
+
+// forget drops references to a project that is no longer registered.
+func (s *session) forget(name string) {
+	if strings.EqualFold(s.lastProject, name) {
+		s.lastProject = ""
+	}
+	if proj, _, ok := strings.Cut(s.lastPatchKey, ":"); ok && strings.EqualFold(proj, name) {
+		s.lastPatchKey = ""
+	}
+}
+
+// evictSessions drops the least recently used sessions over maxSessions.
+func (m *Mind) evictSessions() {
+	for len(m.sessions) > maxSessions {
+		oldest := ""
+		for key, s := range m.sessions {
+			if oldest == "" || s.used.Before(m.sessions[oldest].used) {
+				oldest = key
+			}
+		}
+		delete(m.sessions, oldest)
+	}
+}
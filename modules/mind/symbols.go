@@ -0,0 +1,221 @@
+package mind
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+)
+
+// symbolHitsShown caps how many definitions or uses a reply lists.
+const symbolHitsShown = 20
+
+// --- Symbols ----------------------------------------------------------------
+
+// symbolScope works out which projects a symbol command searches: the one
+// named after " in ", else the last project, else every registered project.
+func (m *Mind) symbolScope(arg string) (string, []core.Project, string) {
+	subject, name, explicit := splitArg(arg, " in ")
+	if !explicit {
+		subject = strings.TrimSpace(arg)
+		name = m.lastProject
+	}
+
+	if name == "" {
+		return subject, m.Projects().List(), ""
+	}
+	proj, ok := m.Projects().FindByName(name)
+	if !ok {
+		return subject, nil, m.address + ", I don’t see a registered project named \"" + name + "\"."
+	}
+	m.lastProject = proj.Name
+	return subject, []core.Project{proj}, ""
+}
+
+// symbolIndexes refreshes and returns the index of each project.
+func (m *Mind) symbolIndexes(ctx context.Context, projects []core.Project) ([]*brain.ProjectIndex, error) {
+	ix := brain.NewIndexer(m.core)
+	out := make([]*brain.ProjectIndex, 0, len(projects))
+	for _, p := range projects {
+		idx, _, err := ix.RefreshContext(ctx, p, nil)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, idx)
+	}
+	return out, nil
+}
+
+// scopeLabel names what was searched, for replies.
+func scopeLabel(projects []core.Project) string {
+	if len(projects) == 1 {
+		return "\"" + projects[0].Name + "\""
+	}
+	return plural(len(projects), "project", "projects")
+}
+
+// hitLocation renders "file:line", prefixed with the project when several
+// were searched.
+func hitLocation(idx *brain.ProjectIndex, many bool, file string, line int) string {
+	loc := file + ":" + strconv.Itoa(line)
+	if many {
+		loc = idx.Project + ": " + loc
+	}
+	return loc
+}
+
+func (m *Mind) handleWhereIs(ctx context.Context, arg string) string {
+	symbol, projects, fail := m.symbolScope(arg)
+	if fail != "" {
+		return fail
+	}
+	if symbol == "" {
+		return m.address + ", tell me which symbol to look for. For example: where is UserController."
+	}
+	if len(projects) == 0 {
+		return m.address + ", there are no registered projects to search yet."
+	}
+
+	indexes, err := m.symbolIndexes(ctx, projects)
+	if err != nil {
+		m.core.Log.Errorf("where is %s failed: %v", symbol, err)
+		return m.address + ", I couldn’t index the project: " + err.Error()
+	}
+
+	var lines []string
+	for _, idx := range indexes {
+		for _, h := range idx.FindSymbol(symbol) {
+			lines = append(lines, "- "+h.Kind+" "+h.FQN+" — "+hitLocation(idx, len(indexes) > 1, h.File, h.Line))
+		}
+	}
+	if len(lines) == 0 {
+		return m.address + ", I don’t see a definition of \"" + symbol + "\" in " + scopeLabel(projects) + "."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", \"" + symbol + "\" is defined in " + scopeLabel(projects) + " at:\n")
+	writeCapped(&b, lines)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (m *Mind) handleWhoUses(ctx context.Context, arg string) string {
+	symbol, projects, fail := m.symbolScope(arg)
+	if fail != "" {
+		return fail
+	}
+	if symbol == "" {
+		return m.address + ", tell me which symbol to look for. For example: who uses render_view."
+	}
+	if len(projects) == 0 {
+		return m.address + ", there are no registered projects to search yet."
+	}
+
+	indexes, err := m.symbolIndexes(ctx, projects)
+	if err != nil {
+		m.core.Log.Errorf("who uses %s failed: %v", symbol, err)
+		return m.address + ", I couldn’t index the project: " + err.Error()
+	}
+
+	var lines []string
+	loose := 0
+	for _, idx := range indexes {
+		for _, h := range idx.FindRefs(symbol) {
+			line := "- " + hitLocation(idx, len(indexes) > 1, h.File, h.Line) + " (" + h.Kind + " " + h.Name + ")"
+			if h.Loose {
+				line += " — method call, class unknown"
+				loose++
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return m.address + ", nothing in " + scopeLabel(projects) + " seems to use \"" + symbol + "\"."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", I found " + plural(len(lines), "use", "uses") + " of \"" + symbol + "\" in " + scopeLabel(projects) + ":\n")
+	writeCapped(&b, lines)
+	if loose > 0 {
+		b.WriteString("\n" + plural(loose, "of them is a method call", "of them are method calls") + " on an object whose class I can’t tell statically.")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (m *Mind) handleOutline(ctx context.Context, arg string) string {
+	file, projects, fail := m.symbolScope(arg)
+	if fail != "" {
+		return fail
+	}
+	if file == "" {
+		return m.address + ", tell me which file to outline. For example: outline app/Http/UserController.php."
+	}
+	if len(projects) != 1 {
+		return m.address + ", which project is \"" + file + "\" in? For example: outline " + file + " in chaos-mvc."
+	}
+	proj := projects[0]
+
+	indexes, err := m.symbolIndexes(ctx, projects)
+	if err != nil {
+		m.core.Log.Errorf("outline %s failed: %v", file, err)
+		return m.address + ", I couldn’t index the project: " + err.Error()
+	}
+	idx := indexes[0]
+
+	matches := idx.FindFile(file)
+	switch len(matches) {
+	case 0:
+		return m.address + ", I don’t see \"" + file + "\" in \"" + proj.Name + "\"."
+	case 1:
+	default:
+		return m.address + ", \"" + file + "\" matches several files: " + joinList(matches) + ". Which one?"
+	}
+
+	e, ok := idx.Outline(matches[0])
+	if !ok {
+		return m.address + ", \"" + matches[0] + "\" isn’t a PHP file, so I have no outline for it."
+	}
+	if len(e.PHP.Symbols) == 0 && len(e.PHP.Uses) == 0 {
+		return m.address + ", \"" + e.Path + "\" doesn’t declare anything; it’s a plain script."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.address + ", here’s the outline of \"" + e.Path + "\".\n")
+
+	if len(e.PHP.Uses) > 0 {
+		names := make([]string, 0, len(e.PHP.Uses))
+		for _, u := range e.PHP.Uses {
+			name := u.Name
+			if u.Alias != u.Name[strings.LastIndex(u.Name, `\`)+1:] {
+				name += " as " + u.Alias
+			}
+			if u.Kind != "class" {
+				name = u.Kind + " " + name
+			}
+			names = append(names, name)
+		}
+		b.WriteString("Imports: " + strings.Join(names, ", ") + ".\n")
+	}
+
+	b.WriteString("\n")
+	for _, s := range e.PHP.Symbols {
+		indent := ""
+		if s.Parent != "" {
+			indent = "    "
+		}
+		b.WriteString(indent + "- " + s.Kind + " " + s.Name + " (line " + strconv.Itoa(s.Line) + ")\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeCapped writes up to symbolHitsShown lines and says how many were left.
+func writeCapped(b *strings.Builder, lines []string) {
+	for i, line := range lines {
+		if i == symbolHitsShown {
+			b.WriteString("- …and " + strconv.Itoa(len(lines)-symbolHitsShown) + " more\n")
+			break
+		}
+		b.WriteString(line + "\n")
+	}
+}
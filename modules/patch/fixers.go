@@ -0,0 +1,388 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+)
+
+// Scope describes how much of a project a fixer needs to see.
+type Scope string
+
+const (
+	// ScopeHeader fixers only touch the top of a file.
+	ScopeHeader Scope = "header"
+	// ScopeFile fixers may touch anything within a single file.
+	ScopeFile Scope = "file"
+	// ScopeProject fixers read other project files to decide what to change.
+	ScopeProject Scope = "project"
+)
+
+// Target is the file a fixer works on.
+type Target struct {
+	ProjectName string
+	ProjectPath string
+	RelPath     string
+	Content     string
+	Config      core.ProjectConfig // the project's .rictus settings
+}
+
+// Fixer is a single rule-based PHP codemod. Fix returns the new content;
+// returning the input unchanged means there was nothing to do.
+type Fixer interface {
+	Name() string
+	Issue() string
+	Scope() Scope
+	// Standalone reports whether the change is safe to apply on its own,
+	// without reviewing behavior elsewhere in the project.
+	Standalone() bool
+	Fix(t Target) (string, error)
+}
+
+// frameworkAware is implemented by fixers that only make sense for some
+// project stacks.
+type frameworkAware interface {
+	AppliesTo(p core.Project) bool
+}
+
+// Applies reports whether a fixer should run on a project. Every fixer is a
+// PHP fixer, so projects detected without PHP are skipped; fixers may narrow
+// this further by implementing AppliesTo.
+func Applies(f Fixer, p core.Project) bool {
+	if !p.HasLanguage("php") {
+		return false
+	}
+	if fa, ok := f.(frameworkAware); ok {
+		return fa.AppliesTo(p)
+	}
+	return true
+}
+
+// autoloaded reports whether a project loads its classes through a
+// framework's autoloader, where hand-written require paths are rare and
+// rewriting them is more likely to break bootstrapping than fix it.
+func autoloaded(p core.Project) bool {
+	return p.HasFramework("laravel") || p.HasFramework("symfony")
+}
+
+// fixers is the built-in set, in the order they are listed to the user.
+var fixers = []Fixer{
+	headerFixer{},
+	strictTypesFixer{},
+	openTagFixer{},
+	closeTagFixer{},
+	requirePathsFixer{},
+	requireOnceFixer{},
+}
+
+// Fixers returns all built-in fixers.
+func Fixers() []Fixer {
+	out := make([]Fixer, len(fixers))
+	copy(out, fixers)
+	return out
+}
+
+// FixerByName looks up a fixer by name (case-insensitive).
+func FixerByName(name string) (Fixer, bool) {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	for _, f := range fixers {
+		if f.Name() == lower {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// --- header / strict-types --------------------------------------------------
+
+type headerFixer struct{}
+
+func (headerFixer) Name() string     { return "header" }
+func (headerFixer) Issue() string    { return "missing declare(strict_types=1) or file docblock" }
+func (headerFixer) Scope() Scope     { return ScopeHeader }
+func (headerFixer) Standalone() bool { return true }
+
+// Fix adds whichever of the two the project's standards profile requires.
+func (headerFixer) Fix(t Target) (string, error) {
+	return patchPHPHeader(t.Content, t.RelPath, t.ProjectName, t.Config.RequireStrict(), t.Config.RequireDocblock()), nil
+}
+
+type strictTypesFixer struct{}
+
+func (strictTypesFixer) Name() string  { return "strict-types" }
+func (strictTypesFixer) Issue() string { return "missing declare(strict_types=1)" }
+func (strictTypesFixer) Scope() Scope  { return ScopeHeader }
+
+// Standalone is false: strict typing can turn silent coercions into
+// TypeErrors, so call sites deserve a look before this lands.
+func (strictTypesFixer) Standalone() bool { return false }
+
+// Fix leaves files alone when the project targets a PHP version without
+// strict_types.
+func (strictTypesFixer) Fix(t Target) (string, error) {
+	return patchPHPHeader(t.Content, t.RelPath, t.ProjectName, t.Config.SupportsStrictTypes(), false), nil
+}
+
+// --- open-tag ---------------------------------------------------------------
+
+type openTagFixer struct{}
+
+func (openTagFixer) Name() string     { return "open-tag" }
+func (openTagFixer) Issue() string    { return "short, mis-cased or late opening tag" }
+func (openTagFixer) Scope() Scope     { return ScopeHeader }
+func (openTagFixer) Standalone() bool { return true }
+
+// Fix removes whitespace before the first opening tag, lowercases <?PHP and
+// expands short <? tags. Only real opening tags are rewritten, never text in
+// strings, comments or inline HTML; <?= echo tags and <?xml prologs are left
+// alone.
+func (openTagFixer) Fix(t Target) (string, error) {
+	src := t.Content
+
+	bom := ""
+	if strings.HasPrefix(src, "\ufeff") {
+		bom, src = "\ufeff", src[len("\ufeff"):]
+	}
+
+	if trimmed := strings.TrimLeft(src, " \t\r\n"); strings.HasPrefix(trimmed, "<?") {
+		src = trimmed
+	}
+
+	var b strings.Builder
+	end := 0
+	for _, tok := range brain.LexPHP([]byte(src)) {
+		end += len(tok.Text)
+		short := tok.Text == "<?" || (len(tok.Text) == 5 && tok.Text != "<?php")
+		if tok.Kind == brain.PHPOpenTag && short && end < len(src) && isSpaceByte(src[end]) {
+			b.WriteString("<?php")
+			continue
+		}
+		b.WriteString(tok.Text)
+	}
+
+	return bom + b.String(), nil
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// --- close-tag --------------------------------------------------------------
+
+type closeTagFixer struct{}
+
+func (closeTagFixer) Name() string  { return "close-tag" }
+func (closeTagFixer) Issue() string { return "trailing ?> that can leak whitespace into output" }
+func (closeTagFixer) Scope() Scope  { return ScopeFile }
+
+// Standalone is false: the closing tag also ends the last statement, so the
+// change reaches past the tag itself.
+func (closeTagFixer) Standalone() bool { return false }
+
+// Fix drops a final ?> from pure-PHP files: one opening tag at the top and
+// nothing but whitespace after the closing tag. When the last statement
+// relied on ?> to end it, a semicolon takes the tag's place.
+func (closeTagFixer) Fix(t Target) (string, error) {
+	src := t.Content
+	toks := phpTokens(src)
+
+	opens, closeAt := 0, -1
+	for i, tok := range toks {
+		switch tok.Kind {
+		case brain.PHPOpenTag:
+			if !strings.EqualFold(tok.Text, "<?php") {
+				return src, nil
+			}
+			opens++
+		case brain.PHPCloseTag:
+			if closeAt >= 0 {
+				return src, nil
+			}
+			closeAt = i
+		case brain.PHPInlineHTML:
+			if strings.TrimSpace(strings.TrimPrefix(tok.Text, "\ufeff")) != "" {
+				return src, nil
+			}
+		}
+	}
+	if opens != 1 || closeAt < 0 {
+		return src, nil
+	}
+
+	nl := "\n"
+	if strings.Contains(src, "\r\n") {
+		nl = "\r\n"
+	}
+
+	body := strings.TrimRight(src[:toks[closeAt].off], " \t\r\n")
+	if last := lastCode(toks[:closeAt]); last.Kind != brain.PHPOpenTag && !last.Is(";") && !last.Is("}") {
+		body += ";"
+	}
+	return body + nl, nil
+}
+
+// --- require paths ----------------------------------------------------------
+
+// offsetToken is a lexed PHP token and the byte offset it starts at.
+type offsetToken struct {
+	brain.PHPToken
+	off int
+}
+
+// phpTokens lexes src, keeping each token's offset so fixers can splice the
+// source without touching strings, comments or inline HTML.
+func phpTokens(src string) []offsetToken {
+	toks := brain.LexPHP([]byte(src))
+	out := make([]offsetToken, len(toks))
+	off := 0
+	for i, tok := range toks {
+		out[i] = offsetToken{tok, off}
+		off += len(tok.Text)
+	}
+	return out
+}
+
+// lastCode returns the last token in toks that carries code.
+func lastCode(toks []offsetToken) offsetToken {
+	for i := len(toks) - 1; i >= 0; i-- {
+		if !toks[i].Trivia() && toks[i].Kind != brain.PHPInlineHTML {
+			return toks[i]
+		}
+	}
+	return offsetToken{}
+}
+
+// nextCode returns the index of the first code token at or after i, or
+// len(toks).
+func nextCode(toks []offsetToken, i int) int {
+	for i < len(toks) && (toks[i].Trivia() || toks[i].Kind == brain.PHPInlineHTML) {
+		i++
+	}
+	return i
+}
+
+// requireKeywords are the statements that load another file.
+var requireKeywords = map[string]bool{"require": true, "require_once": true, "include": true, "include_once": true}
+
+// requireAt reports whether toks[i] is a require or include keyword that
+// starts a statement, as opposed to one used as an expression such as
+// `$cfg = require 'config.php';`.
+func requireAt(toks []offsetToken, i int) bool {
+	if toks[i].Kind != brain.PHPName || !requireKeywords[strings.ToLower(toks[i].Text)] {
+		return false
+	}
+	prev := lastCode(toks[:i])
+	return prev.Kind == brain.PHPOpenTag || prev.Is(";") || prev.Is("{") || prev.Is("}") || prev.Is(":")
+}
+
+// requireTarget matches the rest of a require statement whose target is a
+// single string literal, e.g. ` 'lib/db.php';` or `("/app/x.php");`. It
+// returns the literal's value and the index of the closing semicolon.
+func requireTarget(toks []offsetToken, i int) (string, int, bool) {
+	j := nextCode(toks, i+1)
+	paren := j < len(toks) && toks[j].Is("(")
+	if paren {
+		j = nextCode(toks, j+1)
+	}
+	if j >= len(toks) || toks[j].Kind != brain.PHPString || toks[j].Text[0] == '<' {
+		return "", 0, false
+	}
+	target, _ := toks[j].StringValue()
+	j = nextCode(toks, j+1)
+	if paren {
+		if j >= len(toks) || !toks[j].Is(")") {
+			return "", 0, false
+		}
+		j = nextCode(toks, j+1)
+	}
+	if j >= len(toks) || !toks[j].Is(";") || target == "" {
+		return "", 0, false
+	}
+	return target, j, true
+}
+
+type requirePathsFixer struct{}
+
+func (requirePathsFixer) Name() string { return "require-paths" }
+func (requirePathsFixer) Issue() string {
+	return "require/include paths that depend on the include path or working directory"
+}
+func (requirePathsFixer) Scope() Scope     { return ScopeProject }
+func (requirePathsFixer) Standalone() bool { return true }
+
+func (requirePathsFixer) AppliesTo(p core.Project) bool { return !autoloaded(p) }
+
+// Fix anchors bare string targets to __DIR__. A target is rewritten only when
+// it resolves to a real file, either next to the including file or, for a
+// leading slash, from the project root. Unresolved targets are left alone.
+func (requirePathsFixer) Fix(t Target) (string, error) {
+	fileDir := filepath.Dir(filepath.Join(t.ProjectPath, t.RelPath))
+	toks := phpTokens(t.Content)
+
+	var b strings.Builder
+	pos, next := 0, 0
+	for i := range toks {
+		if i < next || !requireAt(toks, i) {
+			continue
+		}
+		target, semi, ok := requireTarget(toks, i)
+		if !ok {
+			continue
+		}
+
+		var abs string
+		if strings.HasPrefix(target, "/") {
+			abs = filepath.Join(t.ProjectPath, target)
+		} else {
+			abs = filepath.Join(fileDir, target)
+		}
+		if st, err := os.Stat(abs); err != nil || st.IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(fileDir, abs)
+		if err != nil {
+			continue
+		}
+
+		b.WriteString(t.Content[pos:toks[i].off])
+		b.WriteString(toks[i].Text + " __DIR__ . '/" + filepath.ToSlash(rel) + "';")
+		pos = toks[semi].off + 1
+		next = semi + 1
+	}
+	b.WriteString(t.Content[pos:])
+
+	return b.String(), nil
+}
+
+// --- require_once -----------------------------------------------------------
+
+type requireOnceFixer struct{}
+
+func (requireOnceFixer) Name() string  { return "require-once" }
+func (requireOnceFixer) Issue() string { return "require/include that can load the same file twice" }
+func (requireOnceFixer) Scope() Scope  { return ScopeFile }
+
+// Standalone is false: a file included on purpose more than once, or one
+// whose return value is used, behaves differently under _once.
+func (requireOnceFixer) Standalone() bool { return false }
+
+func (requireOnceFixer) AppliesTo(p core.Project) bool { return !autoloaded(p) }
+
+// Fix converts statement-form require/include into require_once/include_once.
+// Expressions such as `$cfg = require 'config.php';` are left alone, as is
+// anything in strings, heredocs and comments.
+func (requireOnceFixer) Fix(t Target) (string, error) {
+	toks := phpTokens(t.Content)
+
+	var b strings.Builder
+	for i, tok := range toks {
+		b.WriteString(tok.Text)
+		if lower := strings.ToLower(tok.Text); (lower == "require" || lower == "include") && requireAt(toks, i) {
+			b.WriteString("_once")
+		}
+	}
+	return b.String(), nil
+}
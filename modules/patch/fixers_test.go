@@ -0,0 +1,154 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloseTagFixer(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"semicolon kept", "<?php\nfoo();\n?>\n", "<?php\nfoo();\n"},
+		{"statement ended by tag", "<?php\nfoo()\n?>\n", "<?php\nfoo();\n"},
+		{"brace ends block", "<?php\nif ($a) {\n}\n?>\n", "<?php\nif ($a) {\n}\n"},
+		{"crlf", "<?php\r\nfoo();\r\n?>\r\n", "<?php\r\nfoo();\r\n"},
+		{"empty file", "<?php\n?>\n", "<?php\n"},
+		{"html after tag", "<?php\nfoo();\n?>\n<p>hi</p>\n", "<?php\nfoo();\n?>\n<p>hi</p>\n"},
+		{"template", "<p><?php echo 1; ?></p>\n", "<p><?php echo 1; ?></p>\n"},
+		{"echo tag", "<?= $x ?>\n", "<?= $x ?>\n"},
+		{"tag in string", "<?php\n$s = '?>';\n", "<?php\n$s = '?>';\n"},
+		{"no close tag", "<?php\nfoo();\n", "<?php\nfoo();\n"},
+	}
+	for _, tt := range tests {
+		got, err := closeTagFixer{}.Fix(Target{Content: tt.in})
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Fix(%q) = %q, %v; want %q", tt.name, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestOpenTagFixer(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"short tag", "<? echo 1;\n", "<?php echo 1;\n"},
+		{"upper case", "<?PHP\necho 1;\n", "<?php\necho 1;\n"},
+		{"leading space", "\n  <?php\necho 1;\n", "<?php\necho 1;\n"},
+		{"echo tag", "<?= $x ?>\n", "<?= $x ?>\n"},
+		{"xml prolog", "<?xml version=\"1.0\"?>\n", "<?xml version=\"1.0\"?>\n"},
+		{"tag in string", "<?php\n$s = '<? x';\n", "<?php\n$s = '<? x';\n"},
+		{"bom kept", "\ufeff<? echo 1;\n", "\ufeff<?php echo 1;\n"},
+	}
+	for _, tt := range tests {
+		got, err := openTagFixer{}.Fix(Target{Content: tt.in})
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Fix(%q) = %q, %v; want %q", tt.name, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestRequireOnceFixer(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"statement", "<?php\nrequire 'a.php';\n", "<?php\nrequire_once 'a.php';\n"},
+		{"parenthesized", "<?php\ninclude('a.php');\n", "<?php\ninclude_once('a.php');\n"},
+		{"after statement", "<?php\nfoo(); require 'a.php';\n", "<?php\nfoo(); require_once 'a.php';\n"},
+		{"already once", "<?php\nrequire_once 'a.php';\n", "<?php\nrequire_once 'a.php';\n"},
+		{"expression", "<?php\n$cfg = require 'config.php';\n", "<?php\n$cfg = require 'config.php';\n"},
+		{"heredoc", "<?php\necho <<<TXT\nrequire 'x.php';\nTXT;\n", "<?php\necho <<<TXT\nrequire 'x.php';\nTXT;\n"},
+		{"comment", "<?php\n/*\ninclude 'y.php';\n*/\n", "<?php\n/*\ninclude 'y.php';\n*/\n"},
+	}
+	for _, tt := range tests {
+		got, err := requireOnceFixer{}.Fix(Target{Content: tt.in})
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Fix(%q) = %q, %v; want %q", tt.name, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestRequirePathsFixer(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "lib", "db.php"), []byte("<?php\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{"relative", "<?php\nrequire 'lib/db.php';\n", "<?php\nrequire __DIR__ . '/lib/db.php';\n"},
+		{"root slash", "<?php\ninclude_once(\"/lib/db.php\");\n", "<?php\ninclude_once __DIR__ . '/lib/db.php';\n"},
+		{"missing", "<?php\nrequire 'lib/gone.php';\n", "<?php\nrequire 'lib/gone.php';\n"},
+		{"anchored", "<?php\nrequire __DIR__ . '/lib/db.php';\n", "<?php\nrequire __DIR__ . '/lib/db.php';\n"},
+		{"heredoc", "<?php\necho <<<TXT\nrequire 'lib/db.php';\nTXT;\n", "<?php\necho <<<TXT\nrequire 'lib/db.php';\nTXT;\n"},
+		{"comment", "<?php\n// require 'lib/db.php';\n", "<?php\n// require 'lib/db.php';\n"},
+	}
+	for _, tt := range tests {
+		got, err := requirePathsFixer{}.Fix(Target{ProjectPath: root, RelPath: "index.php", Content: tt.in})
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Fix(%q) = %q, %v; want %q", tt.name, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestPatchPHPHeader(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			"adds strict",
+			"<?php\necho 1;\n",
+			"<?php\n\ndeclare(strict_types=1);\n\necho 1;\n",
+		},
+		{
+			"already strict",
+			"<?php\n\ndeclare(strict_types=1);\n\necho 1;\n",
+			"<?php\n\ndeclare(strict_types=1);\n\necho 1;\n",
+		},
+		{
+			"file docblock",
+			"<?php\n/**\n * File.\n */\n\nnamespace App;\n",
+			"<?php\n/**\n * File.\n */\n\ndeclare(strict_types=1);\n\nnamespace App;\n",
+		},
+		{
+			"class docblock",
+			"<?php\n/** A user. */\nclass User {}\n",
+			"<?php\n\ndeclare(strict_types=1);\n\n/** A user. */\nclass User {}\n",
+		},
+		{
+			"other declare on tag line",
+			"<?php declare(ticks=1);\necho 1;\n",
+			"<?php\ndeclare(strict_types=1);\ndeclare(ticks=1);\necho 1;\n",
+		},
+		{
+			"leading blank lines",
+			"\n\n<?php\necho 1;\n",
+			"<?php\n\ndeclare(strict_types=1);\n\necho 1;\n",
+		},
+		{
+			"shebang",
+			"#!/usr/bin/env php\n<?php\necho 1;\n",
+			"#!/usr/bin/env php\n<?php\n\ndeclare(strict_types=1);\n\necho 1;\n",
+		},
+		{
+			"crlf",
+			"<?php\r\necho 1;\r\n",
+			"<?php\r\n\r\ndeclare(strict_types=1);\r\n\r\necho 1;\r\n",
+		},
+		{
+			"inline html first",
+			"<p>hi</p>\n<?php echo 1;\n",
+			"<p>hi</p>\n<?php echo 1;\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := patchPHPHeader(tt.in, "index.php", "demo", true, false); got != tt.want {
+			t.Errorf("%s: patchPHPHeader(%q) = %q; want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package patch
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"rictusd/modules/brain"
 	"rictusd/modules/core"
 )
 
@@ -21,8 +26,9 @@ func NewEngine(c *core.Core) *Engine {
 }
 
 // PatchPHPFile reads a PHP file under the given project and returns a patched
-// version with a normalized PSR-ish header: opening tag, strict_types,
-// and a docblock, then the rest of the file.
+// version whose header carries declare(strict_types=1) and a file docblock.
+// Only what is missing is added; existing declares, docblocks, the BOM and the
+// file's line endings are left exactly as they were.
 func (e *Engine) PatchPHPFile(p core.Project, relPath string) (string, error) {
 	full := filepath.Join(p.Path, relPath)
 
@@ -37,108 +43,487 @@ func (e *Engine) PatchPHPFile(p core.Project, relPath string) (string, error) {
 	return patched, nil
 }
 
-// patchPHPContent rewrites the top of the file to:
+// patchPHPContent adds declare(strict_types=1) and a generated file docblock
+// to the top of a PHP file when they are missing. The header it aims for is:
 //
-// <?php
+//	<?php
 //
-// declare(strict_types=1);
+//	declare(strict_types=1);
 //
-// /**
-//  * Entry point for ProjectName.
-//  *
-//  * @package ProjectName
-//  */
+//	/**
+//	 * UserController controller.
+//	 *
+//	 * @package App\Http\Controllers
+//	 */
 //
-// ...rest of original file (with any old header stripped)...
-func patchPHPContent(src, fileName, projectName string) string {
-	// Normalize newlines
-	src = strings.ReplaceAll(src, "\r\n", "\n")
+// Anything already present in the header is kept in place. Blank lines
+// before the opening tag are dropped and a shebang line is kept; files that
+// otherwise do not open with a PHP tag are returned untouched, since a
+// declare after inline output would be a fatal error.
+func patchPHPContent(src, relPath, projectName string) string {
+	return patchPHPHeader(src, relPath, projectName, true, true)
+}
 
-	if !strings.Contains(src, "<?php") {
+// patchPHPHeader is patchPHPContent with control over which header parts to
+// add; the strict-types fixer uses it to leave docblocks out of the change.
+func patchPHPHeader(src, relPath, projectName string, wantStrict, wantDoc bool) string {
+	doc := newPHPDocument(src)
+	if doc == nil {
 		// Not a PHP file we want to touch.
 		return src
 	}
 
-	lines := strings.Split(src, "\n")
+	h := doc.header()
+	needStrict := wantStrict && !h.hasStrict
+	needDoc := wantDoc && h.docStart < 0
+	if !needStrict && !needDoc {
+		return src
+	}
 
-	// Walk from the top and strip:
-	// - leading blank lines
-	// - any "<?php" lines
-	// - any blank lines after that
-	i := 0
-	for i < len(lines) {
-		t := strings.TrimSpace(lines[i])
-		if t == "" {
-			i++
-			continue
+	strictLine := "declare(strict_types=1);"
+
+	// Work from the bottom up so earlier line indexes stay valid.
+	if needDoc {
+		at := h.openLine + 1
+		if len(h.declares) > 0 {
+			at = h.declares[len(h.declares)-1] + 1
 		}
-		if strings.HasPrefix(t, "<?php") {
-			i++
-			for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
-				i++
-			}
-			continue
+
+		block := append([]string{""}, docblockFor(src, relPath, projectName)...)
+		if needStrict && len(h.declares) == 0 {
+			// The docblock follows the declare we are about to add.
+			block = append([]string{"", strictLine}, block...)
+			needStrict = false
 		}
-		break
+		doc.insert(at, block)
 	}
 
-	// Now strip any leading declare() and top-level docblock.
-	for i < len(lines) {
-		t := strings.TrimSpace(lines[i])
-		if t == "" {
-			i++
-			continue
+	if needStrict {
+		switch {
+		case len(h.declares) > 0:
+			// strict_types must be the first declare in the file.
+			doc.insert(h.declares[0], []string{strictLine})
+		case h.docStart >= 0:
+			doc.insert(h.docEnd+1, []string{"", strictLine})
+		default:
+			doc.insert(h.openLine+1, []string{"", strictLine})
 		}
+	}
+
+	return doc.String()
+}
+
+// phpDocument is a line-level view of a PHP source file that remembers the
+// BOM and each line's terminator so it can be reassembled byte for byte.
+type phpDocument struct {
+	bom   string
+	nl    string // dominant line ending, used for inserted lines
+	open  int    // index of the line holding the opening tag
+	lines []string
+	ends  []string // terminator that followed each line ("\n", "\r\n" or "")
+}
+
+// newPHPDocument splits src into lines. It returns nil when the file does
+// not start with a <?php opening tag, after an optional shebang line and
+// any blank lines.
+func newPHPDocument(src string) *phpDocument {
+	d := splitLines(src)
+	if len(d.lines) > 0 && strings.HasPrefix(d.lines[0], "#!") {
+		d.open = 1
+	}
+
+	// Blank lines before the tag are output ahead of any declare, which PHP
+	// rejects, so they go.
+	end := d.open
+	for end < len(d.lines) && strings.TrimSpace(d.lines[end]) == "" {
+		end++
+	}
+	d.lines = append(d.lines[:d.open], d.lines[end:]...)
+	d.ends = append(d.ends[:d.open], d.ends[end:]...)
+
+	if d.open >= len(d.lines) || len(d.lines[d.open]) < 5 || !strings.EqualFold(d.lines[d.open][:5], "<?php") {
+		return nil
+	}
+
+	// Keep anything sharing a line with the opening tag on its own line, so
+	// new header lines can go directly below the tag.
+	first := d.lines[d.open]
+	if rest := strings.TrimSpace(first[5:]); rest != "" && !isHeaderCode(rest) {
+		tail := append([]string{first[:5], rest}, d.lines[d.open+1:]...)
+		d.lines = append(d.lines[:d.open], tail...)
+		tailEnds := append([]string{d.nl}, d.ends[d.open:]...)
+		d.ends = append(d.ends[:d.open], tailEnds...)
+	}
+
+	return d
+}
+
+// splitLines builds a phpDocument from any text without checking for an
+// opening tag.
+func splitLines(src string) *phpDocument {
+	d := &phpDocument{nl: "\n"}
+
+	if strings.HasPrefix(src, "\ufeff") {
+		d.bom = "\ufeff"
+		src = src[len("\ufeff"):]
+	}
+
+	crlf := strings.Count(src, "\r\n")
+	if crlf > 0 && crlf*2 >= strings.Count(src, "\n") {
+		d.nl = "\r\n"
+	}
+
+	for src != "" {
+		idx := strings.IndexByte(src, '\n')
+		if idx == -1 {
+			d.lines = append(d.lines, src)
+			d.ends = append(d.ends, "")
+			break
+		}
+
+		line, end := src[:idx], "\n"
+		if strings.HasSuffix(line, "\r") {
+			line, end = line[:len(line)-1], "\r\n"
+		}
+		d.lines = append(d.lines, line)
+		d.ends = append(d.ends, end)
+		src = src[idx+1:]
+	}
 
-		// Strip declare(...)
-		if strings.HasPrefix(t, "declare(") {
-			i++
+	return d
+}
+
+// isHeaderCode reports whether text on the opening-tag line can stay there:
+// only a strict_types declare, which already is the first statement. Any
+// other declare is split off like code, so header records it and
+// strict_types can go in before it, as PHP requires.
+func isHeaderCode(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "declare") && isStrictDeclare(s)
+}
+
+// phpHeader describes what the top of a PHP file already contains.
+type phpHeader struct {
+	openLine  int
+	docStart  int // -1 when there is no file docblock
+	docEnd    int
+	declares  []int
+	hasStrict bool
+}
+
+// header walks the lines after the opening tag, up to the first real
+// statement, and records declares and the first docblock. A docblock that
+// directly precedes a class, function or other declaration documents that
+// declaration, not the file.
+func (d *phpDocument) header() phpHeader {
+	h := phpHeader{openLine: d.open, docStart: -1, docEnd: -1}
+
+	if isStrictDeclare(d.lines[d.open]) {
+		h.hasStrict = true
+	}
+
+	inComment := false
+	stmt := len(d.lines)
+	for i := d.open + 1; i < len(d.lines); i++ {
+		t := strings.TrimSpace(d.lines[i])
+
+		if inComment {
+			if strings.Contains(t, "*/") {
+				inComment = false
+				if h.docStart >= 0 && h.docEnd < 0 {
+					h.docEnd = i
+				}
+			}
 			continue
 		}
 
-		// Strip leading docblock (/** ... */)
-		if strings.HasPrefix(t, "/**") {
-			i++
-			for i < len(lines) {
-				if strings.Contains(lines[i], "*/") {
-					i++
-					break
+		switch {
+		case t == "":
+			continue
+		case strings.HasPrefix(t, "//"), strings.HasPrefix(t, "#") && !strings.HasPrefix(t, "#["):
+			continue
+		case strings.HasPrefix(t, "/*"):
+			isDoc := strings.HasPrefix(t, "/**") && h.docStart < 0
+			if isDoc {
+				h.docStart = i
+			}
+			if strings.Contains(t[2:], "*/") {
+				if isDoc {
+					h.docEnd = i
 				}
-				i++
+				continue
 			}
-			// Skip any blank lines after the docblock
-			for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
-				i++
+			inComment = true
+			continue
+		case strings.HasPrefix(strings.ToLower(t), "declare"):
+			h.declares = append(h.declares, i)
+			if isStrictDeclare(t) {
+				h.hasStrict = true
 			}
 			continue
 		}
 
-		// Anything else we keep as body.
+		stmt = i
 		break
 	}
 
-	body := strings.Join(lines[i:], "\n")
-	body = strings.TrimLeft(body, "\n")
+	// An unterminated comment is not a docblock we can reason about.
+	if h.docStart >= 0 && h.docEnd < 0 {
+		h.docStart = -1
+	}
+
+	if h.docStart >= 0 && stmt < len(d.lines) && declarationPattern.MatchString(d.lines[stmt]) {
+		documentsDecl := true
+		for _, at := range h.declares {
+			if at > h.docEnd {
+				documentsDecl = false
+			}
+		}
+		if documentsDecl {
+			h.docStart, h.docEnd = -1, -1
+		}
+	}
+
+	return h
+}
+
+// insert places new lines before line index at, using the dominant ending.
+func (d *phpDocument) insert(at int, lines []string) {
+	if at > len(d.lines) {
+		at = len(d.lines)
+	}
+
+	// Keep a blank line between the inserted block and following code.
+	if at < len(d.lines) && strings.TrimSpace(d.lines[at]) != "" && len(lines) > 0 && lines[len(lines)-1] != "" {
+		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(d.lines[at])), "declare") {
+			lines = append(lines, "")
+		}
+	}
+
+	// The previous last line may have had no terminator.
+	if at == len(d.lines) && at > 0 && d.ends[at-1] == "" {
+		d.ends[at-1] = d.nl
+	}
+
+	ends := make([]string, len(lines))
+	for i := range ends {
+		ends[i] = d.nl
+	}
+
+	d.lines = append(d.lines[:at], append(lines, d.lines[at:]...)...)
+	d.ends = append(d.ends[:at], append(ends, d.ends[at:]...)...)
+}
+
+// String reassembles the document.
+func (d *phpDocument) String() string {
+	var b strings.Builder
+	b.WriteString(d.bom)
+	for i, line := range d.lines {
+		b.WriteString(line)
+		b.WriteString(d.ends[i])
+	}
+	return b.String()
+}
+
+// isStrictDeclare reports whether a line declares strict_types=1.
+func isStrictDeclare(line string) bool {
+	compact := strings.ToLower(strings.Join(strings.Fields(line), ""))
+	return strings.Contains(compact, "declare(strict_types=1")
+}
+
+var (
+	declarationPattern = regexp.MustCompile(`(?i)^\s*(?:#\[|(?:abstract|final|readonly|class|interface|trait|enum|function|const)\b)`)
+	namespacePattern   = regexp.MustCompile(`(?m)^\s*namespace\s+([A-Za-z0-9_\\]+)\s*[;{]`)
+	classPattern       = regexp.MustCompile(`(?m)^\s*(?:(?:abstract|final|readonly)\s+)*(class|interface|trait|enum)\s+([A-Za-z0-9_]+)`)
+)
+
+// docblockFor builds a file docblock from the file's role and namespace
+// rather than a fixed template.
+func docblockFor(src, relPath, projectName string) []string {
+	ns := ""
+	if m := namespacePattern.FindStringSubmatch(src); m != nil {
+		ns = m[1]
+	}
+
+	kind, class := "", ""
+	if m := classPattern.FindStringSubmatch(src); m != nil {
+		kind, class = m[1], m[2]
+	}
+
+	base := strings.TrimSuffix(filepath.Base(relPath), filepath.Ext(relPath))
+
+	var summary string
+	switch brain.ClassifyFile(relPath, []byte(src), nil) {
+	case brain.RoleRouter:
+		summary = "Request router for " + projectName + "."
+	case brain.RoleController:
+		if class != "" {
+			summary = class + " controller."
+		} else {
+			summary = "Controller logic for " + base + "."
+		}
+	case brain.RoleView:
+		summary = "View template for " + base + "."
+	case brain.RoleConfig:
+		summary = "Configuration for " + projectName + "."
+	case brain.RoleModel:
+		if class != "" {
+			summary = class + " model."
+		} else {
+			summary = "Model logic for " + base + "."
+		}
+	default:
+		if class != "" {
+			summary = class + " " + kind + "."
+		} else {
+			summary = "The " + base + " script."
+		}
+	}
 
-	headerLines := []string{
-		"<?php",
-		"",
-		"declare(strict_types=1);",
-		"",
+	pkg := projectName
+	if ns != "" {
+		pkg = ns
+	}
+
+	return []string{
 		"/**",
-		" * Entry point for " + projectName + ".",
+		" * " + summary,
 		" *",
-		" * @package " + projectName,
+		" * @package " + pkg,
 		" */",
-		"",
 	}
-	header := strings.Join(headerLines, "\n")
+}
+
+// Propose runs a fixer over one project file. It returns the proposal and
+// whether the fixer changed anything; unchanged files yield no proposal.
+func (e *Engine) Propose(p core.Project, relPath string, f Fixer) (Proposal, bool, error) {
+	return e.propose(p, e.projectConfig(p), relPath, f)
+}
+
+// projectConfig loads the project's .rictus config, falling back to the
+// defaults with a warning when it cannot be parsed.
+func (e *Engine) projectConfig(p core.Project) core.ProjectConfig {
+	cfg, err := core.LoadProjectConfig(p.Path)
+	if err != nil {
+		e.core.Log.Warnf("patch: %s: %v; using defaults", p.Name, err)
+	}
+	return cfg
+}
 
-	if strings.TrimSpace(body) == "" {
-		return header + "\n"
+func (e *Engine) propose(p core.Project, cfg core.ProjectConfig, relPath string, f Fixer) (Proposal, bool, error) {
+	full, err := core.ResolveProjectPath(p.Path, relPath)
+	if err != nil {
+		return Proposal{}, false, fmt.Errorf("read PHP file: %w", err)
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return Proposal{}, false, fmt.Errorf("read PHP file: %w", err)
+	}
+
+	return e.proposeContent(p, cfg, relPath, string(data), f)
+}
+
+// ProposeContent runs a fixer over content that stands in for a project
+// file, such as an unsaved editor buffer. Applying the proposal still
+// checks it against the file on disk.
+func (e *Engine) ProposeContent(p core.Project, relPath, content string, f Fixer) (Proposal, bool, error) {
+	return e.proposeContent(p, e.projectConfig(p), relPath, content, f)
+}
+
+func (e *Engine) proposeContent(p core.Project, cfg core.ProjectConfig, relPath, original string, f Fixer) (Proposal, bool, error) {
+	patched, err := f.Fix(Target{
+		ProjectName: p.Name,
+		ProjectPath: p.Path,
+		RelPath:     relPath,
+		Content:     original,
+		Config:      cfg,
+	})
+	if err != nil {
+		return Proposal{}, false, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+
+	if patched == original {
+		return Proposal{}, false, nil
+	}
+
+	return Proposal{
+		Project:  p.Name,
+		RelPath:  relPath,
+		Fixer:    f.Name(),
+		Original: original,
+		Patched:  patched,
+	}, true, nil
+}
+
+// ProposeProject runs a fixer over every indexed PHP file in the project and
+// returns a proposal for each file it would change.
+func (e *Engine) ProposeProject(ctx context.Context, p core.Project, f Fixer) ([]Proposal, error) {
+	out := make([]Proposal, 0)
+	cfg := e.projectConfig(p)
+
+	idx, _, err := brain.NewIndexer(e.core).RefreshContext(ctx, p, nil)
+	if err != nil {
+		return out, err
+	}
+
+	for _, entry := range idx.Lang("php") {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		prop, changed, err := e.propose(p, cfg, filepath.FromSlash(entry.Path), f)
+		if err != nil {
+			e.core.Log.Warnf("patch: %v", err)
+			continue
+		}
+		if changed {
+			out = append(out, prop)
+		}
+	}
+
+	return out, nil
+}
+
+// ApplyProposal writes an approved proposal to disk. It refuses when the file
+// no longer matches the content the proposal was computed from, or when its
+// path leads outside the project.
+func (e *Engine) ApplyProposal(p core.Project, prop Proposal) error {
+	if prop.Create {
+		_, err := core.ResolveProjectPath(p.Path, prop.RelPath)
+		if err == nil {
+			return fmt.Errorf("%s already exists; I won't overwrite it with a stub", prop.RelPath)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		// The directories that already exist must stay inside the project
+		// before any are created below them.
+		for dir := filepath.Dir(filepath.Clean(prop.RelPath)); dir != "."; dir = filepath.Dir(dir) {
+			if _, err := core.ResolveProjectPath(p.Path, dir); err == nil {
+				break
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(p.Path, prop.RelPath)), 0o755); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+		return e.ApplyFile(p, prop.RelPath, prop.Patched)
+	}
+
+	full, err := core.ResolveProjectPath(p.Path, prop.RelPath)
+	if err != nil {
+		return fmt.Errorf("read current file: %w", err)
+	}
+	current, err := os.ReadFile(full)
+	if err != nil {
+		return fmt.Errorf("read current file: %w", err)
+	}
+	if string(current) != prop.Original {
+		return fmt.Errorf("%s changed since the proposal was made; patch it again", prop.RelPath)
 	}
 
-	return header + "\n" + body
+	return e.ApplyFile(p, prop.RelPath, prop.Patched)
 }
 
 // ApplyFile writes the given content to the target file under the project.
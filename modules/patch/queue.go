@@ -0,0 +1,234 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"rictusd/modules/core"
+)
+
+// ErrNoProposal is returned when no pending proposal matches a lookup.
+var ErrNoProposal = errors.New("no pending proposal")
+
+// Proposal is a prepared change awaiting explicit approval. Original holds
+// the file content the proposal was computed from, so a file that changed
+// in the meantime is never overwritten blindly. Create marks a proposal for
+// a file that does not exist yet; Original is empty then.
+type Proposal struct {
+	ID        int    `json:"id"`
+	Project   string `json:"project"`
+	RelPath   string `json:"rel_path"`
+	Fixer     string `json:"fixer"`
+	Original  string `json:"original"`
+	Patched   string `json:"patched"`
+	Create    bool   `json:"create,omitempty"`
+	CreatedAt string `json:"created_at"` // RFC3339
+}
+
+// Queue keeps pending proposals in data/proposals.json. There is at most one
+// pending proposal per project file; a newer one replaces the older. Other
+// RictusD processes, such as the MCP server, share the file, so every call
+// rereads it.
+type Queue struct {
+	core *core.Core
+	path string
+
+	mu    sync.Mutex
+	items []Proposal
+}
+
+// NewQueue loads the pending proposal queue from the data directory.
+func NewQueue(c *core.Core) *Queue {
+	q := &Queue{
+		core: c,
+		path: filepath.Join(c.Data, "proposals.json"),
+	}
+	q.open(syscall.LOCK_SH)()
+	return q
+}
+
+// open readies the queue for one call: it takes q.mu against this process
+// and an advisory lock on proposals.json.lock against others (shared for
+// reads, exclusive for changes), then rereads the file. The returned func
+// releases both. When the lock file can't be used, only q.mu is held.
+func (q *Queue) open(how int) func() {
+	q.mu.Lock()
+
+	f, err := os.OpenFile(q.path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err == nil {
+		if err = syscall.Flock(int(f.Fd()), how); err != nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		q.core.Log.Warnf("patch queue: lock %s: %v", q.path, err)
+		q.load()
+		return q.mu.Unlock
+	}
+
+	q.load()
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+		q.mu.Unlock()
+	}
+}
+
+// load rereads the queue from disk; callers hold the lock from open.
+// A file that can't be read or decoded leaves the queue as it was.
+func (q *Queue) load() {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			q.core.Log.Errorf("patch queue: read %s: %v", q.path, err)
+		}
+		return
+	}
+
+	var items []Proposal
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &items); err != nil {
+			q.core.Log.Errorf("patch queue: decode %s: %v", q.path, err)
+			return
+		}
+	}
+	q.items = items
+}
+
+// Add queues a proposal, replacing any pending one for the same file.
+func (q *Queue) Add(p Proposal) (Proposal, error) {
+	defer q.open(syscall.LOCK_EX)()
+
+	nextID := 1
+	kept := q.items[:0]
+	for _, it := range q.items {
+		if it.ID >= nextID {
+			nextID = it.ID + 1
+		}
+		if it.Project == p.Project && it.RelPath == p.RelPath {
+			continue
+		}
+		kept = append(kept, it)
+	}
+
+	p.ID = nextID
+	p.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	q.items = append(kept, p)
+
+	if err := q.save(); err != nil {
+		return Proposal{}, err
+	}
+	return p, nil
+}
+
+// Get returns the pending proposal for a project file.
+func (q *Queue) Get(project, relPath string) (Proposal, bool) {
+	defer q.open(syscall.LOCK_SH)()
+
+	for _, it := range q.items {
+		if it.Project == project && it.RelPath == relPath {
+			return it, true
+		}
+	}
+	return Proposal{}, false
+}
+
+// ForProject returns the pending proposals for a project, oldest first.
+func (q *Queue) ForProject(project string) []Proposal {
+	defer q.open(syscall.LOCK_SH)()
+
+	out := make([]Proposal, 0)
+	for _, it := range q.items {
+		if it.Project == project {
+			out = append(out, it)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Remove drops the pending proposal for a project file.
+func (q *Queue) Remove(project, relPath string) error {
+	defer q.open(syscall.LOCK_EX)()
+
+	for i, it := range q.items {
+		if it.Project == project && it.RelPath == relPath {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return q.save()
+		}
+	}
+	return ErrNoProposal
+}
+
+// RenameProject moves pending proposals to a project's new name.
+func (q *Queue) RenameProject(oldName, newName string) error {
+	defer q.open(syscall.LOCK_EX)()
+
+	changed := false
+	for i := range q.items {
+		if q.items[i].Project == oldName {
+			q.items[i].Project = newName
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return q.save()
+}
+
+// DropProject discards every pending proposal for a project.
+func (q *Queue) DropProject(project string) error {
+	defer q.open(syscall.LOCK_EX)()
+
+	kept := q.items[:0]
+	for _, it := range q.items {
+		if it.Project != project {
+			kept = append(kept, it)
+		}
+	}
+	if len(kept) == len(q.items) {
+		return nil
+	}
+	q.items = kept
+	return q.save()
+}
+
+// save writes the queue to disk; callers hold the exclusive lock from open.
+func (q *Queue) save() error {
+	data, err := json.MarshalIndent(q.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode proposals.json: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), "proposals.json.*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp proposals.json: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("write temp proposals.json: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("rename proposals.json: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package patch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+)
+
+// Candidate is a project file that might be what an unresolved require meant.
+type Candidate struct {
+	RelPath string  `json:"rel_path"`
+	Score   float64 `json:"score"` // 0..1, higher is more likely
+}
+
+// RequireRepair pairs an unresolved require with the candidates found for it.
+type RequireRepair struct {
+	Missing    brain.MissingRequire `json:"missing"`
+	Candidates []Candidate          `json:"candidates"`
+}
+
+// minCandidateScore filters out candidates that share little more than an
+// extension with the missing target.
+const minCandidateScore = 0.45
+
+// RequireCandidates searches the project index for files that could satisfy an
+// unresolved require, ranked by basename match and path similarity.
+func (e *Engine) RequireCandidates(p core.Project, mr brain.MissingRequire) ([]Candidate, error) {
+	want := filepath.ToSlash(mr.Resolved)
+	if filepath.Ext(want) == "" {
+		want += ".php"
+	}
+
+	idx, _, err := brain.NewIndexer(e.core).Refresh(p)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Candidate, 0)
+	for _, entry := range idx.Lang("php") {
+		rel := filepath.FromSlash(entry.Path)
+		if rel == mr.File {
+			continue
+		}
+		if score := candidateScore(want, entry.Path); score >= minCandidateScore {
+			out = append(out, Candidate{RelPath: rel, Score: score})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].RelPath < out[j].RelPath
+	})
+	if len(out) > 3 {
+		out = out[:3]
+	}
+
+	return out, nil
+}
+
+// candidateScore weighs basename likeness most heavily, then how many
+// trailing directory names the two paths share, then overall path likeness.
+func candidateScore(want, have string) float64 {
+	wantBase, haveBase := pathBase(want), pathBase(have)
+
+	var score float64
+	switch {
+	case wantBase == haveBase:
+		score = 0.6
+	case strings.EqualFold(wantBase, haveBase):
+		score = 0.5
+	default:
+		score = 0.45 * similarity(strings.ToLower(wantBase), strings.ToLower(haveBase))
+	}
+
+	wantDirs := strings.Split(pathDir(want), "/")
+	haveDirs := strings.Split(pathDir(have), "/")
+	shared := 0
+	for i, j := len(wantDirs)-1, len(haveDirs)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if !strings.EqualFold(wantDirs[i], haveDirs[j]) || wantDirs[i] == "." || wantDirs[i] == ".." {
+			break
+		}
+		shared++
+	}
+	if shared > 3 {
+		shared = 3
+	}
+	score += 0.1 * float64(shared)
+
+	score += 0.1 * similarity(strings.ToLower(want), strings.ToLower(have))
+
+	if score > 1 {
+		score = 1
+	}
+	return score
+}
+
+func pathBase(p string) string {
+	if idx := strings.LastIndex(p, "/"); idx != -1 {
+		return p[idx+1:]
+	}
+	return p
+}
+
+func pathDir(p string) string {
+	if idx := strings.LastIndex(p, "/"); idx != -1 {
+		return p[:idx]
+	}
+	return "."
+}
+
+// similarity is 1 minus the normalized Levenshtein distance of a and b.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	longest := max(len(ra), len(rb))
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// requireSpan matches a whole require/include statement on one line.
+var requireSpan = regexp.MustCompile(`\b(require|require_once|include|include_once)\b[^;]*;`)
+
+// missingSpan returns the submatch indexes of the require statement on line
+// whose target is the given one. Statements that run past the line never
+// match and are left for a human.
+func missingSpan(line, target string) []int {
+	for _, loc := range requireSpan.FindAllStringSubmatchIndex(line, -1) {
+		if t, _, ok := brain.RequirePath(line[loc[0]:loc[1]], "."); ok && t == target {
+			return loc
+		}
+	}
+	return nil
+}
+
+// ProposeRequireRepair rewrites each unresolved require in a file to point at
+// its best-ranked candidate. It returns the proposal, the candidates found for
+// every missing target (including those it could not repair), and whether any
+// line changed.
+func (e *Engine) ProposeRequireRepair(p core.Project, relPath string, missing []brain.MissingRequire) (Proposal, []RequireRepair, bool, error) {
+	full, err := core.ResolveProjectPath(p.Path, relPath)
+	if err != nil {
+		return Proposal{}, nil, false, fmt.Errorf("read PHP file: %w", err)
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return Proposal{}, nil, false, fmt.Errorf("read PHP file: %w", err)
+	}
+
+	original := string(data)
+	doc := splitLines(original)
+	fileDir := filepath.Dir(relPath)
+
+	repairs := make([]RequireRepair, 0, len(missing))
+	changed := false
+
+	for _, mr := range missing {
+		cands, err := e.RequireCandidates(p, mr)
+		if err != nil {
+			return Proposal{}, nil, false, err
+		}
+		repairs = append(repairs, RequireRepair{Missing: mr, Candidates: cands})
+
+		if len(cands) == 0 || mr.Line < 1 || mr.Line > len(doc.lines) {
+			continue
+		}
+
+		line := doc.lines[mr.Line-1]
+		loc := missingSpan(line, mr.Target)
+		if loc == nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(fileDir, cands[0].RelPath)
+		if err != nil {
+			continue
+		}
+
+		keyword := line[loc[2]:loc[3]]
+		stmt := keyword + " __DIR__ . '/" + filepath.ToSlash(rel) + "';"
+		doc.lines[mr.Line-1] = line[:loc[0]] + stmt + line[loc[1]:]
+		changed = true
+	}
+
+	if !changed {
+		return Proposal{}, repairs, false, nil
+	}
+
+	return Proposal{
+		Project:  p.Name,
+		RelPath:  relPath,
+		Fixer:    "require-repair",
+		Original: original,
+		Patched:  doc.String(),
+	}, repairs, true, nil
+}
+
+// ProposeStub proposes a new placeholder file at the path an unresolved
+// require expects, with a strict_types header (unless the project's config
+// rules it out), a namespace derived from the
+// directory and a docblock naming the file that needs it.
+func (e *Engine) ProposeStub(p core.Project, mr brain.MissingRequire) (Proposal, error) {
+	rel := filepath.Clean(mr.Resolved)
+	if filepath.Ext(rel) == "" {
+		rel += ".php"
+	}
+	if rel == "." || strings.HasPrefix(rel, "..") || filepath.IsAbs(rel) {
+		return Proposal{}, fmt.Errorf("%s points outside the project", mr.Target)
+	}
+
+	if _, err := os.Stat(filepath.Join(p.Path, rel)); err == nil {
+		return Proposal{}, fmt.Errorf("%s already exists", rel)
+	}
+
+	ns := stubNamespace(p.Name, filepath.Dir(rel))
+	base := strings.TrimSuffix(filepath.Base(rel), ".php")
+
+	lines := []string{"<?php", ""}
+	if e.projectConfig(p).RequireStrict() {
+		lines = append(lines, "declare(strict_types=1);", "")
+	}
+	lines = append(lines,
+		"/**",
+		" * Placeholder for "+filepath.ToSlash(rel)+", required by "+filepath.ToSlash(mr.File)+".",
+		" *",
+		" * @package "+ns,
+		" */",
+		"",
+		"namespace "+ns+";",
+		"",
+	)
+
+	// A capitalized file name usually means a class is expected.
+	if base != "" && base[0] >= 'A' && base[0] <= 'Z' && isIdentifier(base) {
+		lines = append(lines, "final class "+base, "{", "}", "")
+	} else {
+		lines = append(lines, "// TODO: provide what "+filepath.ToSlash(mr.File)+" expects from this file.", "")
+	}
+
+	return Proposal{
+		Project: p.Name,
+		RelPath: rel,
+		Fixer:   "require-stub",
+		Patched: strings.Join(lines, "\n"),
+		Create:  true,
+	}, nil
+}
+
+// stubNamespace builds a StudlyCase namespace from a directory path, falling
+// back to the project name for files at the root.
+func stubNamespace(projectName, dir string) string {
+	parts := make([]string, 0)
+	for _, seg := range strings.Split(filepath.ToSlash(dir), "/") {
+		if s := studly(seg); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 0 {
+		if s := studly(projectName); s != "" {
+			return s
+		}
+		return "App"
+	}
+	return strings.Join(parts, `\`)
+}
+
+// studly turns "user-admin_tools" into "UserAdminTools".
+func studly(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		switch {
+		case r == '-' || r == '_' || r == ' ' || r == '.':
+			upper = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			if b.Len() == 0 && r >= '0' && r <= '9' {
+				continue
+			}
+			if upper && r >= 'a' && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			b.WriteRune(r)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		ok := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')
+		if !ok {
+			return false
+		}
+	}
+	return s != ""
+}
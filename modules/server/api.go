@@ -0,0 +1,472 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"rictusd/modules/brain"
+	"rictusd/modules/core"
+)
+
+// projectPatch is the body of PATCH /api/projects/{name}. Every field is
+// optional; only the ones present are applied, in the order listed.
+type projectPatch struct {
+	Name          *string  `json:"name"`
+	Path          *string  `json:"path"`
+	Group         *string  `json:"group"`
+	Description   *string  `json:"description"`
+	Owner         *string  `json:"owner"`
+	DefaultBranch *string  `json:"default_branch"`
+	Standards     *string  `json:"standards"`
+	AddAliases    []string `json:"add_aliases"`
+	RemoveAliases []string `json:"remove_aliases"`
+	AddTags       []string `json:"add_tags"`
+	RemoveTags    []string `json:"remove_tags"`
+}
+
+type registerRequest struct {
+	Path string `json:"path"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// registerAPI wires the JSON API routes onto the mux.
+func (s *Server) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("/api/projects", s.handleProjects)
+	mux.HandleFunc("/api/projects/{name}", s.handleProject)
+	mux.HandleFunc("/api/projects/{name}/map", s.handleProjectMap)
+	mux.HandleFunc("/api/projects/{name}/graph", s.handleGraph)
+	mux.HandleFunc("/api/projects/{name}/findings", s.handleFindings)
+	mux.HandleFunc("/api/projects/{name}/symbols", s.handleSymbols)
+	mux.HandleFunc("/api/projects/{name}/uses", s.handleSymbols)
+	mux.HandleFunc("/api/projects/{name}/outline", s.handleOutline)
+	mux.HandleFunc("/api/projects/{name}/autoload", s.handleAutoload)
+	mux.HandleFunc("/api/scans", s.handleScans)
+}
+
+// handleProjectMap maps a project and returns the full ProjectMap: line
+// counts per language, sizes, largest files and the per-directory breakdown.
+func (s *Server) handleProjectMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, ok := s.mind.Projects().FindByName(r.PathValue("name"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	pm, err := brain.NewMapper(s.core).MapProjectContext(r.Context(), p)
+	if err != nil {
+		s.core.Log.Errorf("api: map %s: %v", p.Name, err)
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, pm)
+}
+
+// handleGraph returns a project's include graph as JSON, or rendered for
+// Graphviz (?format=dot) or Mermaid (?format=mermaid).
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, ok := s.mind.Projects().FindByName(r.PathValue("name"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	g, err := brain.NewMapper(s.core).IncludeGraphContext(r.Context(), p)
+	if err != nil {
+		s.core.Log.Errorf("api: graph %s: %v", p.Name, err)
+		if g == nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		s.writeJSON(w, http.StatusOK, g)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.Write([]byte(g.DOT()))
+	case "mermaid":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(g.Mermaid()))
+	default:
+		s.writeError(w, http.StatusBadRequest, "format must be json, dot or mermaid")
+	}
+}
+
+// handleFindings runs every analyzer over a project and returns the
+// findings as JSON (the default) or as a SARIF 2.1 log.
+func (s *Server) handleFindings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, ok := s.mind.Projects().FindByName(r.PathValue("name"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "sarif":
+	default:
+		s.writeError(w, http.StatusBadRequest, "format must be json or sarif")
+		return
+	}
+
+	findings, err := s.mind.Findings(r.Context(), p)
+	if err != nil {
+		s.core.Log.Errorf("api: findings %s: %v", p.Name, err)
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	data, err := brain.EncodeFindings(p, format, findings)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if format == "sarif" {
+		w.Header().Set("Content-Type", "application/sarif+json")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Write(data)
+}
+
+// projectIndex looks up the project in the path and refreshes its index,
+// writing an error response and returning false when either fails.
+func (s *Server) projectIndex(w http.ResponseWriter, r *http.Request) (*brain.ProjectIndex, bool) {
+	p, ok := s.mind.Projects().FindByName(r.PathValue("name"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "project not found")
+		return nil, false
+	}
+
+	idx, _, err := brain.NewIndexer(s.core).RefreshContext(r.Context(), p, nil)
+	if err != nil {
+		s.core.Log.Errorf("api: index %s: %v", p.Name, err)
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return idx, true
+}
+
+// handleSymbols serves GET /api/projects/{name}/symbols?q= (definitions)
+// and GET /api/projects/{name}/uses?q= (references).
+func (s *Server) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		s.writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	idx, ok := s.projectIndex(w, r)
+	if !ok {
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/uses") {
+		s.writeJSON(w, http.StatusOK, idx.FindRefs(q))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, idx.FindSymbol(q))
+}
+
+// handleOutline serves GET /api/projects/{name}/outline?file=, returning
+// the file's symbols, imports and references.
+func (s *Server) handleOutline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file := strings.TrimSpace(r.URL.Query().Get("file"))
+	if file == "" {
+		s.writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	idx, ok := s.projectIndex(w, r)
+	if !ok {
+		return
+	}
+
+	matches := idx.FindFile(file)
+	switch len(matches) {
+	case 0:
+		s.writeError(w, http.StatusNotFound, "file not found")
+		return
+	case 1:
+	default:
+		s.writeError(w, http.StatusConflict, "ambiguous file: "+strings.Join(matches, ", "))
+		return
+	}
+
+	e, ok := idx.Outline(matches[0])
+	if !ok {
+		s.writeError(w, http.StatusUnprocessableEntity, "not a PHP file")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, struct {
+		File string `json:"file"`
+		*brain.PHPFileScan
+	}{File: e.Path, PHPFileScan: e.PHP})
+}
+
+// handleAutoload checks a project's composer.json autoload setup.
+func (s *Server) handleAutoload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, ok := s.mind.Projects().FindByName(r.PathValue("name"))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	report, err := brain.NewPHPScanner(s.core).CheckAutoload(r.Context(), p)
+	if err != nil {
+		s.core.Log.Errorf("api: autoload %s: %v", p.Name, err)
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, report)
+}
+
+// handleScans lists the project index refreshes currently in progress.
+func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, brain.NewIndexer(s.core).Active())
+}
+
+// handleProjects lists projects (GET, optional ?tag= or ?group=) or
+// registers a new one (POST {"path": "..."}).
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	reg := s.mind.Projects()
+
+	switch r.Method {
+	case http.MethodGet:
+		var list []core.Project
+		switch {
+		case r.URL.Query().Get("tag") != "":
+			list = reg.ListByTag(r.URL.Query().Get("tag"))
+		case r.URL.Query().Get("group") != "":
+			list = reg.ListByGroup(r.URL.Query().Get("group"))
+		default:
+			list = reg.List()
+		}
+		s.writeJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		var req registerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		if strings.TrimSpace(req.Path) == "" {
+			s.writeError(w, http.StatusBadRequest, "path is required")
+			return
+		}
+
+		p, err := s.mind.RegisterProject(req.Path)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusCreated, p)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProject shows (GET), updates (PATCH) or unregisters (DELETE) a
+// project by name or alias.
+func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
+	reg := s.mind.Projects()
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		p, ok := reg.FindByName(name)
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "project not found")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, p)
+
+	case http.MethodPatch:
+		var req projectPatch
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+
+		p, err := s.applyProjectPatch(name, req)
+		if err != nil {
+			s.writeError(w, registryStatus(err), err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, p)
+
+	case http.MethodDelete:
+		p, err := s.mind.UnregisterProject(name)
+		if err != nil {
+			s.writeError(w, registryStatus(err), err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, p)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// applyProjectPatch applies each present field of a patch in turn. A rename
+// happens first so later steps address the project by its new name. When
+// any step fails, the project is put back the way it was before the patch.
+func (s *Server) applyProjectPatch(name string, req projectPatch) (core.Project, error) {
+	reg := s.mind.Projects()
+	p, ok := reg.FindByName(name)
+	if !ok {
+		return core.Project{}, core.ErrProjectNotFound
+	}
+
+	if req.Name != nil && *req.Name != p.Name {
+		oldName := p.Name
+		renamed, err := s.mind.RenameProject(oldName, *req.Name)
+		if err != nil {
+			return core.Project{}, err
+		}
+		req.Name = nil
+		p, err = s.applyProjectPatch(renamed.Name, req)
+		if err != nil {
+			if _, rerr := s.mind.RenameProject(renamed.Name, oldName); rerr != nil {
+				s.core.Log.Errorf("api: undo rename of %s: %v", oldName, rerr)
+			}
+			return core.Project{}, err
+		}
+		return p, nil
+	}
+
+	before := p
+	p, err := applyProjectFields(reg, p, req)
+	if err != nil {
+		if _, rerr := reg.Restore(before); rerr != nil {
+			s.core.Log.Errorf("api: undo patch of %s: %v", before.Name, rerr)
+		}
+		return core.Project{}, err
+	}
+	return p, nil
+}
+
+// applyProjectFields applies the fields of a patch other than the name.
+func applyProjectFields(reg *core.ProjectRegistry, p core.Project, req projectPatch) (core.Project, error) {
+	var err error
+	if req.Path != nil {
+		if p, err = reg.Relocate(p.Name, *req.Path); err != nil {
+			return p, err
+		}
+	}
+	if req.Group != nil {
+		if p, err = reg.SetGroup(p.Name, *req.Group); err != nil {
+			return p, err
+		}
+	}
+
+	meta := []struct {
+		key   string
+		value *string
+	}{
+		{"description", req.Description},
+		{"owner", req.Owner},
+		{"default_branch", req.DefaultBranch},
+		{"standards", req.Standards},
+	}
+	for _, m := range meta {
+		if m.value == nil {
+			continue
+		}
+		if p, err = reg.SetMeta(p.Name, m.key, *m.value); err != nil {
+			return p, err
+		}
+	}
+
+	for _, a := range req.AddAliases {
+		if p, err = reg.AddAlias(p.Name, a); err != nil {
+			return p, err
+		}
+	}
+	for _, a := range req.RemoveAliases {
+		if p, err = reg.RemoveAlias(p.Name, a); err != nil {
+			return p, err
+		}
+	}
+	if len(req.AddTags) > 0 {
+		if p, err = reg.AddTags(p.Name, req.AddTags...); err != nil {
+			return p, err
+		}
+	}
+	if len(req.RemoveTags) > 0 {
+		if p, err = reg.RemoveTags(p.Name, req.RemoveTags...); err != nil {
+			return p, err
+		}
+	}
+
+	return p, nil
+}
+
+// registryStatus maps registry errors onto HTTP status codes.
+func registryStatus(err error) int {
+	switch {
+	case errors.Is(err, core.ErrProjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, core.ErrNameTaken):
+		return http.StatusConflict
+	case errors.Is(err, core.ErrInvalidName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.core.Log.Errorf("api encode error: %v", err)
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
+	s.writeJSON(w, status, errorResponse{Error: msg})
+}
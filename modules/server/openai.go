@@ -0,0 +1,295 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"rictusd/modules/core"
+)
+
+// modelName is the model RictusD answers as on the OpenAI-style endpoints.
+const modelName = "rictusd"
+
+// completionRequest is the body of POST /v1/chat/completions. Sampling
+// parameters are accepted and ignored: Mind's replies are not sampled.
+type completionRequest struct {
+	Model         string              `json:"model"`
+	Messages      []completionMessage `json:"messages"`
+	Stream        bool                `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// completionMessage is one entry of the messages array. Content is a string
+// or, in the newer shape, an array of typed parts.
+type completionMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the message's text; non-text parts such as images are
+// dropped.
+func (m completionMessage) text() string {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+type completionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type completionChoice struct {
+	Index        int              `json:"index"`
+	Message      *completionReply `json:"message,omitempty"`
+	Delta        *completionReply `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type completionReply struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type completionResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []completionChoice `json:"choices"`
+	Usage   *completionUsage   `json:"usage,omitempty"`
+}
+
+// openAIError is the error body OpenAI clients know how to show.
+type openAIError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// registerOpenAI wires the OpenAI-compatible routes onto the mux, so tools
+// that only speak that API can use RictusD as a model.
+func (s *Server) registerOpenAI(mux *http.ServeMux) {
+	mux.HandleFunc("/v1/chat/completions", s.handleCompletions)
+	mux.HandleFunc("/v1/models", s.handleModels)
+}
+
+// handleCompletions answers the last user message of a conversation with
+// Mind. Each conversation gets its own Mind session, found again on the
+// next turn from the user messages before it, so "apply" and "patch" keep
+// referring to the project the client's conversation is about.
+func (s *Server) handleCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	token, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req completionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid json: "+err.Error())
+		return
+	}
+	if len(req.Messages) == 0 || req.Messages[len(req.Messages)-1].Role != "user" {
+		s.writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "the last message must come from the user")
+		return
+	}
+	msg := strings.TrimSpace(req.Messages[len(req.Messages)-1].text())
+	if msg == "" {
+		s.writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "the last message has no text")
+		return
+	}
+
+	var users []string
+	prompt := 0
+	for _, m := range req.Messages {
+		text := m.text()
+		prompt += estimateTokens(text)
+		if m.Role == "user" {
+			users = append(users, strings.TrimSpace(text))
+		}
+	}
+	from := ""
+	if len(users) > 1 {
+		from = sessionKey(token, users[:len(users)-1])
+	}
+	to := sessionKey(token, users)
+	client := "v1 " + token.Name
+
+	model := req.Model
+	if model == "" {
+		model = modelName
+	}
+	resp := completionResponse{
+		ID:      completionID(),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+	}
+	s.core.Log.Infof("v1: chat completion for %s (%d messages, stream=%v)", token.Name, len(req.Messages), req.Stream)
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		s.streamCompletion(w, r, resp, client, from, to, msg, prompt, includeUsage)
+		return
+	}
+
+	reply := s.mind.ChatSession(r.Context(), client, from, to, msg)
+	stop := "stop"
+	resp.Choices = []completionChoice{{Message: &completionReply{Role: "assistant", Content: reply}, FinishReason: &stop}}
+	completion := estimateTokens(reply)
+	resp.Usage = &completionUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// streamCompletion sends the reply as server-sent chunks. The role goes out
+// first so the client sees the stream open while Mind works; the reply then
+// follows line by line.
+func (s *Server) streamCompletion(w http.ResponseWriter, r *http.Request, resp completionResponse, client, from, to, msg string, prompt int, includeUsage bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeOpenAIError(w, http.StatusInternalServerError, "server_error", "streaming is not supported here")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	resp.Object = "chat.completion.chunk"
+	send := func(choices []completionChoice, usage *completionUsage) {
+		resp.Choices, resp.Usage = choices, usage
+		data, err := json.Marshal(resp)
+		if err != nil {
+			s.core.Log.Errorf("v1: encode chunk: %v", err)
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+
+	send([]completionChoice{{Delta: &completionReply{Role: "assistant"}}}, nil)
+
+	reply := s.mind.ChatSession(r.Context(), client, from, to, msg)
+	for _, line := range strings.SplitAfter(reply, "\n") {
+		if line != "" {
+			send([]completionChoice{{Delta: &completionReply{Content: line}}}, nil)
+		}
+	}
+
+	stop := "stop"
+	send([]completionChoice{{Delta: &completionReply{}, FinishReason: &stop}}, nil)
+	if includeUsage {
+		completion := estimateTokens(reply)
+		send([]completionChoice{}, &completionUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion})
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// handleModels lists the one model RictusD serves.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	if _, ok := s.authenticate(w, r); !ok {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{"id": modelName, "object": "model", "created": 0, "owned_by": "rictusd"},
+		},
+	})
+}
+
+// authenticate checks the bearer token against conf/tokens.json, which is
+// read on every request so tokens can be added or revoked without a
+// restart. With no tokens configured, every request is refused.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (core.APIToken, bool) {
+	tokens, err := core.LoadAPITokens(s.core.Conf)
+	if err != nil {
+		s.core.Log.Errorf("v1: %v", err)
+		s.writeOpenAIError(w, http.StatusInternalServerError, "server_error", "the API tokens can't be read")
+		return core.APIToken{}, false
+	}
+	if len(tokens) == 0 {
+		s.writeOpenAIError(w, http.StatusUnauthorized, "authentication_error",
+			"no API tokens are configured; add one to conf/"+core.APITokensFile)
+		return core.APIToken{}, false
+	}
+
+	presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		s.writeOpenAIError(w, http.StatusUnauthorized, "authentication_error", "missing bearer token")
+		return core.APIToken{}, false
+	}
+	token, ok := core.MatchAPIToken(tokens, strings.TrimSpace(presented))
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		s.writeOpenAIError(w, http.StatusUnauthorized, "authentication_error", "invalid API token")
+		return core.APIToken{}, false
+	}
+	return token, true
+}
+
+func (s *Server) writeOpenAIError(w http.ResponseWriter, status int, typ, msg string) {
+	var e openAIError
+	e.Error.Message, e.Error.Type = msg, typ
+	s.writeJSON(w, status, e)
+}
+
+// sessionKey identifies a conversation by its client's token and its user
+// messages. The token value is used rather than its name, which several
+// tokens may share. Assistant messages are left out, since clients may trim
+// or edit them.
+func sessionKey(token core.APIToken, users []string) string {
+	h := sha256.New()
+	h.Write([]byte(token.Token))
+	for _, u := range users {
+		h.Write([]byte{0})
+		h.Write([]byte(u))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func completionID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+// estimateTokens approximates a token count for the usage block at about
+// four bytes per token; Mind doesn't tokenize.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
@@ -1,14 +1,20 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"rictusd/modules/core"
+	"rictusd/modules/mcp"
 	"rictusd/modules/mind"
 )
 
@@ -16,6 +22,7 @@ type Server struct {
 	core   *core.Core
 	server *http.Server
 	mind   *mind.Mind
+	mcp    *mcp.Server
 }
 
 type chatRequest struct {
@@ -47,13 +54,48 @@ func New(c *core.Core) (*Server, error) {
 	mux.HandleFunc("/healthz", s.handleHealth)
 	mux.HandleFunc("/chat", s.handleChat)
 
+	// JSON API
+	s.registerAPI(mux)
+
+	// OpenAI-compatible chat completions, with bearer tokens
+	s.registerOpenAI(mux)
+
+	// Model Context Protocol for other local agents
+	s.mcp = mcp.New(c, s.mind)
+	mux.HandleFunc("/mcp", s.handleMCP)
+
 	return s, nil
 }
 
-// Start begins listening and serving HTTP requests.
-func (s *Server) Start() error {
+// Start begins listening and serving HTTP requests until ctx is cancelled,
+// then shuts down gracefully. Requests are served with ctx as their base
+// context, so a shutdown also cancels scans they started.
+func (s *Server) Start(ctx context.Context) error {
 	s.core.Log.Infof("HTTP server starting on %s", s.core.Config.ListenAddr)
-	return s.server.ListenAndServe()
+
+	s.server.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.server.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.core.Log.Info("HTTP server shutting down…")
+	s.mind.StopWatching()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -115,7 +157,7 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reply := s.mind.Chat(msg)
+	reply := s.mind.ChatContext(r.Context(), msg)
 
 	resp := chatResponse{
 		Reply: reply,
@@ -126,3 +168,49 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		s.core.Log.Errorf("chat encode error: %v", err)
 	}
 }
+
+// handleMCP carries MCP over HTTP: each POST holds one JSON-RPC message and
+// the response comes back in the body. There is no server-initiated stream,
+// so GET is refused. The tools read project files, so callers need an API
+// token, as for /v1; browser pages on other origins are refused as well.
+func (s *Server) handleMCP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && !localOrigin(origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if _, ok := s.authenticate(w, r); !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 16<<20))
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+
+	resp, ok := s.mcp.Handle(r.Context(), body)
+	if !ok {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// localOrigin reports whether a browser Origin is this machine.
+func localOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}